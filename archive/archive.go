@@ -0,0 +1,290 @@
+// Package archive 实现整站数据的JSON导出和导入，用于在实例之间迁移博客。
+// 导出文件带有格式版本，与数据库结构相互独立，导入时为所有记录分配新的ID
+package archive
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"goblog/models"
+	"io"
+	"time"
+)
+
+// Format 导出文件的格式标识
+const Format = "goblog"
+
+// Version 当前的导出格式版本，格式有不兼容的修改时递增，导入时拒绝更高的版本
+const Version = 1
+
+// Archive 导出文件的内容，记录之间通过导出时的ID关联
+type Archive struct {
+	Format     string     `json:"format"`
+	Version    int        `json:"version"`
+	ExportedAt time.Time  `json:"exported_at"`
+	Passwords  bool       `json:"passwords"` // 是否包含密码哈希
+	Users      []User     `json:"users"`
+	Categories []Category `json:"categories"` // 上级分类在下级分类之前
+	Posts      []Post     `json:"posts"`
+	Comments   []Comment  `json:"comments"` // 被回复的评论在回复之前
+	Media      []Media    `json:"media"`
+}
+
+// User 导出的用户
+type User struct {
+	ID        int       `json:"id"`
+	Username  string    `json:"username"`
+	Email     string    `json:"email"`
+	Password  string    `json:"password,omitempty"` // bcrypt哈希，只在选择导出密码时存在
+	IsAdmin   bool      `json:"is_admin,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Category 导出的分类，slug在导入时根据名称重新生成
+type Category struct {
+	ID       int    `json:"id"`
+	ParentID int    `json:"parent_id,omitempty"`
+	Name     string `json:"name"`
+	Path     string `json:"path"`
+}
+
+// Post 导出的文章，包括旧别名和按时间正序的历史版本
+type Post struct {
+	ID         int        `json:"id"`
+	Title      string     `json:"title"`
+	Slug       string     `json:"slug"`
+	Content    string     `json:"content"`
+	Format     string     `json:"format"`
+	UserID     int        `json:"user_id"`
+	CategoryID int        `json:"category_id,omitempty"`
+	Tags       []string   `json:"tags"`
+	Status     string     `json:"status"`
+	PublishAt  *time.Time `json:"publish_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	DeletedAt  *time.Time `json:"deleted_at,omitempty"`
+	OldSlugs   []OldSlug  `json:"old_slugs,omitempty"`
+	Revisions  []Revision `json:"revisions"`
+}
+
+// OldSlug 文章以前使用过的别名
+type OldSlug struct {
+	Slug      string    `json:"slug"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Revision 导出的历史版本，修改者可能已被删除，导入时记为文章作者
+type Revision struct {
+	UserID    int       `json:"user_id"`
+	Title     string    `json:"title"`
+	Content   string    `json:"content"`
+	Format    string    `json:"format"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Comment 导出的评论，已删除评论的内容为空
+type Comment struct {
+	ID        int        `json:"id"`
+	PostID    int        `json:"post_id"`
+	UserID    int        `json:"user_id"`
+	ParentID  int        `json:"parent_id,omitempty"`
+	Content   string     `json:"content"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+// Media 导出的上传文件记录，文件内容仍在存储后端中，需要另外复制
+type Media struct {
+	UserID    int       `json:"user_id"`
+	FileName  string    `json:"file_name"`
+	Hash      string    `json:"hash"`
+	MIMEType  string    `json:"mime_type"`
+	Size      int64     `json:"size"`
+	Width     int       `json:"width,omitempty"`
+	Height    int       `json:"height,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// FileName 导出文件的默认文件名，如 goblog-export-20240102-150405.json
+func FileName(t time.Time) string {
+	return "goblog-export-" + t.Format("20060102-150405") + ".json"
+}
+
+// Export 读取整站数据生成导出内容，passwords为假时不包含密码哈希
+func Export(ctx context.Context, s models.SiteStore, passwords bool) (*Archive, error) {
+	data, err := s.Dump(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &Archive{
+		Format:     Format,
+		Version:    Version,
+		ExportedAt: time.Now(),
+		Passwords:  passwords,
+		Users:      make([]User, 0, len(data.Users)),
+		Categories: make([]Category, 0, len(data.Categories)),
+		Posts:      make([]Post, 0, len(data.Posts)),
+		Comments:   make([]Comment, 0, len(data.Comments)),
+		Media:      make([]Media, 0, len(data.Media)),
+	}
+
+	for _, u := range data.Users {
+		user := User{ID: u.ID, Username: u.Username, Email: u.Email, IsAdmin: u.IsAdmin, CreatedAt: u.CreatedAt, UpdatedAt: u.UpdatedAt}
+		if passwords {
+			user.Password = u.Password
+		}
+		a.Users = append(a.Users, user)
+	}
+
+	for _, c := range data.Categories {
+		a.Categories = append(a.Categories, Category{ID: c.ID, ParentID: c.ParentID, Name: c.Name, Path: c.Path})
+	}
+
+	index := make(map[int]int, len(data.Posts))
+	for _, p := range data.Posts {
+		index[p.ID] = len(a.Posts)
+		a.Posts = append(a.Posts, Post{
+			ID:         p.ID,
+			Title:      p.Title,
+			Slug:       p.Slug,
+			Content:    p.Content,
+			Format:     string(p.Format),
+			UserID:     p.UserID,
+			CategoryID: p.CategoryID,
+			Tags:       p.Tags,
+			Status:     string(p.Status),
+			PublishAt:  p.PublishAt,
+			CreatedAt:  p.CreatedAt,
+			UpdatedAt:  p.UpdatedAt,
+			DeletedAt:  p.DeletedAt,
+			Revisions:  []Revision{},
+		})
+	}
+	for _, r := range data.Redirects {
+		if i, ok := index[r.PostID]; ok {
+			a.Posts[i].OldSlugs = append(a.Posts[i].OldSlugs, OldSlug{Slug: r.Slug, CreatedAt: r.CreatedAt})
+		}
+	}
+	for _, r := range data.Revisions {
+		if i, ok := index[r.PostID]; ok {
+			a.Posts[i].Revisions = append(a.Posts[i].Revisions, Revision{
+				UserID: r.UserID, Title: r.Title, Content: r.Content, Format: string(r.Format), CreatedAt: r.CreatedAt,
+			})
+		}
+	}
+
+	for _, c := range data.Comments {
+		a.Comments = append(a.Comments, Comment{
+			ID: c.ID, PostID: c.PostID, UserID: c.UserID, ParentID: c.ParentID, Content: c.Content,
+			CreatedAt: c.CreatedAt, UpdatedAt: c.UpdatedAt, DeletedAt: c.DeletedAt,
+		})
+	}
+
+	for _, m := range data.Media {
+		a.Media = append(a.Media, Media{
+			UserID: m.UserID, FileName: m.FileName, Hash: m.Hash, MIMEType: m.MIMEType,
+			Size: m.Size, Width: m.Width, Height: m.Height, CreatedAt: m.CreatedAt,
+		})
+	}
+
+	return a, nil
+}
+
+// Write 把导出内容以缩进的JSON写入w
+func (a *Archive) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(a)
+}
+
+// Read 读取并检查导出文件，格式版本高于程序支持的版本时返回错误
+func Read(r io.Reader) (*Archive, error) {
+	var a Archive
+	if err := json.NewDecoder(r).Decode(&a); err != nil {
+		return nil, fmt.Errorf("解析导出文件失败: %w", err)
+	}
+	if a.Format != Format {
+		return nil, errors.New("不是goblog的导出文件")
+	}
+	if a.Version < 1 || a.Version > Version {
+		return nil, fmt.Errorf("不支持导出文件的格式版本 %d，程序支持的最高版本为 %d", a.Version, Version)
+	}
+
+	for _, p := range a.Posts {
+		if _, err := models.ParsePostStatus(p.Status); err != nil {
+			return nil, fmt.Errorf("文章 %s: %w", p.Slug, err)
+		}
+		if _, err := models.ParsePostFormat(p.Format); err != nil {
+			return nil, fmt.Errorf("文章 %s: %w", p.Slug, err)
+		}
+	}
+	return &a, nil
+}
+
+// siteData 把导出内容转换为存储写入的整站数据，历史版本按出现顺序编号
+func (a *Archive) siteData() *models.SiteData {
+	data := &models.SiteData{}
+
+	for _, u := range a.Users {
+		data.Users = append(data.Users, &models.User{
+			ID: u.ID, Username: u.Username, Email: u.Email, Password: u.Password, IsAdmin: u.IsAdmin,
+			CreatedAt: u.CreatedAt, UpdatedAt: u.UpdatedAt,
+		})
+	}
+
+	for _, c := range a.Categories {
+		data.Categories = append(data.Categories, &models.Category{ID: c.ID, ParentID: c.ParentID, Name: c.Name, Path: c.Path})
+	}
+
+	revisionID := 0
+	for _, p := range a.Posts {
+		// Read 已经检查过状态和格式
+		status, _ := models.ParsePostStatus(p.Status)
+		format, _ := models.ParsePostFormat(p.Format)
+		tags := make([]string, 0, len(p.Tags))
+		for _, tag := range p.Tags {
+			if tag = models.NormalizeTag(tag); tag != "" {
+				tags = append(tags, tag)
+			}
+		}
+
+		data.Posts = append(data.Posts, &models.Post{
+			ID: p.ID, Title: p.Title, Slug: p.Slug, Content: p.Content, Format: format, UserID: p.UserID,
+			CategoryID: p.CategoryID, Tags: tags, Status: status, PublishAt: p.PublishAt,
+			CreatedAt: p.CreatedAt, UpdatedAt: p.UpdatedAt, DeletedAt: p.DeletedAt,
+		})
+		for _, old := range p.OldSlugs {
+			data.Redirects = append(data.Redirects, &models.SlugRedirect{Slug: old.Slug, PostID: p.ID, CreatedAt: old.CreatedAt})
+		}
+		for _, r := range p.Revisions {
+			revisionID++
+			format, err := models.ParsePostFormat(r.Format)
+			if err != nil {
+				format = models.FormatText
+			}
+			data.Revisions = append(data.Revisions, &models.Revision{
+				ID: revisionID, PostID: p.ID, UserID: r.UserID, Title: r.Title, Content: r.Content, Format: format, CreatedAt: r.CreatedAt,
+			})
+		}
+	}
+
+	for _, c := range a.Comments {
+		data.Comments = append(data.Comments, &models.Comment{
+			ID: c.ID, PostID: c.PostID, UserID: c.UserID, ParentID: c.ParentID, Content: c.Content,
+			CreatedAt: c.CreatedAt, UpdatedAt: c.UpdatedAt, DeletedAt: c.DeletedAt,
+		})
+	}
+
+	for _, m := range a.Media {
+		data.Media = append(data.Media, &models.Media{
+			UserID: m.UserID, FileName: m.FileName, Hash: m.Hash, MIMEType: m.MIMEType,
+			Size: m.Size, Width: m.Width, Height: m.Height, CreatedAt: m.CreatedAt,
+		})
+	}
+
+	return data
+}
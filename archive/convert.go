@@ -0,0 +1,179 @@
+package archive
+
+import (
+	"fmt"
+	"goblog/models"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Conversion 从其他博客系统的数据转换得到的导出内容，之后与导出文件一样使用 Import 导入
+type Conversion struct {
+	Archive *Archive
+
+	// Unmapped 无法对应到goblog而被忽略的字段或记录，值为出现的次数
+	Unmapped map[string]int
+}
+
+// UnmappedFields 返回按名称排序的被忽略字段
+func (c *Conversion) UnmappedFields() []string {
+	fields := make([]string, 0, len(c.Unmapped))
+	for field := range c.Unmapped {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// ConvertOptions 转换选项
+type ConvertOptions struct {
+	// Author 没有作者的文章归属的用户名
+	Author string
+
+	// SlugFallback 根据标题生成别名的方式
+	SlugFallback models.SlugFallback
+
+	// Progress 每转换一篇文章调用一次，可以为空
+	Progress func(done, total int, name string)
+}
+
+// placeholderDomain 没有邮箱的作者使用的邮箱域名，.invalid 是保留的顶级域名，不会误发邮件
+const placeholderDomain = "users.invalid"
+
+// builder 逐篇文章构建导出内容，按名称合并作者和分类并分配ID
+type builder struct {
+	opts     ConvertOptions
+	archive  *Archive
+	unmapped map[string]int
+
+	users      map[string]int // 用户名到ID
+	emails     map[string]bool
+	categories map[string]int // 分类路径到ID
+}
+
+// newBuilder 创建空的导出内容
+func newBuilder(opts ConvertOptions) *builder {
+	return &builder{
+		opts: opts,
+		archive: &Archive{
+			Format:     Format,
+			Version:    Version,
+			ExportedAt: time.Now(),
+			Users:      []User{},
+			Categories: []Category{},
+			Posts:      []Post{},
+			Comments:   []Comment{},
+			Media:      []Media{},
+		},
+		unmapped:   make(map[string]int),
+		users:      make(map[string]int),
+		emails:     make(map[string]bool),
+		categories: make(map[string]int),
+	}
+}
+
+// skip 记录一个被忽略的字段或记录
+func (b *builder) skip(field string) {
+	b.unmapped[field]++
+}
+
+// progress 报告转换进度
+func (b *builder) progress(done, total int, name string) {
+	if b.opts.Progress != nil {
+		b.opts.Progress(done, total, name)
+	}
+}
+
+// user 返回用户名对应的用户ID，用户不存在时创建；email为空时使用占位邮箱。
+// 导入时与现有用户重复的按冲突处理方式处理，默认使用现有用户
+func (b *builder) user(username, email string) int {
+	if id, ok := b.users[username]; ok {
+		return id
+	}
+
+	if email == "" || b.emails[email] {
+		local := models.Slugify(username)
+		if local == "" {
+			local = "user"
+		}
+		email = local + "@" + placeholderDomain
+		for n := 2; b.emails[email]; n++ {
+			email = fmt.Sprintf("%s-%d@%s", local, n, placeholderDomain)
+		}
+	}
+
+	now := time.Now()
+	id := len(b.archive.Users) + 1
+	b.archive.Users = append(b.archive.Users, User{ID: id, Username: username, Email: email, CreatedAt: now, UpdatedAt: now})
+	b.users[username] = id
+	b.emails[email] = true
+	return id
+}
+
+// author 返回文章作者的用户ID，没有作者时使用 ConvertOptions.Author，也没有指定时返回错误
+func (b *builder) author(username, email, source string) (int, error) {
+	if username == "" {
+		username = b.opts.Author
+	}
+	if username == "" {
+		return 0, fmt.Errorf("%s没有作者，请指定默认作者", source)
+	}
+	return b.user(username, email), nil
+}
+
+// category 返回各级分类名组成的路径对应的分类ID，不存在的各级分类会被创建，names为空时返回0
+func (b *builder) category(names []string) int {
+	id, path := 0, ""
+	for _, name := range names {
+		// 分类名中的 / 会被当作路径分隔符
+		name = strings.Join(strings.Fields(strings.ReplaceAll(name, "/", " ")), " ")
+		if name == "" {
+			continue
+		}
+
+		parent := id
+		if path != "" {
+			path += "/"
+		}
+		path += name
+
+		var ok bool
+		if id, ok = b.categories[path]; !ok {
+			id = len(b.archive.Categories) + 1
+			b.archive.Categories = append(b.archive.Categories, Category{ID: id, ParentID: parent, Name: name, Path: path})
+			b.categories[path] = id
+		}
+	}
+	return id
+}
+
+// slug 根据原来的别名生成文章别名，没有时根据标题生成
+func (b *builder) slug(source, title string) string {
+	if strings.TrimSpace(source) == "" {
+		source = title
+	}
+	return models.MakeSlug(source, b.opts.SlugFallback)
+}
+
+// addPost 添加一篇文章，以文章当前内容作为唯一的历史版本，返回分配的文章ID
+func (b *builder) addPost(post Post) int {
+	post.ID = len(b.archive.Posts) + 1
+	if post.Tags == nil {
+		post.Tags = []string{}
+	}
+	post.Revisions = []Revision{{
+		UserID:    post.UserID,
+		Title:     post.Title,
+		Content:   post.Content,
+		Format:    post.Format,
+		CreatedAt: post.UpdatedAt,
+	}}
+	b.archive.Posts = append(b.archive.Posts, post)
+	return post.ID
+}
+
+// result 返回转换结果
+func (b *builder) result() *Conversion {
+	return &Conversion{Archive: b.archive, Unmapped: b.unmapped}
+}
@@ -0,0 +1,202 @@
+package archive
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"goblog/db"
+	"goblog/models"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Policy 导入的用户与现有用户的用户名或邮箱重复时的处理方式
+type Policy string
+
+const (
+	// PolicySkip 不导入该用户，其内容归属现有用户
+	PolicySkip Policy = "skip"
+
+	// PolicyOverwrite 用导入的用户名、邮箱、管理员权限和密码哈希覆盖现有用户，其内容归属现有用户
+	PolicyOverwrite Policy = "overwrite"
+
+	// PolicyRename 在用户名和邮箱后加上序号，作为新用户导入
+	PolicyRename Policy = "rename"
+)
+
+// ParsePolicy 解析冲突处理方式，空字符串视为 PolicySkip
+func ParsePolicy(s string) (Policy, error) {
+	switch policy := Policy(s); policy {
+	case "":
+		return PolicySkip, nil
+	case PolicySkip, PolicyOverwrite, PolicyRename:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("未知的冲突处理方式: %s", s)
+	}
+}
+
+// Options 导入选项
+type Options struct {
+	Policy Policy
+	DryRun bool // 只检查导入结果，不保存任何修改
+}
+
+// Conflict 一个与现有用户重复的导入用户及其处理结果
+type Conflict struct {
+	Username string // 导入文件中的用户名
+	Existing string // 用户名或邮箱重复的现有用户
+	Action   string
+}
+
+// Report 导入结果
+type Report struct {
+	models.LoadResult
+
+	Conflicts []Conflict
+
+	// Passwords 为没有密码哈希的新用户生成的临时密码，键为用户名
+	Passwords map[string]string
+}
+
+// Import 把导出内容写入站点，为所有记录分配新的ID，用户名或邮箱重复的用户按opts.Policy处理
+func Import(ctx context.Context, users models.UserStore, site models.SiteStore, a *Archive, opts Options) (*Report, error) {
+	data := a.siteData()
+	report := &Report{Passwords: map[string]string{}}
+
+	// 改名时要避开现有用户和导入文件中的所有用户名和邮箱
+	taken := make(map[string]bool)
+	for _, u := range data.Users {
+		taken[u.Username] = true
+		taken[u.Email] = true
+	}
+
+	existing := make(map[int]int)
+	imported := make([]*models.User, 0, len(data.Users))
+	for _, u := range data.Users {
+		byName, err := findUser(ctx, users.FindByUsername, u.Username)
+		if err != nil {
+			return nil, err
+		}
+		byEmail, err := findUser(ctx, users.FindByEmail, u.Email)
+		if err != nil {
+			return nil, err
+		}
+
+		target := byName
+		if target == nil {
+			target = byEmail
+		}
+		if target == nil {
+			imported = append(imported, u)
+			continue
+		}
+
+		conflict := Conflict{Username: u.Username, Existing: target.Username}
+		switch opts.Policy {
+		case PolicyOverwrite:
+			if byName != nil && byEmail != nil && byName.ID != byEmail.ID {
+				return nil, fmt.Errorf("无法覆盖用户 %s: 用户名和邮箱分别属于现有用户 %s 和 %s", u.Username, byName.Username, byEmail.Username)
+			}
+			existing[u.ID] = target.ID
+			imported = append(imported, u)
+			conflict.Action = "覆盖现有用户"
+		case PolicyRename:
+			if byName != nil {
+				if u.Username, err = uniqueName(ctx, users.FindByUsername, taken, u.Username, usernameWithSuffix); err != nil {
+					return nil, err
+				}
+			}
+			if byEmail != nil {
+				if u.Email, err = uniqueName(ctx, users.FindByEmail, taken, u.Email, emailWithSuffix); err != nil {
+					return nil, err
+				}
+			}
+			imported = append(imported, u)
+			conflict.Action = fmt.Sprintf("改名为 %s <%s>", u.Username, u.Email)
+		default:
+			existing[u.ID] = target.ID
+			conflict.Action = "使用现有用户"
+		}
+		report.Conflicts = append(report.Conflicts, conflict)
+	}
+
+	for _, u := range imported {
+		if _, ok := existing[u.ID]; ok || u.Password != "" {
+			continue
+		}
+		password, hash, err := temporaryPassword()
+		if err != nil {
+			return nil, err
+		}
+		u.Password = hash
+		report.Passwords[u.Username] = password
+	}
+	data.Users = imported
+
+	result, err := site.Load(ctx, data, existing, opts.DryRun)
+	if err != nil {
+		return nil, err
+	}
+	report.LoadResult = *result
+	return report, nil
+}
+
+// findUser 查找用户，不存在时返回nil
+func findUser(ctx context.Context, find func(context.Context, string) (*models.User, error), key string) (*models.User, error) {
+	user, err := find(ctx, key)
+	if errors.Is(err, db.ErrNotFound) {
+		return nil, nil
+	}
+	return user, err
+}
+
+// uniqueName 依次尝试加上序号2、3等，返回既不属于现有用户也不在taken中的用户名或邮箱，并将其加入taken
+func uniqueName(ctx context.Context, find func(context.Context, string) (*models.User, error), taken map[string]bool, name string, withSuffix func(string, int) string) (string, error) {
+	for n := 2; ; n++ {
+		candidate := withSuffix(name, n)
+		if taken[candidate] {
+			continue
+		}
+		user, err := findUser(ctx, find, candidate)
+		if err != nil {
+			return "", err
+		}
+		if user == nil {
+			taken[candidate] = true
+			return candidate, nil
+		}
+	}
+}
+
+// usernameWithSuffix 在用户名后加上序号，如 alice-2
+func usernameWithSuffix(username string, n int) string {
+	return fmt.Sprintf("%s-%d", username, n)
+}
+
+// emailWithSuffix 在邮箱的用户部分加上序号，如 alice+2@example.com
+func emailWithSuffix(email string, n int) string {
+	at := strings.LastIndexByte(email, '@')
+	if at < 0 {
+		return fmt.Sprintf("%s+%d", email, n)
+	}
+	return fmt.Sprintf("%s+%d%s", email[:at], n, email[at:])
+}
+
+// temporaryPassword 生成随机的临时密码及其bcrypt哈希
+func temporaryPassword() (password, hash string, err error) {
+	buf := make([]byte, 10)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	password = strings.ToLower(base32.StdEncoding.EncodeToString(buf))
+
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", "", err
+	}
+	return password, string(hashed), nil
+}
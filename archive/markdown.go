@@ -0,0 +1,292 @@
+package archive
+
+import (
+	"fmt"
+	"goblog/models"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// markdownExts 作为文章导入的文件扩展名
+var markdownExts = map[string]bool{".md": true, ".markdown": true}
+
+// jekyllDate Jekyll文章文件名开头的日期，如 2024-01-02-hello.md
+var jekyllDate = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2})-`)
+
+// frontMatterTimeLayouts 前置元数据中以字符串表示的时间可能使用的格式，没有时区的按本地时区解析
+var frontMatterTimeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// ConvertMarkdown 把目录中Hugo或Jekyll的Markdown文件转换为导出内容。
+// 文件开头可以有 --- 包围的YAML或 +++ 包围的TOML前置元数据；
+// 没有日期时依次使用Jekyll文件名中的日期和文件的修改时间，没有别名时使用去掉日期的文件名，
+// Hugo页面包（目录下的 index.md）使用目录名
+func ConvertMarkdown(dir string, opts ConvertOptions) (*Conversion, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != dir && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if markdownExts[strings.ToLower(filepath.Ext(path))] {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	b := newBuilder(opts)
+	for i, path := range files {
+		name, err := filepath.Rel(dir, path)
+		if err != nil {
+			name = path
+		}
+		b.progress(i+1, len(files), name)
+
+		// Hugo的 _index.md 是章节的列表页，不是文章
+		if strings.HasPrefix(filepath.Base(path), "_index.") {
+			b.skip("章节页 _index.md")
+			continue
+		}
+		if err := b.addMarkdown(path, name); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+
+	return b.result(), nil
+}
+
+// addMarkdown 把一个Markdown文件作为文章添加
+func (b *builder) addMarkdown(path, name string) error {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	meta, content, err := splitFrontMatter(raw)
+	if err != nil {
+		return err
+	}
+
+	// 文件名去掉扩展名和Jekyll的日期前缀后作为默认的别名和标题
+	base := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	if base == "index" {
+		base = filepath.Base(filepath.Dir(path))
+	}
+	var fileDate time.Time
+	if m := jekyllDate.FindStringSubmatch(base); m != nil {
+		fileDate, _ = time.ParseInLocation("2006-01-02", m[1], time.Local)
+		base = strings.TrimPrefix(base, m[0])
+	}
+
+	var (
+		title, slug, author     string
+		created, updated        time.Time
+		draft                   bool
+		tags, categories, extra []string
+	)
+	keys := make([]string, 0, len(meta))
+	for key := range meta {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value := meta[key]
+		switch strings.ToLower(key) {
+		case "title":
+			title = stringValue(value)
+		case "slug":
+			slug = stringValue(value)
+		case "date":
+			if created, err = timeValue(value); err != nil {
+				return fmt.Errorf("%s: %w", key, err)
+			}
+		case "lastmod", "updated", "last_modified_at", "modified":
+			if updated, err = timeValue(value); err != nil {
+				return fmt.Errorf("%s: %w", key, err)
+			}
+		case "draft":
+			draft = draft || value == true
+		case "published":
+			draft = draft || value == false
+		case "tags":
+			tags = append(tags, stringList(value)...)
+		case "categories", "category":
+			categories = append(categories, stringList(value)...)
+		case "author", "authors":
+			// 作者名可能包含空格，不按空格分隔
+			authors := []string{stringValue(value)}
+			if list, ok := value.([]interface{}); ok {
+				authors = stringList(list)
+			}
+			for _, name := range authors {
+				if author == "" {
+					author = name
+				} else if name != "" && name != author {
+					extra = append(extra, name)
+				}
+			}
+		default:
+			b.skip("前置元数据 " + key)
+		}
+	}
+	if len(extra) > 0 {
+		b.skip("第一个以外的作者")
+	}
+
+	if title == "" {
+		title = base
+	}
+	if slug == "" {
+		slug = base
+	}
+	if created.IsZero() {
+		created = fileDate
+	}
+	if created.IsZero() {
+		created = info.ModTime()
+	}
+	if updated.IsZero() || updated.Before(created) {
+		updated = created
+	}
+
+	userID, err := b.author(author, "", "文章")
+	if err != nil {
+		return err
+	}
+
+	post := Post{
+		Title:     title,
+		Slug:      b.slug(slug, title),
+		Content:   content,
+		Format:    string(models.FormatMarkdown),
+		UserID:    userID,
+		Tags:      tags,
+		CreatedAt: created,
+		UpdatedAt: updated,
+	}
+	switch {
+	case draft:
+		post.Status = string(models.StatusDraft)
+	case created.After(time.Now()):
+		post.Status = string(models.StatusScheduled)
+		post.PublishAt = &post.CreatedAt
+	default:
+		post.Status = string(models.StatusPublished)
+		post.PublishAt = &post.CreatedAt
+	}
+	if len(categories) > 0 {
+		post.CategoryID = b.category(categories[:1])
+		if len(categories) > 1 {
+			b.skip("第一个以外的分类")
+		}
+	}
+
+	b.addPost(post)
+	return nil
+}
+
+// splitFrontMatter 分离文件开头的YAML或TOML前置元数据和正文，没有前置元数据时返回空的元数据
+func splitFrontMatter(raw []byte) (map[string]interface{}, string, error) {
+	text := strings.TrimPrefix(strings.ReplaceAll(string(raw), "\r\n", "\n"), "\ufeff")
+	meta := map[string]interface{}{}
+
+	lines := strings.SplitAfter(text, "\n")
+	delimiter := strings.TrimSpace(lines[0])
+	if delimiter != "---" && delimiter != "+++" {
+		return meta, text, nil
+	}
+	end := 1
+	for end < len(lines) && strings.TrimSpace(lines[end]) != delimiter {
+		end++
+	}
+	if end == len(lines) {
+		return nil, "", fmt.Errorf("前置元数据缺少结束的 %s", delimiter)
+	}
+	header := strings.Join(lines[1:end], "")
+	body := strings.TrimLeft(strings.Join(lines[end+1:], ""), "\n")
+
+	var err error
+	if delimiter == "---" {
+		err = yaml.Unmarshal([]byte(header), &meta)
+	} else {
+		_, err = toml.Decode(header, &meta)
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("解析前置元数据失败: %w", err)
+	}
+	if meta == nil {
+		meta = map[string]interface{}{}
+	}
+	return meta, body, nil
+}
+
+// stringValue 把前置元数据中的标量转换为字符串
+func stringValue(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+	return strings.TrimSpace(fmt.Sprint(value))
+}
+
+// stringList 把前置元数据中的列表转换为字符串切片，Jekyll允许用空格分隔的字符串表示列表
+func stringList(value interface{}) []string {
+	var list []string
+	switch v := value.(type) {
+	case []interface{}:
+		for _, item := range v {
+			if s := stringValue(item); s != "" {
+				list = append(list, s)
+			}
+		}
+	case string:
+		list = strings.Fields(v)
+	default:
+		if s := stringValue(v); s != "" {
+			list = append(list, s)
+		}
+	}
+	return list
+}
+
+// timeValue 把前置元数据中的时间转换为 time.Time
+func timeValue(value interface{}) (time.Time, error) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, nil
+	case string:
+		for _, layout := range frontMatterTimeLayouts {
+			if t, err := time.ParseInLocation(layout, strings.TrimSpace(v), time.Local); err == nil {
+				return t, nil
+			}
+		}
+		return time.Time{}, fmt.Errorf("无法解析时间 %q", v)
+	default:
+		return time.Time{}, fmt.Errorf("无法解析时间 %v", value)
+	}
+}
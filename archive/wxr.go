@@ -0,0 +1,292 @@
+package archive
+
+import (
+	"encoding/xml"
+	"fmt"
+	"goblog/models"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// wxrFile WordPress导出文件（WXR）中用到的部分。WXR的命名空间带有版本号，
+// 因此除了需要区分正文和摘要的 encoded 元素，其余元素只按本地名称匹配
+type wxrFile struct {
+	Channel struct {
+		Authors    []wxrAuthor   `xml:"author"`
+		Categories []wxrCategory `xml:"category"`
+		Items      []wxrItem     `xml:"item"`
+	} `xml:"channel"`
+}
+
+// wxrAuthor 站点的作者
+type wxrAuthor struct {
+	ID          int    `xml:"author_id"`
+	Login       string `xml:"author_login"`
+	Email       string `xml:"author_email"`
+	DisplayName string `xml:"author_display_name"`
+}
+
+// wxrCategory 站点的分类，上级分类以其别名表示
+type wxrCategory struct {
+	Nicename string `xml:"category_nicename"`
+	Parent   string `xml:"category_parent"`
+	Name     string `xml:"cat_name"`
+}
+
+// wxrItem 一篇文章、页面或附件
+type wxrItem struct {
+	Title       string        `xml:"title"`
+	Creator     string        `xml:"creator"`
+	Encoded     []wxrEncoded  `xml:"encoded"`
+	PostDate    string        `xml:"post_date"`
+	PostDateGMT string        `xml:"post_date_gmt"`
+	Modified    string        `xml:"post_modified"`
+	ModifiedGMT string        `xml:"post_modified_gmt"`
+	Name        string        `xml:"post_name"`
+	Status      string        `xml:"status"`
+	Type        string        `xml:"post_type"`
+	Password    string        `xml:"post_password"`
+	Sticky      int           `xml:"is_sticky"`
+	Terms       []wxrTerm     `xml:"category"`
+	Meta        []wxrPostMeta `xml:"postmeta"`
+	Comments    []wxrComment  `xml:"comment"`
+}
+
+// wxrEncoded 正文（content:encoded）或摘要（excerpt:encoded）
+type wxrEncoded struct {
+	XMLName xml.Name
+	Value   string `xml:",chardata"`
+}
+
+// wxrTerm 文章所属的分类或标签
+type wxrTerm struct {
+	Domain   string `xml:"domain,attr"`
+	Nicename string `xml:"nicename,attr"`
+	Name     string `xml:",chardata"`
+}
+
+// wxrPostMeta 文章的自定义字段
+type wxrPostMeta struct {
+	Key   string `xml:"meta_key"`
+	Value string `xml:"meta_value"`
+}
+
+// wxrComment 文章的评论，访客评论的 user_id 为0
+type wxrComment struct {
+	ID       int    `xml:"comment_id"`
+	Date     string `xml:"comment_date"`
+	DateGMT  string `xml:"comment_date_gmt"`
+	Content  string `xml:"comment_content"`
+	Approved string `xml:"comment_approved"`
+	Type     string `xml:"comment_type"`
+	Parent   int    `xml:"comment_parent"`
+	UserID   int    `xml:"comment_user_id"`
+}
+
+// wxrIgnoredMeta WordPress内部使用、没有对应含义的自定义字段，不计入被忽略的字段
+var wxrIgnoredMeta = map[string]bool{
+	"_edit_last": true, "_edit_lock": true, "_pingme": true, "_encloseme": true,
+	"_wp_trash_meta_status": true, "_wp_trash_meta_time": true, "_wp_desired_post_slug": true,
+}
+
+// ConvertWXR 把WordPress导出的WXR文件转换为导出内容。
+// 只导入文章（post），页面、附件等其他类型记为被忽略；正文是HTML，按Markdown格式导入，渲染时原样保留并清理；
+// 只导入已批准的注册用户评论，访客评论、引用通告和未批准的评论记为被忽略
+func ConvertWXR(r io.Reader, opts ConvertOptions) (*Conversion, error) {
+	var file wxrFile
+	if err := xml.NewDecoder(r).Decode(&file); err != nil {
+		return nil, fmt.Errorf("解析WXR文件失败: %w", err)
+	}
+
+	b := newBuilder(opts)
+	logins := make(map[int]string)
+	for _, author := range file.Channel.Authors {
+		if author.Login == "" {
+			continue
+		}
+		b.user(author.Login, author.Email)
+		logins[author.ID] = author.Login
+		if author.DisplayName != "" && author.DisplayName != author.Login {
+			b.skip("作者显示名称")
+		}
+	}
+
+	// 分类按别名引用上级分类，转换为从顶级分类开始的名称路径
+	categories := make(map[string]wxrCategory)
+	for _, category := range file.Channel.Categories {
+		categories[category.Nicename] = category
+	}
+	categoryPath := func(nicename string) []string {
+		var names []string
+		for seen := make(map[string]bool); nicename != "" && !seen[nicename]; {
+			seen[nicename] = true
+			category, ok := categories[nicename]
+			if !ok {
+				break
+			}
+			names = append([]string{category.Name}, names...)
+			nicename = category.Parent
+		}
+		return names
+	}
+
+	total := len(file.Channel.Items)
+	for i, item := range file.Channel.Items {
+		b.progress(i+1, total, item.Title)
+
+		if item.Type != "post" {
+			b.skip("类型为 " + item.Type + " 的内容")
+			continue
+		}
+		if item.Status == "auto-draft" {
+			b.skip("自动草稿")
+			continue
+		}
+
+		userID, err := b.author(item.Creator, "", "文章《"+item.Title+"》")
+		if err != nil {
+			return nil, err
+		}
+
+		post := Post{
+			Title:     item.Title,
+			Slug:      b.slug(wxrSlug(item.Name), item.Title),
+			Format:    string(models.FormatMarkdown),
+			UserID:    userID,
+			CreatedAt: wxrTime(item.PostDateGMT, item.PostDate),
+			UpdatedAt: wxrTime(item.ModifiedGMT, item.Modified),
+		}
+		if post.CreatedAt.IsZero() {
+			post.CreatedAt = b.archive.ExportedAt
+		}
+		if post.UpdatedAt.IsZero() {
+			post.UpdatedAt = post.CreatedAt
+		}
+		for _, encoded := range item.Encoded {
+			switch {
+			case strings.Contains(encoded.XMLName.Space, "/content/"):
+				post.Content = encoded.Value
+			case strings.TrimSpace(encoded.Value) != "":
+				b.skip("摘要")
+			}
+		}
+
+		switch item.Status {
+		case "publish":
+			post.Status = string(models.StatusPublished)
+			post.PublishAt = &post.CreatedAt
+		case "future":
+			post.Status = string(models.StatusScheduled)
+			post.PublishAt = &post.CreatedAt
+		case "trash":
+			post.Status = string(models.StatusDraft)
+			post.DeletedAt = &post.UpdatedAt
+		default:
+			// draft、pending、private 都只有作者可见，导入为草稿
+			post.Status = string(models.StatusDraft)
+			if item.Status != "draft" {
+				b.skip("状态 " + item.Status)
+			}
+		}
+		if item.Password != "" {
+			b.skip("文章密码")
+		}
+		if item.Sticky != 0 {
+			b.skip("置顶")
+		}
+
+		for _, term := range item.Terms {
+			switch term.Domain {
+			case "category":
+				if post.CategoryID == 0 {
+					names := categoryPath(term.Nicename)
+					if len(names) == 0 {
+						names = []string{term.Name}
+					}
+					post.CategoryID = b.category(names)
+				} else {
+					b.skip("第一个以外的分类")
+				}
+			case "post_tag":
+				post.Tags = append(post.Tags, term.Name)
+			default:
+				b.skip("分类法 " + term.Domain)
+			}
+		}
+
+		for _, meta := range item.Meta {
+			switch {
+			case meta.Key == "_wp_old_slug":
+				if slug := wxrSlug(meta.Value); slug != "" {
+					post.OldSlugs = append(post.OldSlugs, OldSlug{Slug: slug, CreatedAt: post.UpdatedAt})
+				}
+			case !wxrIgnoredMeta[meta.Key]:
+				b.skip("自定义字段 " + meta.Key)
+			}
+		}
+
+		postID := b.addPost(post)
+		b.addWXRComments(postID, post.CreatedAt, item.Comments, logins)
+	}
+
+	return b.result(), nil
+}
+
+// addWXRComments 添加已批准的注册用户评论，回复的评论没有导入时作为直接评论文章
+func (b *builder) addWXRComments(postID int, posted time.Time, comments []wxrComment, logins map[int]string) {
+	ids := make(map[int]int)
+	for _, c := range comments {
+		login, registered := logins[c.UserID]
+		switch {
+		case c.Type != "" && c.Type != "comment":
+			b.skip("引用通告")
+			continue
+		case c.Approved != "1":
+			b.skip("未批准的评论")
+			continue
+		case c.UserID == 0 || !registered:
+			b.skip("访客评论")
+			continue
+		}
+
+		created := wxrTime(c.DateGMT, c.Date)
+		if created.IsZero() {
+			created = posted
+		}
+		comment := Comment{
+			ID:        len(b.archive.Comments) + 1,
+			PostID:    postID,
+			UserID:    b.user(login, ""),
+			ParentID:  ids[c.Parent],
+			Content:   c.Content,
+			CreatedAt: created,
+			UpdatedAt: created,
+		}
+		b.archive.Comments = append(b.archive.Comments, comment)
+		ids[c.ID] = comment.ID
+	}
+}
+
+// wxrSlug 解码WordPress中百分号编码的别名
+func wxrSlug(name string) string {
+	if decoded, err := url.PathUnescape(name); err == nil {
+		return decoded
+	}
+	return name
+}
+
+// wxrTimeLayout WXR中的时间格式
+const wxrTimeLayout = "2006-01-02 15:04:05"
+
+// wxrTime 解析WXR中的时间，优先使用UTC时间，草稿的UTC时间为全0，此时按本地时区解析站点时间
+func wxrTime(gmt, local string) time.Time {
+	if t, err := time.ParseInLocation(wxrTimeLayout, gmt, time.UTC); err == nil && !strings.HasPrefix(gmt, "0000") {
+		return t
+	}
+	if t, err := time.ParseInLocation(wxrTimeLayout, local, time.Local); err == nil && !strings.HasPrefix(local, "0000") {
+		return t
+	}
+	return time.Time{}
+}
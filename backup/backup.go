@@ -0,0 +1,191 @@
+// Package backup 实现SQLite数据库的在线备份、旧备份轮换和从备份恢复
+package backup
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"goblog/config"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Snapshotter 能够写入数据库一致性快照的存储，由 db.Store 实现
+type Snapshotter interface {
+	Snapshot(ctx context.Context, path string) error
+}
+
+// ErrInvalidName 表示备份文件名不符合格式，用于防止通过文件名访问备份目录之外的文件
+var ErrInvalidName = errors.New("无效的备份文件名")
+
+// namePattern 备份文件名格式，如 goblog-20240102-150405.db.gz，同一秒内的多个备份加序号
+var namePattern = regexp.MustCompile(`^goblog-\d{8}-\d{6}(-\d)?\.db(\.gz)?$`)
+
+// timeLayout 备份文件名中的时间格式
+const timeLayout = "20060102-150405"
+
+// Info 一个备份文件的信息
+type Info struct {
+	Name      string
+	Size      int64
+	CreatedAt time.Time
+}
+
+// Create 在备份目录中创建一个新的备份，按配置压缩，然后删除超出保留数量的旧备份。
+// ctx 只用于写入快照，取消后不会留下备份文件
+func Create(ctx context.Context, s Snapshotter, cfg config.BackupConfig) (*Info, error) {
+	if err := os.MkdirAll(cfg.Dir, 0755); err != nil {
+		return nil, err
+	}
+
+	name, err := newName(cfg.Dir, time.Now(), cfg.Compress)
+	if err != nil {
+		return nil, err
+	}
+
+	// 快照先写入临时文件，完成后再改名，目录中的备份文件总是完整的
+	snapshot := filepath.Join(cfg.Dir, "."+name+".snapshot")
+	defer os.Remove(snapshot)
+	if err := s.Snapshot(ctx, snapshot); err != nil {
+		return nil, fmt.Errorf("写入数据库快照失败: %w", err)
+	}
+
+	path := filepath.Join(cfg.Dir, name)
+	if cfg.Compress {
+		err = compressFile(snapshot, path)
+	} else {
+		err = os.Rename(snapshot, path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := Rotate(cfg.Dir, cfg.Keep); err != nil {
+		return nil, fmt.Errorf("删除旧备份失败: %w", err)
+	}
+
+	stat, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Info{Name: name, Size: stat.Size(), CreatedAt: stat.ModTime()}, nil
+}
+
+// newName 生成不与已有备份重名的文件名
+func newName(dir string, now time.Time, compress bool) (string, error) {
+	ext := ".db"
+	if compress {
+		ext += ".gz"
+	}
+
+	base := "goblog-" + now.Format(timeLayout)
+	for i := 1; i < 10; i++ {
+		name := base + ext
+		if i > 1 {
+			name = fmt.Sprintf("%s-%d%s", base, i, ext)
+		}
+		if _, err := os.Stat(filepath.Join(dir, name)); errors.Is(err, os.ErrNotExist) {
+			return name, nil
+		}
+	}
+	return "", errors.New("同一秒内创建的备份过多")
+}
+
+// compressFile 把src压缩为dst，先写入临时文件再改名
+func compressFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	tmp := dst + ".tmp"
+	out, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+
+	zw := gzip.NewWriter(out)
+	if _, err := io.Copy(zw, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, dst)
+}
+
+// List 列出备份目录中的备份，最新的在前，目录不存在时返回空列表
+func List(dir string) ([]Info, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return []Info{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	backups := []Info{}
+	for _, entry := range entries {
+		if entry.IsDir() || !namePattern.MatchString(entry.Name()) {
+			continue
+		}
+		stat, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		backups = append(backups, Info{Name: entry.Name(), Size: stat.Size(), CreatedAt: stat.ModTime()})
+	}
+
+	// 文件名以创建时间开头，按文件名排序即按时间排序
+	sort.Slice(backups, func(i, j int) bool {
+		return sortKey(backups[i].Name) > sortKey(backups[j].Name)
+	})
+	return backups, nil
+}
+
+// sortKey 备份文件的排序键，去掉扩展名后按字符串比较即按创建时间和序号排序
+func sortKey(name string) string {
+	return strings.TrimSuffix(strings.TrimSuffix(name, ".gz"), ".db")
+}
+
+// Rotate 保留最新的keep个备份，删除其余的备份，keep不大于0时不删除，返回被删除的文件名
+func Rotate(dir string, keep int) ([]string, error) {
+	if keep <= 0 {
+		return nil, nil
+	}
+
+	backups, err := List(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var removed []string
+	for i := keep; i < len(backups); i++ {
+		if err := os.Remove(filepath.Join(dir, backups[i].Name)); err != nil {
+			return removed, err
+		}
+		removed = append(removed, backups[i].Name)
+	}
+	return removed, nil
+}
+
+// Path 返回备份目录中指定备份文件的路径，文件名不符合格式时返回 ErrInvalidName
+func Path(dir, name string) (string, error) {
+	if !namePattern.MatchString(name) {
+		return "", ErrInvalidName
+	}
+	return filepath.Join(dir, name), nil
+}
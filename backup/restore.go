@@ -0,0 +1,131 @@
+package backup
+
+import (
+	"compress/gzip"
+	"database/sql"
+	"errors"
+	"fmt"
+	"goblog/db/migrate"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// RestoreResult 恢复备份的结果
+type RestoreResult struct {
+	Version int    // 备份的数据库结构版本
+	Latest  int    // 程序内置的最新结构版本，大于 Version 时启动服务会执行剩余的迁移
+	Moved   string // 原数据库文件被移动到的路径，原来没有数据库文件时为空
+}
+
+// Restore 用备份文件替换SQLite数据库文件target。
+// 备份先解压到target所在目录，检查完整性和结构版本后再替换，原数据库文件改名保留。
+// 恢复前应停止服务，否则运行中的服务仍使用已打开的旧文件
+func Restore(snapshot, target string) (*RestoreResult, error) {
+	tmp, err := extract(snapshot, filepath.Dir(target))
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp)
+
+	result, err := validate(tmp)
+	if err != nil {
+		return nil, err
+	}
+
+	// 旧数据库的日志文件不能留给新数据库，与数据库文件一起改名
+	suffix := ".before-restore-" + time.Now().Format(timeLayout)
+	if _, err := os.Stat(target); err == nil {
+		result.Moved = target + suffix
+	}
+	for _, ext := range []string{"", "-journal", "-wal", "-shm"} {
+		err := os.Rename(target+ext, target+suffix+ext)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("移动原数据库文件失败: %w", err)
+		}
+	}
+
+	if err := os.Rename(tmp, target); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// extract 把备份复制到dir中的临时文件，gzip压缩的备份同时解压
+func extract(snapshot, dir string) (string, error) {
+	in, err := os.Open(snapshot)
+	if err != nil {
+		return "", err
+	}
+	defer in.Close()
+
+	var r io.Reader = in
+	if strings.HasSuffix(snapshot, ".gz") {
+		zr, err := gzip.NewReader(in)
+		if err != nil {
+			return "", fmt.Errorf("解压备份失败: %w", err)
+		}
+		defer zr.Close()
+		r = zr
+	}
+
+	out, err := os.CreateTemp(dir, ".goblog-restore-*.db")
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(out, r); err != nil {
+		out.Close()
+		os.Remove(out.Name())
+		return "", fmt.Errorf("解压备份失败: %w", err)
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(out.Name())
+		return "", err
+	}
+	return out.Name(), nil
+}
+
+// validate 检查备份是完整的goblog数据库，且结构版本不高于程序支持的版本
+func validate(path string) (*RestoreResult, error) {
+	conn, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	var check string
+	if err := conn.QueryRow(`PRAGMA integrity_check`).Scan(&check); err != nil {
+		return nil, fmt.Errorf("备份不是有效的SQLite数据库: %w", err)
+	}
+	if check != "ok" {
+		return nil, fmt.Errorf("备份数据库已损坏: %s", check)
+	}
+
+	var tables int
+	err = conn.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'schema_migrations'`).Scan(&tables)
+	if err != nil {
+		return nil, err
+	}
+	if tables == 0 {
+		return nil, errors.New("备份中没有迁移记录，不是goblog的数据库")
+	}
+
+	// Status 会检查已执行迁移的校验和，以及是否存在程序不认识的版本
+	migrator, err := migrate.New(conn, "sqlite3")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := migrator.Status(); err != nil {
+		return nil, err
+	}
+	version, err := migrator.Version()
+	if err != nil {
+		return nil, err
+	}
+
+	return &RestoreResult{Version: version, Latest: migrator.Latest()}, nil
+}
@@ -0,0 +1,206 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"goblog/archive"
+	"goblog/config"
+	"goblog/db"
+	"goblog/models"
+	"io"
+	"os"
+	"sort"
+	"time"
+)
+
+// runExport 执行 goblog export [-o 文件] [-passwords]，把整站数据导出为JSON文件
+func runExport(cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	output := flags.String("o", archive.FileName(time.Now()), "导出文件路径，- 表示标准输出")
+	passwords := flags.Bool("passwords", false, "包含用户的密码哈希，导入后用户可以使用原密码登录")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 0 {
+		return errors.New("用法: export [-o 文件] [-passwords]")
+	}
+
+	if cfg.Database.Type == "memory" {
+		return errors.New("内存数据库的数据不会保存，没有可以导出的数据")
+	}
+
+	store, err := db.Open(cfg.Database)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	a, err := archive.Export(context.Background(), store.Site, *passwords)
+	if err != nil {
+		return err
+	}
+
+	if *output == "-" {
+		return a.Write(os.Stdout)
+	}
+
+	// 先写入临时文件，完成后再改名，避免留下不完整的导出文件
+	tmp := *output + ".tmp"
+	file, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+	if err := a.Write(file); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, *output); err != nil {
+		return err
+	}
+
+	fmt.Printf("已导出 %d 个用户、%d 篇文章、%d 条评论、%d 个文件记录到 %s\n",
+		len(a.Users), len(a.Posts), len(a.Comments), len(a.Media), *output)
+	if *passwords {
+		fmt.Println("导出文件包含密码哈希，请妥善保管")
+	}
+	return nil
+}
+
+// runImport 执行 goblog import [-from goblog|wxr|markdown] [-author 用户名] [-dry-run] [-conflict skip|overwrite|rename] <文件或目录>，
+// 导入整站数据，或者转换并导入WordPress和Hugo/Jekyll的文章
+func runImport(cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	from := flags.String("from", "goblog", "导入的数据来源: goblog（导出文件）、wxr（WordPress导出文件）或 markdown（Hugo/Jekyll文章目录）")
+	author := flags.String("author", "", "没有作者的文章归属的用户名，只用于 wxr 和 markdown")
+	dryRun := flags.Bool("dry-run", false, "只检查导入结果，不保存任何修改")
+	conflict := flags.String("conflict", string(archive.PolicySkip), "用户名或邮箱与现有用户重复时的处理方式: skip、overwrite 或 rename")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("用法: import [-from goblog|wxr|markdown] [-author 用户名] [-dry-run] [-conflict skip|overwrite|rename] <文件或目录>")
+	}
+	policy, err := archive.ParsePolicy(*conflict)
+	if err != nil {
+		return err
+	}
+	fallback, err := models.ParseSlugFallback(cfg.Blog.SlugFallback)
+	if err != nil {
+		return err
+	}
+
+	if cfg.Database.Type == "memory" {
+		return errors.New("内存数据库的数据不会保存，无法导入")
+	}
+
+	opts := archive.ConvertOptions{
+		Author:       *author,
+		SlugFallback: fallback,
+		Progress: func(done, total int, name string) {
+			fmt.Printf("[%d/%d] %s\n", done, total, name)
+		},
+	}
+	var a *archive.Archive
+	switch *from {
+	case "goblog":
+		a, err = readArchive(flags.Arg(0), archive.Read)
+	case "wxr":
+		a, err = readArchive(flags.Arg(0), func(r io.Reader) (*archive.Archive, error) {
+			return convert(archive.ConvertWXR(r, opts))
+		})
+	case "markdown":
+		a, err = convert(archive.ConvertMarkdown(flags.Arg(0), opts))
+	default:
+		err = fmt.Errorf("未知的数据来源: %s", *from)
+	}
+	if err != nil {
+		return err
+	}
+
+	store, err := db.Open(cfg.Database)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	report, err := archive.Import(context.Background(), store.Users, store.Site, a, archive.Options{Policy: policy, DryRun: *dryRun})
+	if err != nil {
+		return err
+	}
+	printImportReport(report, *dryRun)
+	return nil
+}
+
+// readArchive 打开文件（- 表示标准输入）并用read读取
+func readArchive(path string, read func(io.Reader) (*archive.Archive, error)) (*archive.Archive, error) {
+	if path == "-" {
+		return read(os.Stdin)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return read(file)
+}
+
+// convert 打印转换时被忽略的字段，返回转换得到的导出内容
+func convert(c *archive.Conversion, err error) (*archive.Archive, error) {
+	if err != nil {
+		return nil, err
+	}
+	if fields := c.UnmappedFields(); len(fields) > 0 {
+		fmt.Println("以下字段或记录没有对应的功能，已忽略:")
+		for _, field := range fields {
+			fmt.Printf("  %s（%d 处）\n", field, c.Unmapped[field])
+		}
+	}
+	return c.Archive, nil
+}
+
+// printImportReport 打印导入结果，试运行时不打印临时密码
+func printImportReport(report *archive.Report, dryRun bool) {
+	for _, c := range report.Conflicts {
+		fmt.Printf("用户 %s 与现有用户 %s 重复: %s\n", c.Username, c.Existing, c.Action)
+	}
+
+	olds := make([]string, 0, len(report.RenamedSlugs))
+	for old := range report.RenamedSlugs {
+		olds = append(olds, old)
+	}
+	sort.Strings(olds)
+	for _, old := range olds {
+		fmt.Printf("文章别名 %s 已被占用，改为 %s\n", old, report.RenamedSlugs[old])
+	}
+
+	verb := "已导入"
+	if dryRun {
+		verb = "试运行，将导入"
+	}
+	fmt.Printf("%s %d 个用户（覆盖 %d 个现有用户）、%d 个分类、%d 篇文章、%d 个历史版本、%d 条评论、%d 个文件记录\n",
+		verb, report.Users, report.Overwritten, report.Categories, report.Posts, report.Revisions, report.Comments, report.Media)
+
+	if dryRun {
+		return
+	}
+	if len(report.Passwords) > 0 {
+		fmt.Println("导出文件不包含密码哈希，以下新用户使用临时密码:")
+		names := make([]string, 0, len(report.Passwords))
+		for name := range report.Passwords {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Printf("  %s  %s\n", name, report.Passwords[name])
+		}
+	}
+	if report.Media > 0 {
+		fmt.Println("导出文件只包含上传文件的记录，请把原实例的上传文件复制到当前的存储后端")
+	}
+}
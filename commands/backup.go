@@ -0,0 +1,102 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"goblog/backup"
+	"goblog/config"
+	"goblog/db"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+)
+
+// runBackup 执行 goblog backup [list]，创建SQLite数据库的备份或列出已有的备份
+func runBackup(cfg *config.Config, args []string) error {
+	if len(args) > 0 {
+		if args[0] != "list" || len(args) > 1 {
+			return errors.New("用法: backup [list]")
+		}
+		return listBackups(cfg.Backup.Dir)
+	}
+
+	if !isSQLite(cfg) {
+		return db.ErrSnapshotUnsupported
+	}
+
+	store, err := db.Open(cfg.Database)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	info, err := backup.Create(context.Background(), store, cfg.Backup)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("已创建备份 %s (%d 字节)\n", filepath.Join(cfg.Backup.Dir, info.Name), info.Size)
+	return nil
+}
+
+// listBackups 打印备份目录中的备份，最新的在前
+func listBackups(dir string) error {
+	backups, err := backup.List(dir)
+	if err != nil {
+		return err
+	}
+	if len(backups) == 0 {
+		fmt.Printf("%s 中没有备份\n", dir)
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "文件\t大小\t创建时间")
+	for _, b := range backups {
+		fmt.Fprintf(w, "%s\t%d\t%s\n", b.Name, b.Size, b.CreatedAt.Format("2006-01-02 15:04:05"))
+	}
+	return w.Flush()
+}
+
+// runRestore 执行 goblog restore <备份>，用备份替换SQLite数据库文件。
+// 参数可以是备份文件的路径，也可以是备份目录中的文件名
+func runRestore(cfg *config.Config, args []string) error {
+	if len(args) != 1 {
+		return errors.New("用法: restore <备份文件>")
+	}
+	if !isSQLite(cfg) {
+		return errors.New("只有SQLite数据库支持从备份恢复")
+	}
+
+	snapshot := args[0]
+	if _, err := os.Stat(snapshot); errors.Is(err, os.ErrNotExist) {
+		path, pathErr := backup.Path(cfg.Backup.Dir, snapshot)
+		if pathErr != nil {
+			return err
+		}
+		snapshot = path
+	}
+
+	result, err := backup.Restore(snapshot, cfg.Database.Path)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("已从 %s 恢复数据库，结构版本 %d\n", snapshot, result.Version)
+	if result.Moved != "" {
+		fmt.Printf("原数据库已移动到 %s\n", result.Moved)
+	}
+	if result.Version < result.Latest {
+		fmt.Printf("启动服务时将自动执行到版本 %d 的迁移\n", result.Latest)
+	}
+	return nil
+}
+
+// isSQLite 判断配置的数据库是否为SQLite
+func isSQLite(cfg *config.Config) bool {
+	switch cfg.Database.Type {
+	case "sqlite3", "sqlite", "":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,243 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"goblog/config"
+	"goblog/db"
+	"goblog/imaging"
+	"goblog/models"
+	"goblog/storage"
+	"io"
+)
+
+// blobsUsage blobs 命令的用法
+const blobsUsage = "用法: blobs migrate [-delete] <源后端> <目标后端>，后端为 local 或 s3；blobs variants"
+
+// runBlobs 执行 goblog blobs migrate|variants，管理上传文件的内容
+func runBlobs(cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		return errors.New(blobsUsage)
+	}
+
+	switch args[0] {
+	case "migrate":
+		return runBlobsMigrate(cfg, args[1:])
+	case "variants":
+		return runBlobsVariants(cfg)
+	default:
+		return errors.New(blobsUsage)
+	}
+}
+
+// runBlobsMigrate 执行 goblog blobs migrate [-delete] <源后端> <目标后端>，在存储后端之间复制上传文件
+func runBlobsMigrate(cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("blobs migrate", flag.ContinueOnError)
+	deleteSource := flags.Bool("delete", false, "复制成功后删除源后端中的文件")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 2 {
+		return errors.New(blobsUsage)
+	}
+	if flags.Arg(0) == flags.Arg(1) {
+		return errors.New("源后端和目标后端不能相同")
+	}
+
+	if cfg.Database.Type == "memory" {
+		return errors.New("内存数据库没有保存上传文件的记录")
+	}
+
+	from, err := storage.New(cfg.Upload, flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	to, err := storage.New(cfg.Upload, flags.Arg(1))
+	if err != nil {
+		return err
+	}
+
+	images, err := imaging.NewProcessor(cfg.Upload.Image)
+	if err != nil {
+		return err
+	}
+
+	store, err := db.Open(cfg.Database)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	media, err := store.Media.FindAll(context.Background())
+	if err != nil {
+		return err
+	}
+
+	// 相同内容只保存一份，按键去重；复制是幂等的，中断后可以重新执行
+	copied, missing := 0, 0
+	seen := make(map[string]bool)
+	for _, m := range media {
+		key := m.Key()
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		err := copyBlob(from, to, key, m.Size, m.MIMEType)
+		if errors.Is(err, storage.ErrNotExist) {
+			fmt.Printf("跳过 %s: 源后端中没有该文件\n", key)
+			missing++
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("复制 %s 失败: %v", key, err)
+		}
+		copied++
+
+		if *deleteSource {
+			if err := from.Delete(key); err != nil {
+				return fmt.Errorf("删除源文件 %s 失败: %v", key, err)
+			}
+		}
+
+		if err := copyVariants(from, to, images, m, *deleteSource); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("已复制 %d 个文件，缺失 %d 个\n", copied, missing)
+	if flags.Arg(1) != cfg.Upload.Storage {
+		fmt.Printf("请把配置中的 upload.storage 改为 %s 后重启服务\n", flags.Arg(1))
+	}
+	return nil
+}
+
+// copyBlob 把一个文件从源后端复制到目标后端
+func copyBlob(from, to storage.Storage, key string, size int64, contentType string) error {
+	body, err := from.Get(key)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	return to.Put(key, io.LimitReader(body, size), size, contentType)
+}
+
+// copyVariants 复制按当前配置生成的图片缩放版本和缩略图，缺失的版本可以用 blobs variants 重新生成
+func copyVariants(from, to storage.Storage, images *imaging.Processor, m *models.Media, deleteSource bool) error {
+	if !images.HasVariants(m.MIMEType) {
+		return nil
+	}
+
+	variantType := images.VariantType(m.MIMEType)
+	for _, name := range images.VariantNames(m.Width) {
+		if !m.HasVariant(name) {
+			continue
+		}
+		key := m.VariantKey(name, variantType)
+		data, err := readBlob(from, key)
+		if errors.Is(err, storage.ErrNotExist) {
+			fmt.Printf("跳过 %s: 源后端中没有该文件\n", key)
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("读取 %s 失败: %v", key, err)
+		}
+		if err := to.Put(key, bytes.NewReader(data), int64(len(data)), variantType); err != nil {
+			return fmt.Errorf("复制 %s 失败: %v", key, err)
+		}
+
+		if deleteSource {
+			if err := from.Delete(key); err != nil {
+				return fmt.Errorf("删除源文件 %s 失败: %v", key, err)
+			}
+		}
+	}
+	return nil
+}
+
+// runBlobsVariants 执行 goblog blobs variants，按当前配置重新生成所有图片的缩放版本和缩略图，
+// 用于修改 upload.image 配置之后或处理在支持缩放版本之前上传的图片
+func runBlobsVariants(cfg *config.Config) error {
+	if cfg.Database.Type == "memory" {
+		return errors.New("内存数据库没有保存上传文件的记录")
+	}
+
+	images, err := imaging.NewProcessor(cfg.Upload.Image)
+	if err != nil {
+		return err
+	}
+	uploads, err := storage.New(cfg.Upload, "")
+	if err != nil {
+		return err
+	}
+
+	store, err := db.Open(cfg.Database)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	media, err := store.Media.FindAll(context.Background())
+	if err != nil {
+		return err
+	}
+
+	generated, missing := 0, 0
+	seen := make(map[string]bool)
+	for _, m := range media {
+		if seen[m.Hash] || !images.HasVariants(m.MIMEType) {
+			continue
+		}
+		seen[m.Hash] = true
+
+		data, err := readBlob(uploads, m.Key())
+		if errors.Is(err, storage.ErrNotExist) {
+			fmt.Printf("跳过 %s: 存储后端中没有该文件\n", m.Key())
+			missing++
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("读取 %s 失败: %v", m.Key(), err)
+		}
+
+		variants, skipped, err := images.Variants(context.Background(), data, m.MIMEType)
+		if err != nil {
+			return fmt.Errorf("处理 %s 失败: %v", m.Key(), err)
+		}
+		for _, v := range variants {
+			key := m.VariantKey(v.Name, v.MIMEType)
+			if err := uploads.Put(key, bytes.NewReader(v.Data), int64(len(v.Data)), v.MIMEType); err != nil {
+				return fmt.Errorf("保存 %s 失败: %v", key, err)
+			}
+		}
+		// 删除之前生成的、比原图大的版本
+		for _, name := range skipped {
+			key := m.VariantKey(name, images.VariantType(m.MIMEType))
+			if err := uploads.Delete(key); err != nil {
+				return fmt.Errorf("删除 %s 失败: %v", key, err)
+			}
+		}
+		if err := store.Media.SetSkippedVariants(context.Background(), m.Hash, skipped); err != nil {
+			return fmt.Errorf("更新 %s 的记录失败: %v", m.Key(), err)
+		}
+		generated++
+	}
+
+	fmt.Printf("已处理 %d 张图片，缺失 %d 张\n", generated, missing)
+	return nil
+}
+
+// readBlob 读取一个文件的全部内容
+func readBlob(s storage.Storage, key string) ([]byte, error) {
+	body, err := s.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	return io.ReadAll(body)
+}
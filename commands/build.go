@@ -0,0 +1,64 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"goblog/config"
+	"goblog/controllers"
+	"goblog/db"
+	"goblog/models"
+	"goblog/router"
+	"goblog/staticsite"
+)
+
+// runBuild 执行 goblog build -out 目录 [-force]，把公开页面生成为静态站点
+func runBuild(cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("build", flag.ContinueOnError)
+	out := flags.String("out", "", "输出目录")
+	force := flags.Bool("force", false, "忽略上次的构建结果，重新渲染所有页面")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *out == "" || flags.NArg() != 0 {
+		return errors.New("用法: build -out <目录> [-force]")
+	}
+
+	if cfg.Database.Type == "memory" {
+		return errors.New("内存数据库的数据不会保存，没有可以生成的页面")
+	}
+	permalink, err := models.ParsePermalink(cfg.Blog.Permalink)
+	if err != nil {
+		return err
+	}
+
+	store, err := db.Open(cfg.Database)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	app, err := controllers.NewApp(cfg, store)
+	if err != nil {
+		return err
+	}
+	app.Static = true
+
+	result, err := staticsite.Build(context.Background(), router.SetupRouter(app), store.Posts, store.Comments, permalink, staticsite.Options{
+		Out:       *out,
+		Public:    "public",
+		Templates: "templates",
+		Force:     *force,
+		Progress: func(action, file string) {
+			fmt.Printf("%s %s\n", action, file)
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("已生成静态站点到 %s: 渲染 %d 个页面，复制 %d 个静态资源和上传文件，保留 %d 个未变化的文件，删除 %d 个过期文件\n",
+		*out, result.Rendered, result.Copied, result.Kept, result.Removed)
+	return nil
+}
@@ -0,0 +1,81 @@
+// Package commands 实现 goblog 的命令行子命令，如 goblog migrate up
+package commands
+
+import (
+	"fmt"
+	"goblog/config"
+	"os"
+	"sort"
+)
+
+// command 一个子命令
+type command struct {
+	usage string
+	run   func(cfg *config.Config, args []string) error
+}
+
+// registry 所有子命令，键为命令名
+var registry = map[string]command{
+	"backup": {
+		usage: "backup [list]  创建SQLite数据库的备份，或列出已有的备份",
+		run:   runBackup,
+	},
+	"blobs": {
+		usage: "blobs migrate [-delete] <源> <目标>|variants  在存储后端(local、s3)之间迁移上传文件，或重新生成图片的缩放版本",
+		run:   runBlobs,
+	},
+	"build": {
+		usage: "build -out <目录> [-force]  把首页、文章、标签和分类等公开页面生成为静态站点，只重新渲染有修改的页面",
+		run:   runBuild,
+	},
+	"export": {
+		usage: "export [-o 文件] [-passwords]  把用户、文章、评论等整站数据导出为JSON文件",
+		run:   runExport,
+	},
+	"import": {
+		usage: "import [-from goblog|wxr|markdown] [-author 用户名] [-dry-run] [-conflict skip|overwrite|rename] <文件或目录>  导入 export 生成的文件，或WordPress、Hugo/Jekyll的文章",
+		run:   runImport,
+	},
+	"migrate": {
+		usage: "migrate up|down [N]|status  执行、回滚或查看数据库迁移",
+		run:   runMigrate,
+	},
+	"restore": {
+		usage: "restore <备份文件>  停止服务后用备份替换SQLite数据库文件",
+		run:   runRestore,
+	},
+	"user": {
+		usage: "user admin|unadmin <用户名>  设置或取消管理员",
+		run:   runUser,
+	},
+}
+
+// Run 执行子命令，args[0]为命令名，返回进程退出码
+func Run(cfg *config.Config, args []string) int {
+	cmd, ok := registry[args[0]]
+	if !ok {
+		printUsage()
+		return 2
+	}
+
+	if err := cmd.run(cfg, args[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", args[0], err)
+		return 1
+	}
+	return 0
+}
+
+// printUsage 打印所有子命令的用法
+func printUsage() {
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintln(os.Stderr, "用法: goblog [命令]")
+	fmt.Fprintln(os.Stderr, "不带命令时启动博客服务。可用命令:")
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  goblog %s\n", registry[name].usage)
+	}
+}
@@ -0,0 +1,53 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"goblog/config"
+	"goblog/db"
+)
+
+// runUser 执行 goblog user admin|unadmin <用户名>，设置或取消管理员
+func runUser(cfg *config.Config, args []string) error {
+	if len(args) != 2 {
+		return errors.New("用法: user admin|unadmin <用户名>")
+	}
+
+	var isAdmin bool
+	switch args[0] {
+	case "admin":
+		isAdmin = true
+	case "unadmin":
+		isAdmin = false
+	default:
+		return fmt.Errorf("未知操作: %s", args[0])
+	}
+
+	if cfg.Database.Type == "memory" {
+		return errors.New("内存数据库的数据不会保存，无法设置管理员")
+	}
+
+	store, err := db.Open(cfg.Database)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	user, err := store.Users.FindByUsername(context.Background(), args[1])
+	if err != nil {
+		return fmt.Errorf("找不到用户 %s: %v", args[1], err)
+	}
+
+	user.IsAdmin = isAdmin
+	if err := store.Users.Update(context.Background(), user); err != nil {
+		return err
+	}
+
+	if isAdmin {
+		fmt.Printf("已将 %s 设置为管理员，管理页面立即可用，导航中的链接重新登录后显示\n", user.Username)
+	} else {
+		fmt.Printf("已取消 %s 的管理员权限，立即生效\n", user.Username)
+	}
+	return nil
+}
@@ -11,6 +11,10 @@ import (
 type Config struct {
 	Server   ServerConfig   `json:"server"`
 	Database DatabaseConfig `json:"database"`
+	Blog     BlogConfig     `json:"blog"`
+	Upload   UploadConfig   `json:"upload"`
+	Backup   BackupConfig   `json:"backup"`
+	Cache    CacheConfig    `json:"cache"`
 }
 
 // ServerConfig 服务器配置
@@ -18,16 +22,112 @@ type ServerConfig struct {
 	Port         int `json:"port"`
 	ReadTimeout  int `json:"readTimeout"`
 	WriteTimeout int `json:"writeTimeout"`
+
+	// StatsAllowLoopback 允许来自本机的请求不登录访问 /admin/stats。
+	// 在同一台机器上使用反向代理时所有请求都来自本机，此时不能开启
+	StatsAllowLoopback bool `json:"statsAllowLoopback"`
 }
 
 // DatabaseConfig 数据库配置
 type DatabaseConfig struct {
-	Type     string `json:"type"`
+	Type     string `json:"type"` // sqlite3、postgres、mysql 或 memory（内存演示模式）
 	Host     string `json:"host"`
 	Port     int    `json:"port"`
 	User     string `json:"user"`
 	Password string `json:"password"`
 	DBName   string `json:"dbname"`
+	Path     string `json:"path"`    // SQLite数据库文件路径
+	SSLMode  string `json:"sslmode"` // PostgreSQL连接的sslmode，默认disable
+
+	// QueryTimeoutSeconds 每次数据库操作（一条查询或一个事务）的超时秒数，0表示不限制
+	QueryTimeoutSeconds int `json:"queryTimeoutSeconds"`
+
+	// BulkTimeoutSeconds 在线备份的快照、整站导出和导入的超时秒数，耗时随数据量增长，默认0表示不限制
+	BulkTimeoutSeconds int `json:"bulkTimeoutSeconds"`
+}
+
+// BlogConfig 博客展示配置
+type BlogConfig struct {
+	PageSize  int `json:"pageSize"`  // 文章列表每页数量
+	HomePosts int `json:"homePosts"` // 首页展示的最新文章数量
+
+	// TrashRetentionDays 回收站中的文章保留天数，超过后由后台任务永久删除，0表示不自动清理
+	TrashRetentionDays int `json:"trashRetentionDays"`
+
+	// Permalink 文章固定链接格式，可用 {year}、{month}、{day}、{slug}、{id} 占位符
+	Permalink string `json:"permalink"`
+
+	// SlugFallback 中文等非ASCII标题生成别名的方式：pinyin 把汉字转写为拼音，
+	// unicode 保留原字符，ascii 只保留ASCII字母和数字
+	SlugFallback string `json:"slugFallback"`
+}
+
+// UploadConfig 文件上传配置
+type UploadConfig struct {
+	MaxSizeMB int `json:"maxSizeMB"` // 单个文件的最大大小，单位MB
+
+	// Storage 上传文件的存储后端：local 保存在本地目录，s3 保存在S3兼容的对象存储中
+	Storage string   `json:"storage"`
+	Dir     string   `json:"dir"` // local 后端的保存目录
+	S3      S3Config `json:"s3"`
+
+	Image ImageConfig `json:"image"`
+}
+
+// ImageConfig 上传图片的处理配置，上传时生成缩放版本和缩略图
+type ImageConfig struct {
+	// Widths 生成的缩放版本宽度，只生成比原图窄的版本，文章中的图片通过 srcset 按屏幕选择
+	Widths    []int `json:"widths"`
+	ThumbSize int   `json:"thumbSize"` // 媒体库缩略图的边长
+
+	// Format 缩放版本的格式：auto 时JPEG原图生成JPEG，其他图片生成无损WebP；也可以指定 jpeg 或 webp
+	Format  string `json:"format"`
+	Quality int    `json:"quality"` // JPEG质量，1-100
+
+	// MaxPixels 允许上传的图片最大像素数（宽×高）。解码需要的内存与像素数成正比，
+	// 压缩率很高的大尺寸图片文件很小，只限制文件大小无法防止耗尽内存
+	MaxPixels int `json:"maxPixels"`
+
+	// Workers 同时生成图片版本的数量，解码和编码占用大量CPU和内存，超过时上传请求排队等待；
+	// 为0时使用CPU核数
+	Workers int `json:"workers"`
+}
+
+// S3Config S3兼容对象存储的配置，也可以用于MinIO等自建服务
+type S3Config struct {
+	Endpoint  string `json:"endpoint"` // 服务地址，如 https://s3.amazonaws.com 或 http://localhost:9000
+	Region    string `json:"region"`
+	Bucket    string `json:"bucket"`
+	AccessKey string `json:"accessKey"`
+	SecretKey string `json:"secretKey"`
+
+	// PathStyle 使用 {endpoint}/{bucket}/{key} 形式的地址，MinIO等自建服务通常需要开启
+	PathStyle bool `json:"pathStyle"`
+
+	// SignedURLs 存储桶不允许公开读取，/uploads/ 的请求重定向到带签名的临时地址，有效期为 URLExpiryMinutes。
+	// 公开文件任何人都能得到签名地址，私有文件只对上传者和管理员签名；
+	// 不开启时私有文件由应用读取后返回，但仍然可以通过存储桶地址直接访问
+	SignedURLs       bool `json:"signedURLs"`
+	URLExpiryMinutes int  `json:"urlExpiryMinutes"`
+
+	// PublicURL 公开存储桶的访问地址前缀，如CDN域名，为空时使用存储桶地址
+	PublicURL string `json:"publicURL"`
+}
+
+// BackupConfig SQLite数据库的备份配置
+type BackupConfig struct {
+	Dir      string `json:"dir"`      // 备份文件的保存目录
+	Compress bool   `json:"compress"` // 使用gzip压缩备份文件
+	Keep     int    `json:"keep"`     // 保留最近的备份数量，更早的备份被删除，0表示全部保留
+
+	// IntervalHours 自动备份的间隔小时数，0表示不自动备份
+	IntervalHours int `json:"intervalHours"`
+}
+
+// CacheConfig 文章读取缓存的配置，只用于服务进程
+type CacheConfig struct {
+	Size       int `json:"size"`       // 最多缓存的查询结果数量，0表示不缓存
+	TTLSeconds int `json:"ttlSeconds"` // 缓存结果的有效秒数，0表示直到文章修改前一直有效
 }
 
 // 默认配置
@@ -44,6 +144,42 @@ var defaultConfig = Config{
 		User:     "root",
 		Password: "password",
 		DBName:   "goblog",
+		Path:     "./goblog.db",
+
+		QueryTimeoutSeconds: 10,
+	},
+	Blog: BlogConfig{
+		PageSize:           10,
+		HomePosts:          5,
+		TrashRetentionDays: 30,
+		Permalink:          "/{year}/{month}/{slug}",
+		SlugFallback:       "pinyin",
+	},
+	Upload: UploadConfig{
+		MaxSizeMB: 10,
+		Storage:   "local",
+		Dir:       "./uploads",
+		S3: S3Config{
+			Region:           "us-east-1",
+			URLExpiryMinutes: 15,
+		},
+		Image: ImageConfig{
+			Widths:    []int{480, 960, 1600},
+			ThumbSize: 240,
+			Format:    "auto",
+			Quality:   82,
+			MaxPixels: 40000000,
+		},
+	},
+	Backup: BackupConfig{
+		Dir:           "./backups",
+		Compress:      true,
+		Keep:          7,
+		IntervalHours: 24,
+	},
+	Cache: CacheConfig{
+		Size:       1000,
+		TTLSeconds: 300,
 	},
 }
 
@@ -65,8 +201,8 @@ func LoadConfig() *Config {
 	}
 	defer configFile.Close()
 
-	// 解析配置
-	var config Config
+	// 解析配置，未出现在文件中的字段保留默认值
+	config := defaultConfig
 	decoder := json.NewDecoder(configFile)
 	if err := decoder.Decode(&config); err != nil {
 		log.Printf("解析配置文件失败: %v，使用默认配置", err)
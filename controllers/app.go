@@ -0,0 +1,218 @@
+package controllers
+
+import (
+	"bytes"
+	"goblog/config"
+	"goblog/db"
+	"goblog/imaging"
+	"goblog/models"
+	"goblog/storage"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// templateDir 模板根目录
+const templateDir = "templates"
+
+// App 应用容器，持有配置、存储和模板等在整个进程生命周期内共享的对象
+type App struct {
+	Config   *config.Config
+	Posts    models.PostStore
+	Users    models.UserStore
+	Taxonomy models.TaxonomyStore
+	Comments models.CommentStore
+	Media    models.MediaStore
+	Site     models.SiteStore
+
+	// Static 为真时用于生成静态站点，页面中不显示登录、搜索和发表评论等需要服务端处理的功能
+	Static bool
+
+	// uploads 上传文件内容的存储后端，由配置选择
+	uploads storage.Storage
+
+	// images 按配置处理上传的图片，生成缩放版本和缩略图
+	images *imaging.Processor
+
+	// store 数据库存储，用于在线备份
+	store *db.Store
+
+	// permalink 文章固定链接格式，slugFallback 非ASCII标题生成别名的方式
+	permalink    *models.Permalink
+	slugFallback models.SlugFallback
+
+	// templates 页面模板，键为相对templates目录的路径，如 "posts/list.html"
+	templates map[string]*template.Template
+}
+
+// NewApp 创建应用容器，store由调用方创建并负责关闭
+func NewApp(cfg *config.Config, store *db.Store) (*App, error) {
+	permalink, err := models.ParsePermalink(cfg.Blog.Permalink)
+	if err != nil {
+		return nil, err
+	}
+	slugFallback, err := models.ParseSlugFallback(cfg.Blog.SlugFallback)
+	if err != nil {
+		return nil, err
+	}
+
+	uploads, err := storage.New(cfg.Upload, "")
+	if err != nil {
+		return nil, err
+	}
+	images, err := imaging.NewProcessor(cfg.Upload.Image)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &App{
+		Config:       cfg,
+		Posts:        store.Posts,
+		Users:        store.Users,
+		Taxonomy:     store.Taxonomy,
+		Comments:     store.Comments,
+		Media:        store.Media,
+		Site:         store.Site,
+		uploads:      uploads,
+		images:       images,
+		store:        store,
+		permalink:    permalink,
+		slugFallback: slugFallback,
+	}
+
+	// 文章链接和图片版本依赖配置，不能放在全局的 templateFuncs 中
+	funcs := template.FuncMap{
+		"postURL":  a.postURL,
+		"srcset":   a.srcset,
+		"thumbURL": a.thumbURL,
+	}
+	for name, fn := range templateFuncs {
+		funcs[name] = fn
+	}
+
+	a.templates, err = loadTemplates(templateDir, funcs)
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// UploadsHandler 返回提供上传文件下载的处理器，请求路径为文件的键
+func (a *App) UploadsHandler() http.Handler {
+	return http.HandlerFunc(a.serveUpload)
+}
+
+// postURL 返回文章的固定链接
+func (a *App) postURL(post *models.Post) string {
+	return a.permalink.URL(post)
+}
+
+// templateFuncs 模板中可用的辅助函数
+var templateFuncs = template.FuncMap{
+	"highlight":   highlight,
+	"join":        strings.Join,
+	"tagURL":      tagURL,
+	"categoryURL": categoryURL,
+	"fileSize":    fileSize,
+}
+
+// tagURL 生成标签页面的链接
+func tagURL(name string) template.URL {
+	return template.URL("/tags/" + url.PathEscape(name))
+}
+
+// categoryURL 生成分类页面的链接，slug中的 / 保留为路径分隔符
+func categoryURL(slug string) template.URL {
+	parts := strings.Split(slug, "/")
+	for i, part := range parts {
+		parts[i] = url.PathEscape(part)
+	}
+	return template.URL("/categories/" + strings.Join(parts, "/"))
+}
+
+// highlight 转义带高亮标记的文本，并把标记替换为 <mark> 标签
+func highlight(s string) template.HTML {
+	escaped := template.HTMLEscapeString(s)
+	escaped = strings.ReplaceAll(escaped, models.HighlightStart, "<mark>")
+	escaped = strings.ReplaceAll(escaped, models.HighlightEnd, "</mark>")
+	return template.HTML(escaped)
+}
+
+// partialDir 公共模板片段所在的子目录，其中的文件不作为页面
+const partialDir = "partials"
+
+// loadTemplates 一次性解析所有页面模板，每个页面都与基础布局和公共片段组合
+func loadTemplates(dir string, funcs template.FuncMap) (map[string]*template.Template, error) {
+	base := filepath.Join(dir, "base.html")
+
+	partials, err := filepath.Glob(filepath.Join(dir, partialDir, "*.html"))
+	if err != nil {
+		return nil, err
+	}
+
+	pages, err := filepath.Glob(filepath.Join(dir, "*.html"))
+	if err != nil {
+		return nil, err
+	}
+	nested, err := filepath.Glob(filepath.Join(dir, "*", "*.html"))
+	if err != nil {
+		return nil, err
+	}
+	pages = append(pages, nested...)
+
+	templates := make(map[string]*template.Template)
+	for _, page := range pages {
+		if page == base || filepath.Base(filepath.Dir(page)) == partialDir {
+			continue
+		}
+
+		files := append([]string{base, page}, partials...)
+		tmpl, err := template.New(filepath.Base(base)).Funcs(funcs).ParseFiles(files...)
+		if err != nil {
+			log.Printf("模板解析错误 %s: %v", page, err)
+			return nil, err
+		}
+
+		name, err := filepath.Rel(dir, page)
+		if err != nil {
+			return nil, err
+		}
+		templates[filepath.ToSlash(name)] = tmpl
+	}
+
+	return templates, nil
+}
+
+// render 使用基础布局渲染页面，先写入缓冲区以便出错时返回完整的错误响应
+func (a *App) render(w http.ResponseWriter, name string, data map[string]interface{}) {
+	a.renderStatus(w, http.StatusOK, name, data)
+}
+
+// renderStatus 与 render 相同，但以指定的状态码返回页面
+func (a *App) renderStatus(w http.ResponseWriter, status int, name string, data map[string]interface{}) {
+	tmpl, ok := a.templates[name]
+	if !ok {
+		log.Printf("模板不存在: %s", name)
+		http.Error(w, "模板解析错误", http.StatusInternalServerError)
+		return
+	}
+
+	// 获取当前年份
+	data["CurrentYear"] = time.Now().Year()
+	data["Static"] = a.Static
+
+	var buf bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&buf, "base", data); err != nil {
+		log.Printf("模板渲染错误: %v", err)
+		http.Error(w, "模板渲染错误", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	buf.WriteTo(w)
+}
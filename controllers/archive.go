@@ -0,0 +1,39 @@
+package controllers
+
+import (
+	"bytes"
+	"goblog/archive"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// ExportHandler 下载整站数据的导出文件，只有管理员可以访问，passwords=1 时包含密码哈希
+func (a *App) ExportHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := a.requireAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	passwords := r.URL.Query().Get("passwords") == "1"
+	export, err := archive.Export(r.Context(), a.Site, passwords)
+	if err != nil {
+		writeError(w, r, err, "导出失败")
+		return
+	}
+
+	// 先完整编码再发送，出错时还能返回错误页面
+	var buf bytes.Buffer
+	if err := export.Write(&buf); err != nil {
+		writeError(w, r, err, "编码导出文件失败")
+		return
+	}
+	log.Printf("管理员 %s 导出了整站数据，包含密码哈希: %v", user.Username, passwords)
+
+	name := archive.FileName(time.Now())
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+name+`"`)
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	w.Write(buf.Bytes())
+}
@@ -0,0 +1,117 @@
+package controllers
+
+import (
+	"errors"
+	"goblog/backup"
+	"goblog/db"
+	"goblog/models"
+	"goblog/utils"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// requireAdmin 返回已登录的管理员，未登录时重定向到登录页，不是管理员时返回403。
+// 会话中的管理员标记是登录时写入的，这里每次从数据库读取，取消管理员权限后立即生效
+func (a *App) requireAdmin(w http.ResponseWriter, r *http.Request) (*models.User, bool) {
+	session := utils.GetUserFromSession(r)
+	if session == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return nil, false
+	}
+
+	user, err := a.Users.FindByID(r.Context(), session.ID)
+	if errors.Is(err, db.ErrNotFound) {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return nil, false
+	}
+	if err != nil {
+		writeError(w, r, err, "无法验证管理员权限")
+		return nil, false
+	}
+	if !user.IsAdmin {
+		http.Error(w, "需要管理员权限", http.StatusForbidden)
+		return nil, false
+	}
+	return user, true
+}
+
+// BackupsHandler 显示数据库备份列表，只有管理员可以访问
+func (a *App) BackupsHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := a.requireAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	backups, err := backup.List(a.Config.Backup.Dir)
+	if err != nil {
+		writeError(w, r, err, "无法获取备份列表")
+		return
+	}
+
+	data := map[string]interface{}{
+		"Title":     "数据库备份",
+		"User":      user,
+		"Backups":   backups,
+		"Supported": a.store.CanSnapshot(),
+		"Config":    a.Config.Backup,
+	}
+
+	a.render(w, "admin/backups.html", data)
+}
+
+// CreateBackupHandler 处理立即备份请求
+func (a *App) CreateBackupHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "方法不允许", http.StatusMethodNotAllowed)
+		return
+	}
+	if _, ok := a.requireAdmin(w, r); !ok {
+		return
+	}
+
+	info, err := backup.Create(r.Context(), a.store, a.Config.Backup)
+	if err != nil {
+		writeError(w, r, err, "备份数据库失败")
+		return
+	}
+	log.Printf("已备份数据库: %s", info.Name)
+
+	http.Redirect(w, r, "/admin/backups", http.StatusSeeOther)
+}
+
+// DownloadBackupHandler 下载一个备份文件，路由为 /admin/backups/download/{文件名}
+func (a *App) DownloadBackupHandler(w http.ResponseWriter, r *http.Request) {
+	if _, ok := a.requireAdmin(w, r); !ok {
+		return
+	}
+
+	name := strings.TrimPrefix(r.URL.Path, "/admin/backups/download/")
+	path, err := backup.Path(a.Config.Backup.Dir, name)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		writeError(w, r, err, "无法读取备份")
+		return
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		writeError(w, r, err, "无法读取备份")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+name+`"`)
+	http.ServeContent(w, r, name, stat.ModTime(), file)
+}
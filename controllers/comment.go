@@ -0,0 +1,243 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"goblog/db"
+	"goblog/models"
+	"goblog/utils"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// commentURL 返回评论在文章页面中的位置
+func (a *App) commentURL(post *models.Post, comment *models.Comment) string {
+	return a.postURL(post) + "#comment-" + strconv.Itoa(comment.ID)
+}
+
+// validateComment 检查评论内容，返回错误提示，内容有效时返回空字符串
+func validateComment(content string) string {
+	if content == "" {
+		return "评论内容不能为空"
+	}
+	if utf8.RuneCountInString(content) > models.MaxCommentLength {
+		return "评论内容不能超过" + strconv.Itoa(models.MaxCommentLength) + "个字符"
+	}
+	return ""
+}
+
+// attachCommentCounts 为文章列表填充评论数，失败时只记录日志，不影响页面显示
+func (a *App) attachCommentCounts(ctx context.Context, posts []*models.Post) {
+	if len(posts) == 0 {
+		return
+	}
+
+	ids := make([]int, len(posts))
+	for i, post := range posts {
+		ids[i] = post.ID
+	}
+
+	counts, err := a.Comments.CountByPosts(ctx, ids)
+	if err != nil {
+		log.Printf("获取评论数错误: %v", err)
+		return
+	}
+	for _, post := range posts {
+		post.CommentCount = counts[post.ID]
+	}
+}
+
+// CreateCommentHandler 处理发表评论请求，parent_id 不为空时作为对该评论的回复
+func (a *App) CreateCommentHandler(w http.ResponseWriter, r *http.Request, postID int) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "方法不允许", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// 检查用户是否已登录
+	user := utils.GetUserFromSession(r)
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	// 获取文章，不能评论看不到的文章
+	post, err := a.Posts.FindByID(r.Context(), postID)
+	if err != nil {
+		writeError(w, r, err, "无法获取文章")
+		return
+	}
+	if !post.CanView(user) {
+		http.NotFound(w, r)
+		return
+	}
+
+	// 解析表单
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "表单解析错误", http.StatusBadRequest)
+		return
+	}
+
+	content := strings.TrimSpace(r.FormValue("content"))
+	if msg := validateComment(content); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	comment := &models.Comment{
+		PostID:  post.ID,
+		UserID:  user.ID,
+		Content: content,
+	}
+
+	// 回复的评论必须属于同一篇文章且未被删除
+	if parent := r.FormValue("parent_id"); parent != "" {
+		parentID, err := strconv.Atoi(parent)
+		if err != nil {
+			http.Error(w, "无效的回复对象", http.StatusBadRequest)
+			return
+		}
+		parentComment, err := a.Comments.FindByID(r.Context(), parentID)
+		if err != nil && !errors.Is(err, db.ErrNotFound) {
+			writeError(w, r, err, "无法发表评论")
+			return
+		}
+		if err != nil || parentComment.PostID != post.ID {
+			http.Error(w, "回复的评论不存在", http.StatusBadRequest)
+			return
+		}
+		comment.ParentID = parentComment.ID
+	}
+
+	if err := a.Comments.Create(r.Context(), comment); err != nil {
+		writeError(w, r, err, "无法发表评论")
+		return
+	}
+
+	http.Redirect(w, r, a.commentURL(post, comment), http.StatusSeeOther)
+}
+
+// EditCommentFormHandler 处理编辑评论表单请求
+func (a *App) EditCommentFormHandler(w http.ResponseWriter, r *http.Request) {
+	comment, post, user, ok := a.findCommentForEdit(w, r, "/comments/edit/")
+	if !ok {
+		return
+	}
+
+	data := map[string]interface{}{
+		"Title":   "编辑评论",
+		"Comment": comment,
+		"Post":    post,
+		"User":    user,
+	}
+
+	a.render(w, "comments/edit.html", data)
+}
+
+// UpdateCommentHandler 处理更新评论请求
+func (a *App) UpdateCommentHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "方法不允许", http.StatusMethodNotAllowed)
+		return
+	}
+
+	comment, post, _, ok := a.findCommentForEdit(w, r, "/comments/update/")
+	if !ok {
+		return
+	}
+
+	// 解析表单
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "表单解析错误", http.StatusBadRequest)
+		return
+	}
+
+	content := strings.TrimSpace(r.FormValue("content"))
+	if msg := validateComment(content); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	comment.Content = content
+	if err := a.Comments.Update(r.Context(), comment); err != nil {
+		writeError(w, r, err, "无法更新评论")
+		return
+	}
+
+	http.Redirect(w, r, a.commentURL(post, comment), http.StatusSeeOther)
+}
+
+// DeleteCommentHandler 处理删除评论请求，评论作者、文章作者和管理员可以删除
+func (a *App) DeleteCommentHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "方法不允许", http.StatusMethodNotAllowed)
+		return
+	}
+
+	comment, post, user, ok := a.findComment(w, r, "/comments/delete/")
+	if !ok {
+		return
+	}
+
+	if !comment.CanDelete(user, post) {
+		http.Error(w, "没有权限删除该评论", http.StatusForbidden)
+		return
+	}
+
+	if err := a.Comments.Delete(r.Context(), comment.ID); err != nil {
+		writeError(w, r, err, "无法删除评论")
+		return
+	}
+
+	http.Redirect(w, r, a.postURL(post)+"#comments", http.StatusSeeOther)
+}
+
+// findCommentForEdit 获取评论并检查当前用户是否为评论作者，失败时已写入响应
+func (a *App) findCommentForEdit(w http.ResponseWriter, r *http.Request, prefix string) (*models.Comment, *models.Post, *models.User, bool) {
+	comment, post, user, ok := a.findComment(w, r, prefix)
+	if !ok {
+		return nil, nil, nil, false
+	}
+
+	if !comment.CanEdit(user) {
+		http.Error(w, "没有权限编辑该评论", http.StatusForbidden)
+		return nil, nil, nil, false
+	}
+
+	return comment, post, user, true
+}
+
+// findComment 从URL中解析评论ID，获取未删除的评论及其所属文章，要求用户已登录，失败时已写入响应
+func (a *App) findComment(w http.ResponseWriter, r *http.Request, prefix string) (*models.Comment, *models.Post, *models.User, bool) {
+	// 检查用户是否已登录
+	user := utils.GetUserFromSession(r)
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return nil, nil, nil, false
+	}
+
+	// 从URL中提取评论ID
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, prefix))
+	if err != nil {
+		http.NotFound(w, r)
+		return nil, nil, nil, false
+	}
+
+	comment, err := a.Comments.FindByID(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err, "无法获取评论")
+		return nil, nil, nil, false
+	}
+
+	// 获取评论所属的文章，用于生成链接和检查文章作者权限
+	post, err := a.Posts.FindByID(r.Context(), comment.PostID)
+	if err != nil {
+		writeError(w, r, err, "无法获取文章")
+		return nil, nil, nil, false
+	}
+
+	return comment, post, user, true
+}
@@ -0,0 +1,54 @@
+package controllers
+
+import (
+	"errors"
+	"goblog/models"
+	"goblog/utils"
+	"net/http"
+	"time"
+)
+
+// publishAtLayout 定时发布时间输入框（datetime-local）使用的格式
+const publishAtLayout = "2006-01-02T15:04"
+
+// setStatus 从表单读取发布状态和定时发布时间写入文章，定时发布时间按服务器本地时区解析
+func setStatus(post *models.Post, r *http.Request) error {
+	status, err := models.ParsePostStatus(r.FormValue("status"))
+	if err != nil {
+		return err
+	}
+
+	var publishAt time.Time
+	if status == models.StatusScheduled {
+		publishAt, err = time.ParseInLocation(publishAtLayout, r.FormValue("publish_at"), time.Local)
+		if err != nil {
+			return errors.New("无效的定时发布时间")
+		}
+	}
+
+	return post.SetStatus(status, publishAt, time.Now().Truncate(time.Second))
+}
+
+// DraftsHandler 处理“我的草稿”页面请求，列出当前用户的草稿和定时文章
+func (a *App) DraftsHandler(w http.ResponseWriter, r *http.Request) {
+	// 检查用户是否已登录
+	user := utils.GetUserFromSession(r)
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	posts, err := a.Posts.Drafts(r.Context(), user.ID)
+	if err != nil {
+		writeError(w, r, err, "无法获取草稿")
+		return
+	}
+
+	data := map[string]interface{}{
+		"Title": "我的草稿",
+		"Posts": posts,
+		"User":  user,
+	}
+
+	a.render(w, "drafts.html", data)
+}
@@ -0,0 +1,48 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"goblog/db"
+	"log"
+	"net/http"
+)
+
+// fieldLabels 违反唯一约束的列在提示中显示的名称
+var fieldLabels = map[string]string{
+	"username":      "用户名",
+	"email":         "邮箱",
+	"slug":          "别名",
+	"name":          "名称",
+	"path":          "分类",
+	"user_id, hash": "文件",
+}
+
+// writeError 把存储返回的错误转换为响应，所有处理器统一使用：记录不存在返回404，
+// 分类名无效返回400，违反唯一约束返回409并说明重复的内容，记录已被修改返回409，查询超时记录日志后返回503，
+// 客户端断开连接时不再响应，其他错误（如数据库不可用）记录日志后返回500，msg 是此时显示给用户的提示
+func writeError(w http.ResponseWriter, r *http.Request, err error, msg string) {
+	var dup *db.DuplicateError
+	switch {
+	case errors.Is(err, db.ErrNotFound):
+		http.NotFound(w, r)
+	case errors.As(err, &dup):
+		label, ok := fieldLabels[dup.Field]
+		if !ok {
+			label = "记录"
+		}
+		http.Error(w, label+"已存在", http.StatusConflict)
+	case errors.Is(err, db.ErrInvalidCategory):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, db.ErrConflict):
+		http.Error(w, "内容已被其他人修改，请刷新后重试", http.StatusConflict)
+	case errors.Is(err, context.Canceled) && r.Context().Err() != nil:
+		// 客户端已经断开，响应无法送达
+	case errors.Is(err, context.DeadlineExceeded):
+		log.Printf("%s: %s %s: 数据库查询超时: %v", msg, r.Method, r.URL.Path, err)
+		http.Error(w, "服务器繁忙，请稍后重试", http.StatusServiceUnavailable)
+	default:
+		log.Printf("%s: %s %s: %v", msg, r.Method, r.URL.Path, err)
+		http.Error(w, msg, http.StatusInternalServerError)
+	}
+}
@@ -1,19 +1,17 @@
 package controllers
 
 import (
-	"goblog/db"
 	"goblog/models"
 	"goblog/utils"
-	"html/template"
 	"log"
 	"net/http"
-	"time"
 )
 
 // HomeHandler 处理首页请求
-func HomeHandler(w http.ResponseWriter, r *http.Request) {
+func (a *App) HomeHandler(w http.ResponseWriter, r *http.Request) {
+	// 其他未注册的路径按文章固定链接处理
 	if r.URL.Path != "/" {
-		http.NotFound(w, r)
+		a.PermalinkHandler(w, r)
 		return
 	}
 
@@ -25,52 +23,27 @@ func HomeHandler(w http.ResponseWriter, r *http.Request) {
 
 	// 预设空文章列表
 	posts := []*models.Post{}
+	hasMore := false
 
-	// 尝试获取文章，但如果失败也继续显示页面
-	store, err := db.NewSQLiteStore("./goblog.db")
+	// 尝试获取最新文章，但如果失败也继续显示页面
+	page, err := a.Posts.List(r.Context(), models.ListOptions{Limit: a.Config.Blog.HomePosts})
 	if err != nil {
-		log.Printf("数据库连接错误: %v", err)
+		log.Printf("获取文章错误: %v", err)
 	} else {
-		defer store.Close()
-
-		// 尝试获取文章
-		retrievedPosts, err := store.FindAllPosts()
-		if err != nil {
-			log.Printf("获取文章错误: %v", err)
-		} else if retrievedPosts != nil {
-			posts = retrievedPosts
-		}
+		posts = page.Posts
+		hasMore = page.Next != nil
 	}
+	a.attachCommentCounts(r.Context(), posts)
 
 	log.Printf("获取到 %d 篇文章", len(posts))
 
-	// 渲染模板 - 修复模板解析方式
-	files := []string{
-		"templates/base.html",
-		"templates/home.html",
-	}
-
-	tmpl, err := template.ParseFiles(files...)
-	if err != nil {
-		log.Printf("模板解析错误: %v", err)
-		http.Error(w, "模板解析错误", http.StatusInternalServerError)
-		return
-	}
-
-	// 获取当前年份
-	currentYear := time.Now().Year()
-
 	data := map[string]interface{}{
-		"Title":       "博客首页",
-		"Posts":       posts,
-		"User":        user,
-		"CurrentYear": currentYear,
+		"Title":   "博客首页",
+		"Posts":   posts,
+		"HasMore": hasMore,
+		"User":    user,
 	}
 
-	log.Println("开始渲染模板")
-	if err := tmpl.ExecuteTemplate(w, "base", data); err != nil {
-		log.Printf("模板渲染错误: %v", err)
-		http.Error(w, "模板渲染错误", http.StatusInternalServerError)
-	}
+	a.render(w, "home.html", data)
 	log.Println("首页请求处理完成")
 }
@@ -0,0 +1,116 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"goblog/imaging"
+	"goblog/models"
+	"html"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// imageSizes 文章图片的 sizes 属性，正文在宽屏上最多约1100像素宽
+const imageSizes = "(max-width: 1200px) 100vw, 1100px"
+
+// uploadImagePattern 匹配文章HTML中引用上传图片的 <img> 标签，第一个分组为内容哈希
+var uploadImagePattern = regexp.MustCompile(`<img [^>]*?src="/uploads/[0-9a-f]{2}/([0-9a-f]{64})\.[a-z]+"[^>]*>`)
+
+// srcset 返回图片的 srcset 属性值，包括所有比原图小的缩放版本和原图，没有缩放版本时返回空字符串
+func (a *App) srcset(media *models.Media) string {
+	if !a.images.HasVariants(media.MIMEType) {
+		return ""
+	}
+	variantType := a.images.VariantType(media.MIMEType)
+	var candidates []string
+	for _, width := range a.images.Widths(media.Width) {
+		name := imaging.WidthName(width)
+		if media.HasVariant(name) {
+			candidates = append(candidates, media.VariantURL(name, variantType)+" "+name)
+		}
+	}
+	if len(candidates) == 0 {
+		return ""
+	}
+	candidates = append(candidates, media.URL()+" "+imaging.WidthName(media.Width))
+	return strings.Join(candidates, ", ")
+}
+
+// thumbURL 返回媒体库中显示的缩略图地址，GIF等没有缩略图的图片和缩略图比原图大的图片使用原图
+func (a *App) thumbURL(media *models.Media) string {
+	if !a.images.HasVariants(media.MIMEType) || !media.HasVariant(imaging.ThumbName) {
+		return media.URL()
+	}
+	return media.VariantURL(imaging.ThumbName, a.images.VariantType(media.MIMEType))
+}
+
+// saveVariants 生成并保存图片的缩放版本和缩略图，没有生成的版本记录在 media.SkippedVariants 中
+func (a *App) saveVariants(ctx context.Context, media *models.Media, data []byte) error {
+	variants, skipped, err := a.images.Variants(ctx, data, media.MIMEType)
+	if err != nil {
+		return err
+	}
+	media.SkippedVariants = skipped
+	for _, variant := range variants {
+		key := media.VariantKey(variant.Name, variant.MIMEType)
+		if err := a.uploads.Put(key, bytes.NewReader(variant.Data), int64(len(variant.Data)), variant.MIMEType); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteVariants 删除按当前配置生成的缩放版本和缩略图，失败只记录日志
+func (a *App) deleteVariants(media *models.Media) {
+	if !a.images.HasVariants(media.MIMEType) {
+		return
+	}
+	variantType := a.images.VariantType(media.MIMEType)
+	for _, name := range a.images.VariantNames(media.Width) {
+		if !media.HasVariant(name) {
+			continue
+		}
+		key := media.VariantKey(name, variantType)
+		if err := a.uploads.Delete(key); err != nil {
+			log.Printf("删除文件 %s 失败: %v", key, err)
+		}
+	}
+}
+
+// responsiveImages 给文章HTML中引用上传图片的 <img> 标签添加 srcset、sizes 和宽高，
+// 浏览器按屏幕宽度选择合适的版本。在缓存的渲染结果之后处理，修改图片配置后不需要清除缓存
+func (a *App) responsiveImages(ctx context.Context, content string) string {
+	matches := uploadImagePattern.FindAllStringSubmatch(content, -1)
+	if len(matches) == 0 {
+		return content
+	}
+
+	hashes := make([]string, 0, len(matches))
+	for _, match := range matches {
+		hashes = append(hashes, match[1])
+	}
+	media, err := a.Media.FindByHashes(ctx, hashes)
+	if err != nil {
+		log.Printf("查询文章图片失败: %v", err)
+		return content
+	}
+
+	return uploadImagePattern.ReplaceAllStringFunc(content, func(tag string) string {
+		m, ok := media[uploadImagePattern.FindStringSubmatch(tag)[1]]
+		if !ok || strings.Contains(tag, " srcset=") {
+			return tag
+		}
+
+		attrs := ` width="` + strconv.Itoa(m.Width) + `" height="` + strconv.Itoa(m.Height) + `" loading="lazy"`
+		if srcset := a.srcset(m); srcset != "" {
+			attrs += ` srcset="` + html.EscapeString(srcset) + `" sizes="` + imageSizes + `"`
+		}
+		end := len(tag) - 1
+		if strings.HasSuffix(tag, "/>") {
+			end--
+		}
+		return strings.TrimRight(tag[:end], " ") + attrs + tag[end:]
+	})
+}
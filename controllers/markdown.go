@@ -0,0 +1,36 @@
+package controllers
+
+import (
+	"context"
+	"goblog/models"
+	"goblog/utils"
+	"html/template"
+	"log"
+)
+
+// postContent 返回文章正文的HTML
+// 纯文本文章只做转义，Markdown文章使用最新版本中缓存的渲染结果，未缓存时渲染并写回缓存，
+// 最后给其中的上传图片添加响应式属性
+func (a *App) postContent(ctx context.Context, post *models.Post) (template.HTML, error) {
+	if post.Format != models.FormatMarkdown {
+		return template.HTML(template.HTMLEscapeString(post.Content)), nil
+	}
+
+	// 最新版本与文章内容一致时才能使用其缓存，查找失败时直接渲染
+	revision, err := a.Posts.LatestRevision(ctx, post.ID)
+	cacheable := err == nil && revision.Content == post.Content && revision.Format == post.Format
+	if cacheable && revision.HTML != "" {
+		return template.HTML(a.responsiveImages(ctx, revision.HTML)), nil
+	}
+
+	html, err := utils.RenderMarkdown(post.Content)
+	if err != nil {
+		return "", err
+	}
+	if cacheable {
+		if err := a.Posts.SaveRevisionHTML(ctx, revision.ID, html); err != nil {
+			log.Printf("缓存文章 %d 的渲染结果失败: %v", post.ID, err)
+		}
+	}
+	return template.HTML(a.responsiveImages(ctx, html)), nil
+}
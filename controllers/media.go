@@ -0,0 +1,357 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"goblog/db"
+	"goblog/imaging"
+	"goblog/models"
+	"goblog/storage"
+	"goblog/utils"
+	"io"
+	"log"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxFileNameLength 保存的原始文件名的最大字符数
+const maxFileNameLength = 255
+
+// MediaHandler 显示当前用户上传的文件
+func (a *App) MediaHandler(w http.ResponseWriter, r *http.Request) {
+	user := utils.GetUserFromSession(r)
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	media, err := a.Media.ListByUser(r.Context(), user.ID)
+	if err != nil {
+		writeError(w, r, err, "无法获取文件列表")
+		return
+	}
+
+	data := map[string]interface{}{
+		"Title":     "媒体库",
+		"User":      user,
+		"Media":     media,
+		"MaxSizeMB": a.Config.Upload.MaxSizeMB,
+	}
+
+	a.render(w, "media/index.html", data)
+}
+
+// UploadMediaHandler 处理文件上传请求，表单字段为 file。
+// 请求头 Accept 包含 application/json 时返回文件信息，供编辑器插入文章，否则重定向到媒体库
+func (a *App) UploadMediaHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "方法不允许", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user := utils.GetUserFromSession(r)
+	if user == nil {
+		http.Error(w, "请先登录", http.StatusUnauthorized)
+		return
+	}
+
+	// 限制请求体大小，为表单的其他部分留出1MB余量
+	maxSize := int64(a.Config.Upload.MaxSizeMB) << 20
+	tooLarge := fmt.Sprintf("文件不能超过%dMB", a.Config.Upload.MaxSizeMB)
+	r.Body = http.MaxBytesReader(w, r.Body, maxSize+1<<20)
+	if err := r.ParseMultipartForm(1 << 20); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			http.Error(w, tooLarge, http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "表单解析错误", http.StatusBadRequest)
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "请选择要上传的文件", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxSize+1))
+	if err != nil {
+		http.Error(w, "读取文件失败", http.StatusBadRequest)
+		return
+	}
+	if int64(len(data)) > maxSize {
+		http.Error(w, tooLarge, http.StatusRequestEntityTooLarge)
+		return
+	}
+	if len(data) == 0 {
+		http.Error(w, "不能上传空文件", http.StatusBadRequest)
+		return
+	}
+
+	media, err := a.saveMedia(r.Context(), user, header.Filename, data, r.FormValue("private") != "")
+	if err != nil {
+		if errors.Is(err, utils.ErrUnsupportedMedia) {
+			http.Error(w, "不支持的文件类型，只能上传JPEG、PNG、GIF、WebP图片和PDF、ZIP、纯文本文件", http.StatusUnsupportedMediaType)
+			return
+		}
+		if errors.Is(err, imaging.ErrTooManyPixels) {
+			http.Error(w, fmt.Sprintf("图片尺寸过大，宽×高不能超过%d像素", a.images.MaxPixels()), http.StatusRequestEntityTooLarge)
+			return
+		}
+		writeError(w, r, err, "保存文件失败")
+		return
+	}
+
+	if strings.Contains(r.Header.Get("Accept"), "application/json") {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":       media.ID,
+			"url":      media.URL(),
+			"markdown": media.Markdown(),
+			"private":  media.Private,
+		})
+		return
+	}
+
+	http.Redirect(w, r, "/media", http.StatusSeeOther)
+}
+
+// saveMedia 保存文件内容并创建文件记录，用户上传过相同内容时返回已有的记录，可见范围不变
+func (a *App) saveMedia(ctx context.Context, user *models.User, fileName string, data []byte, private bool) (*models.Media, error) {
+	mimeType, width, height, err := utils.DetectMedia(data)
+	if err != nil {
+		return nil, err
+	}
+
+	// 图片先校正方向并删除元数据，按处理后的内容计算哈希
+	if strings.HasPrefix(mimeType, "image/") {
+		data, width, height, err = a.images.Clean(data, mimeType)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+	existing, err := a.Media.FindByHash(ctx, user.ID, hash)
+	if err == nil {
+		return existing, nil
+	}
+	if !errors.Is(err, db.ErrNotFound) {
+		return nil, err
+	}
+
+	media := &models.Media{
+		UserID:   user.ID,
+		FileName: cleanFileName(fileName, mimeType),
+		Hash:     hash,
+		MIMEType: mimeType,
+		Size:     int64(len(data)),
+		Width:    width,
+		Height:   height,
+		Private:  private,
+	}
+
+	// 先保存内容和图片版本再创建记录，记录存在时文件一定存在
+	if err := a.uploads.Put(media.Key(), bytes.NewReader(data), media.Size, mimeType); err != nil {
+		return nil, err
+	}
+	if err := a.saveVariants(ctx, media, data); err != nil {
+		return nil, err
+	}
+	if err := a.Media.Create(ctx, media); err != nil {
+		// 同一用户同时上传相同文件时，其中一个请求会违反唯一约束，返回另一个请求创建的记录
+		if errors.Is(err, db.ErrDuplicate) {
+			return a.Media.FindByHash(ctx, user.ID, hash)
+		}
+		return nil, err
+	}
+	return media, nil
+}
+
+// cleanFileName 去掉客户端文件名中的路径并限制长度，为空时按类型生成
+func cleanFileName(name, mimeType string) string {
+	name = strings.TrimSpace(filepath.Base(strings.ReplaceAll(name, "\\", "/")))
+	if name == "." || name == "/" || name == "" {
+		return "file" + models.MediaTypes[mimeType]
+	}
+	if utf8.RuneCountInString(name) > maxFileNameLength {
+		name = string([]rune(name)[:maxFileNameLength])
+	}
+	return name
+}
+
+// DeleteMediaHandler 处理删除文件请求，没有其他记录引用相同内容时删除文件内容和图片版本
+func (a *App) DeleteMediaHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "方法不允许", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user := utils.GetUserFromSession(r)
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/media/delete/"))
+	if err != nil {
+		http.Error(w, "无效的文件ID", http.StatusBadRequest)
+		return
+	}
+
+	media, err := a.Media.FindByID(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err, "无法获取文件")
+		return
+	}
+	if !media.CanDelete(user) {
+		http.Error(w, "没有权限删除该文件", http.StatusForbidden)
+		return
+	}
+
+	if err := a.Media.Delete(r.Context(), id); err != nil {
+		writeError(w, r, err, "无法删除文件")
+		return
+	}
+
+	// 文件内容可能被其他用户的记录引用，删除失败只记录日志
+	if count, err := a.Media.CountByHash(r.Context(), media.Hash); err != nil {
+		log.Printf("统计文件 %s 的引用失败: %v", media.Hash, err)
+	} else if count == 0 {
+		if err := a.uploads.Delete(media.Key()); err != nil {
+			log.Printf("删除文件 %s 失败: %v", media.Key(), err)
+		}
+		a.deleteVariants(media)
+	}
+
+	http.Redirect(w, r, "/media", http.StatusSeeOther)
+}
+
+// SetMediaVisibilityHandler 处理修改文件可见范围的请求，表单字段 private 非空时设为私有
+func (a *App) SetMediaVisibilityHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "方法不允许", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user := utils.GetUserFromSession(r)
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/media/visibility/"))
+	if err != nil {
+		http.Error(w, "无效的文件ID", http.StatusBadRequest)
+		return
+	}
+
+	media, err := a.Media.FindByID(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err, "无法获取文件")
+		return
+	}
+	if !media.CanEdit(user) {
+		http.Error(w, "没有权限修改该文件", http.StatusForbidden)
+		return
+	}
+
+	if err := a.Media.SetPrivate(r.Context(), id, r.FormValue("private") != ""); err != nil {
+		writeError(w, r, err, "无法修改文件")
+		return
+	}
+
+	http.Redirect(w, r, "/media", http.StatusSeeOther)
+}
+
+// uploadKeyPattern 匹配上传文件及其图片版本的键，第一个分组为内容哈希
+var uploadKeyPattern = regexp.MustCompile(`^[0-9a-f]{2}/([0-9a-f]{64})(-[0-9a-z]+)?\.[a-z]+$`)
+
+// serveUpload 返回上传文件的内容。相同内容可能被多条记录引用，任一记录公开时所有人都能访问，
+// 全部为私有时只有上传过该内容的用户和管理员可以访问，其他人得到404，不暴露文件是否存在
+func (a *App) serveUpload(w http.ResponseWriter, r *http.Request) {
+	key := strings.TrimPrefix(r.URL.Path, "/")
+	m := uploadKeyPattern.FindStringSubmatch(key)
+	if m == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	records, err := a.Media.ListByHash(r.Context(), m[1])
+	if err != nil {
+		writeError(w, r, err, "无法读取文件")
+		return
+	}
+	if len(records) == 0 {
+		http.NotFound(w, r)
+		return
+	}
+
+	private := true
+	for _, record := range records {
+		if !record.Private {
+			private = false
+			break
+		}
+	}
+	if private {
+		allowed, err := a.canViewPrivate(r, records)
+		if err != nil {
+			writeError(w, r, err, "无法读取文件")
+			return
+		}
+		if !allowed {
+			http.NotFound(w, r)
+			return
+		}
+	}
+
+	storage.Serve(w, r, a.uploads, key, private)
+}
+
+// canViewPrivate 判断当前用户能否访问私有文件：上传过该内容的用户和数据库中标记的管理员可以访问
+func (a *App) canViewPrivate(r *http.Request, records []*models.Media) (bool, error) {
+	session := utils.GetUserFromSession(r)
+	if session == nil {
+		return false, nil
+	}
+	for _, record := range records {
+		if record.UserID == session.ID {
+			return true, nil
+		}
+	}
+
+	user, err := a.Users.FindByID(r.Context(), session.ID)
+	if errors.Is(err, db.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return user.IsAdmin, nil
+}
+
+// fileSize 把字节数格式化为便于阅读的大小
+func fileSize(size int64) string {
+	switch {
+	case size >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(size)/(1<<20))
+	case size >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(size)/(1<<10))
+	default:
+		return fmt.Sprintf("%d B", size)
+	}
+}
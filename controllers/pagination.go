@@ -0,0 +1,73 @@
+package controllers
+
+import (
+	"goblog/models"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// listOptionsFromRequest 从查询参数 limit、after、before、sort 解析分页参数，
+// limit 缺少或小于1时使用 defaultLimit
+func listOptionsFromRequest(r *http.Request, defaultLimit int) (models.ListOptions, error) {
+	query := r.URL.Query()
+	opts := models.ListOptions{
+		Limit: defaultLimit,
+		Sort:  models.ParsePostSort(query.Get("sort")),
+	}
+
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil {
+			return opts, err
+		}
+		if n >= 1 {
+			opts.Limit = n
+		}
+	}
+
+	if after := query.Get("after"); after != "" {
+		cursor, err := models.ParseCursor(after)
+		if err != nil {
+			return opts, err
+		}
+		opts.After = cursor
+	} else if before := query.Get("before"); before != "" {
+		cursor, err := models.ParseCursor(before)
+		if err != nil {
+			return opts, err
+		}
+		opts.Before = cursor
+	}
+
+	opts.Normalize()
+	return opts, nil
+}
+
+// pageURLs 生成上一页和下一页的链接，保留排序等其余查询参数，没有对应页时返回空字符串
+func pageURLs(r *http.Request, page *models.PostPage) (prev, next string) {
+	link := func(key string, cursor *models.Cursor) string {
+		query := r.URL.Query()
+		query.Del("after")
+		query.Del("before")
+		query.Set(key, cursor.String())
+		return (&url.URL{Path: r.URL.Path, RawQuery: query.Encode()}).String()
+	}
+
+	if page.Prev != nil {
+		prev = link("before", page.Prev)
+	}
+	if page.Next != nil {
+		next = link("after", page.Next)
+	}
+	return prev, next
+}
+
+// sortURL 生成切换排序方式的链接，从第一页开始
+func sortURL(r *http.Request, sort models.PostSort) string {
+	query := r.URL.Query()
+	query.Del("after")
+	query.Del("before")
+	query.Set("sort", string(sort))
+	return (&url.URL{Path: r.URL.Path, RawQuery: query.Encode()}).String()
+}
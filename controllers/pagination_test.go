@@ -0,0 +1,290 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"goblog/config"
+	"goblog/db"
+	"goblog/models"
+	"html"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestListOptionsFromRequest(t *testing.T) {
+	cursor := &models.Cursor{CreatedAt: time.Unix(1700000000, 0).UTC(), ID: 42}
+	other := &models.Cursor{CreatedAt: time.Unix(1600000000, 0).UTC(), ID: 7}
+
+	tests := []struct {
+		name    string
+		query   string
+		want    models.ListOptions
+		wantErr bool
+	}{
+		{name: "默认参数", query: "", want: models.ListOptions{Limit: 5, Sort: models.SortNewest}},
+		{name: "指定数量", query: "limit=20", want: models.ListOptions{Limit: 20, Sort: models.SortNewest}},
+		{name: "数量为0时使用默认值", query: "limit=0", want: models.ListOptions{Limit: 5, Sort: models.SortNewest}},
+		{name: "负数数量", query: "limit=-3", want: models.ListOptions{Limit: 5, Sort: models.SortNewest}},
+		{name: "数量超过上限", query: "limit=1000", want: models.ListOptions{Limit: models.MaxPageSize, Sort: models.SortNewest}},
+		{name: "数量不是数字", query: "limit=abc", wantErr: true},
+		{name: "数量为空时使用默认值", query: "limit=", want: models.ListOptions{Limit: 5, Sort: models.SortNewest}},
+		{name: "最早发布排序", query: "sort=oldest", want: models.ListOptions{Limit: 5, Sort: models.SortOldest}},
+		{name: "无法识别的排序", query: "sort=random", want: models.ListOptions{Limit: 5, Sort: models.SortNewest}},
+		{name: "下一页", query: "after=" + cursor.String(), want: models.ListOptions{Limit: 5, After: cursor, Sort: models.SortNewest}},
+		{name: "上一页", query: "before=" + cursor.String(), want: models.ListOptions{Limit: 5, Before: cursor, Sort: models.SortNewest}},
+		{name: "同时指定时after优先", query: "before=" + other.String() + "&after=" + cursor.String(), want: models.ListOptions{Limit: 5, After: cursor, Sort: models.SortNewest}},
+		{name: "after有效时忽略无效的before", query: "after=" + cursor.String() + "&before=bad", want: models.ListOptions{Limit: 5, After: cursor, Sort: models.SortNewest}},
+		{name: "无效的after", query: "after=bad", wantErr: true},
+		{name: "after缺少ID", query: "after=1700000000", wantErr: true},
+		{name: "after的ID不是数字", query: "after=1700000000_x", wantErr: true},
+		{name: "无效的before", query: "before=_42", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/posts?"+tt.query, nil)
+			got, err := listOptionsFromRequest(r, 5)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("期望返回错误，得到 %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("解析失败: %v", err)
+			}
+			if got.Limit != tt.want.Limit || got.Sort != tt.want.Sort {
+				t.Errorf("Limit、Sort 为 %d、%s，期望 %d、%s", got.Limit, got.Sort, tt.want.Limit, tt.want.Sort)
+			}
+			if !sameCursor(got.After, tt.want.After) {
+				t.Errorf("After 为 %v，期望 %v", got.After, tt.want.After)
+			}
+			if !sameCursor(got.Before, tt.want.Before) {
+				t.Errorf("Before 为 %v，期望 %v", got.Before, tt.want.Before)
+			}
+		})
+	}
+}
+
+// sameCursor 比较两个可能为nil的游标
+func sameCursor(a, b *models.Cursor) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.ID == b.ID && a.CreatedAt.Equal(b.CreatedAt)
+}
+
+func TestPageURLs(t *testing.T) {
+	prevCursor := &models.Cursor{CreatedAt: time.Unix(1700000000, 0).UTC(), ID: 9}
+	nextCursor := &models.Cursor{CreatedAt: time.Unix(1600000000, 0).UTC(), ID: 3}
+
+	tests := []struct {
+		name     string
+		target   string
+		page     *models.PostPage
+		wantPrev url.Values
+		wantNext url.Values
+	}{
+		{
+			name:   "保留排序和数量",
+			target: "/posts?sort=oldest&limit=5",
+			page:   &models.PostPage{Prev: prevCursor, Next: nextCursor},
+			wantPrev: url.Values{
+				"sort": {"oldest"}, "limit": {"5"}, "before": {prevCursor.String()},
+			},
+			wantNext: url.Values{
+				"sort": {"oldest"}, "limit": {"5"}, "after": {nextCursor.String()},
+			},
+		},
+		{
+			name:     "替换原有的游标",
+			target:   "/tags/go?after=1_1&before=2_2&sort=newest",
+			page:     &models.PostPage{Prev: prevCursor, Next: nextCursor},
+			wantPrev: url.Values{"sort": {"newest"}, "before": {prevCursor.String()}},
+			wantNext: url.Values{"sort": {"newest"}, "after": {nextCursor.String()}},
+		},
+		{
+			name:     "第一页没有上一页",
+			target:   "/posts?sort=oldest",
+			page:     &models.PostPage{Next: nextCursor},
+			wantNext: url.Values{"sort": {"oldest"}, "after": {nextCursor.String()}},
+		},
+		{
+			name:   "只有一页",
+			target: "/posts",
+			page:   &models.PostPage{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			prev, next := pageURLs(r, tt.page)
+			checkPageURL(t, "上一页", prev, r.URL.Path, tt.wantPrev)
+			checkPageURL(t, "下一页", next, r.URL.Path, tt.wantNext)
+		})
+	}
+}
+
+// checkPageURL 检查分页链接的路径和查询参数，want为nil时链接应为空
+func checkPageURL(t *testing.T, label, link, path string, want url.Values) {
+	t.Helper()
+	if want == nil {
+		if link != "" {
+			t.Errorf("%s链接为 %q，期望为空", label, link)
+		}
+		return
+	}
+
+	u, err := url.Parse(link)
+	if err != nil {
+		t.Fatalf("%s链接 %q 无法解析: %v", label, link, err)
+	}
+	if u.Path != path {
+		t.Errorf("%s链接的路径为 %q，期望 %q", label, u.Path, path)
+	}
+	if got := u.Query(); got.Encode() != want.Encode() {
+		t.Errorf("%s链接的参数为 %s，期望 %s", label, got.Encode(), want.Encode())
+	}
+}
+
+// nextLinkPattern 匹配文章列表页面中的下一页链接
+var nextLinkPattern = regexp.MustCompile(`<a href="([^"]*)" class="btn btn-secondary next">`)
+
+// prevLinkPattern 匹配文章列表页面中的上一页链接
+var prevLinkPattern = regexp.MustCompile(`<a href="([^"]*)" class="btn btn-secondary">&laquo;`)
+
+// newTestApp 使用内存数据库和仓库中的模板创建应用，posts 篇文章依次创建
+func newTestApp(t *testing.T, posts int) *App {
+	t.Helper()
+
+	// 模板以仓库根目录为基准加载
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(".."); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	cfg := &config.Config{
+		Database: config.DatabaseConfig{Type: "memory"},
+		Blog: config.BlogConfig{
+			PageSize:  2,
+			HomePosts: 2,
+			Permalink: "/{year}/{month}/{slug}",
+		},
+		Upload: config.UploadConfig{
+			Storage: "local",
+			Dir:     t.TempDir(),
+			Image:   config.ImageConfig{Widths: []int{480}, ThumbSize: 240, Format: "auto", Quality: 82},
+		},
+	}
+	store, err := db.Open(cfg.Database)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	ctx := context.Background()
+	user := &models.User{Username: "alice", Email: "alice@example.com", Password: "hash"}
+	if err := store.Users.Create(ctx, user); err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= posts; i++ {
+		post := &models.Post{Title: fmt.Sprintf("post %d", i), Content: "内容", UserID: user.ID, Status: models.StatusPublished}
+		if err := store.Posts.Create(ctx, post); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	a, err := NewApp(cfg, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+func TestListPostsHandlerInvalidParams(t *testing.T) {
+	a := newTestApp(t, 0)
+
+	for _, query := range []string{"limit=abc", "after=bad", "before=1_x", "after=1700000000"} {
+		rec := httptest.NewRecorder()
+		a.ListPostsHandler(rec, httptest.NewRequest(http.MethodGet, "/posts?"+query, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s 返回 %d，期望 400", query, rec.Code)
+		}
+	}
+}
+
+func TestListPostsHandlerPagination(t *testing.T) {
+	a := newTestApp(t, 5)
+
+	// get 请求文章列表，返回页面中文章的标题和上一页、下一页链接
+	get := func(target string) (titles []string, prev, next string) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		a.ListPostsHandler(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s 返回 %d: %s", target, rec.Code, rec.Body.String())
+		}
+		body := rec.Body.String()
+		for i := 1; i <= 5; i++ {
+			if strings.Contains(body, fmt.Sprintf(">post %d<", i)) {
+				titles = append(titles, fmt.Sprintf("post %d", i))
+			}
+		}
+		if m := prevLinkPattern.FindStringSubmatch(body); m != nil {
+			prev = html.UnescapeString(m[1])
+		}
+		if m := nextLinkPattern.FindStringSubmatch(body); m != nil {
+			next = html.UnescapeString(m[1])
+		}
+		return titles, prev, next
+	}
+
+	// 按最早发布排序逐页向后翻，排序参数在每一页的链接中保留
+	var seen []string
+	target := "/posts?sort=oldest"
+	for page := 1; target != ""; page++ {
+		if page > 3 {
+			t.Fatal("翻页没有结束")
+		}
+		titles, prev, next := get(target)
+		seen = append(seen, titles...)
+		if (page == 1) != (prev == "") {
+			t.Errorf("第 %d 页的上一页链接为 %q", page, prev)
+		}
+		for _, link := range []string{prev, next} {
+			if link == "" {
+				continue
+			}
+			u, err := url.Parse(link)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if u.Query().Get("sort") != "oldest" {
+				t.Errorf("第 %d 页的链接 %q 没有保留排序参数", page, link)
+			}
+		}
+		target = next
+	}
+	if want := []string{"post 1", "post 2", "post 3", "post 4", "post 5"}; strings.Join(seen, ",") != strings.Join(want, ",") {
+		t.Errorf("依次翻页得到 %v，期望 %v", seen, want)
+	}
+
+	// 从第二页返回上一页得到第一页的文章
+	_, _, next := get("/posts?sort=oldest")
+	_, prev, _ := get(next)
+	titles, _, _ := get(prev)
+	if strings.Join(titles, ",") != "post 1,post 2" {
+		t.Errorf("返回上一页得到 %v，期望 [post 1 post 2]", titles)
+	}
+}
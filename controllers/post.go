@@ -1,116 +1,178 @@
 package controllers
 
 import (
+	"errors"
 	"goblog/db"
 	"goblog/models"
 	"goblog/utils"
-	"html/template"
 	"net/http"
 	"strconv"
 	"strings"
-	"time"
 )
 
-// ListPostsHandler 处理文章列表请求
-func ListPostsHandler(w http.ResponseWriter, r *http.Request) {
-	// 获取存储实例
-	store, err := db.NewSQLiteStore("./goblog.db")
+// ListPostsHandler 处理文章列表请求，支持 limit、after、before、sort 分页参数
+func (a *App) ListPostsHandler(w http.ResponseWriter, r *http.Request) {
+	// 解析分页参数
+	opts, err := listOptionsFromRequest(r, a.Config.Blog.PageSize)
 	if err != nil {
-		http.Error(w, "服务器内部错误", http.StatusInternalServerError)
+		http.Error(w, "无效的分页参数", http.StatusBadRequest)
 		return
 	}
-	defer store.Close()
 
-	// 获取所有文章
-	posts, err := store.FindAllPosts()
+	// 获取当前页文章
+	page, err := a.Posts.List(r.Context(), opts)
 	if err != nil {
-		http.Error(w, "无法获取文章", http.StatusInternalServerError)
+		writeError(w, r, err, "无法获取文章")
 		return
 	}
 
 	// 获取当前用户
 	user := utils.GetUserFromSession(r)
 
-	// 渲染模板
-	tmpl, err := template.ParseFiles(
-		"templates/base.html",
-		"templates/posts/list.html",
-	)
-	if err != nil {
-		http.Error(w, "模板解析错误", http.StatusInternalServerError)
-		return
-	}
-
-	// 获取当前年份
-	currentYear := time.Now().Year()
+	a.attachCommentCounts(r.Context(), page.Posts)
+	prevURL, nextURL := pageURLs(r, page)
 
 	data := map[string]interface{}{
-		"Title":       "所有文章",
-		"Posts":       posts,
-		"User":        user,
-		"CurrentYear": currentYear,
+		"Title":     "所有文章",
+		"Posts":     page.Posts,
+		"User":      user,
+		"Sort":      string(opts.Sort),
+		"NewestURL": sortURL(r, models.SortNewest),
+		"OldestURL": sortURL(r, models.SortOldest),
+		"PrevURL":   prevURL,
+		"NextURL":   nextURL,
 	}
 
-	if err := tmpl.ExecuteTemplate(w, "base", data); err != nil {
-		http.Error(w, "模板渲染错误", http.StatusInternalServerError)
-	}
+	a.render(w, "posts/list.html", data)
 }
 
-// GetPostHandler 处理单个文章请求
-func GetPostHandler(w http.ResponseWriter, r *http.Request) {
-	// 从URL中提取文章ID
+// PostHandler 分发 /posts/{id} 及其子路径的请求，其他 /posts/ 下的路径按固定链接处理
+func (a *App) PostHandler(w http.ResponseWriter, r *http.Request) {
+	// 从URL中提取文章ID和子路径
 	path := strings.TrimPrefix(r.URL.Path, "/posts/")
-	id, err := strconv.Atoi(path)
+	idPart, sub, _ := strings.Cut(path, "/")
+	id, err := strconv.Atoi(idPart)
+	if err != nil {
+		a.PermalinkHandler(w, r)
+		return
+	}
+
+	switch {
+	case sub == "":
+		a.GetPostHandler(w, r, id)
+	case sub == "comments":
+		a.CreateCommentHandler(w, r, id)
+	case sub == "revisions":
+		a.RevisionsHandler(w, r, id)
+	case sub == "revisions/diff":
+		a.RevisionDiffHandler(w, r, id)
+	case strings.HasPrefix(sub, "revisions/") && strings.HasSuffix(sub, "/restore"):
+		revisionID, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(sub, "revisions/"), "/restore"))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		a.RestoreRevisionHandler(w, r, id, revisionID)
+	default:
+		a.PermalinkHandler(w, r)
+	}
+}
+
+// GetPostHandler 处理 /posts/{id} 请求，固定链接格式不是该路径时重定向到固定链接
+func (a *App) GetPostHandler(w http.ResponseWriter, r *http.Request, id int) {
+	post, err := a.Posts.FindByID(r.Context(), id)
 	if err != nil {
+		writeError(w, r, err, "无法获取文章")
+		return
+	}
+
+	a.showPost(w, r, post)
+}
+
+// PermalinkHandler 按配置的固定链接格式查找文章，旧别名和日期不符的链接重定向到当前的固定链接
+func (a *App) PermalinkHandler(w http.ResponseWriter, r *http.Request) {
+	match, ok := a.permalink.Match(r.URL.EscapedPath())
+	if !ok {
 		http.NotFound(w, r)
 		return
 	}
 
-	// 获取存储实例
-	store, err := db.NewSQLiteStore("./goblog.db")
+	var post *models.Post
+	var err error
+	if match.Slug != "" {
+		post, err = a.Posts.FindBySlug(r.Context(), match.Slug)
+	} else {
+		post, err = a.Posts.FindByID(r.Context(), match.ID)
+	}
 	if err != nil {
-		http.Error(w, "服务器内部错误", http.StatusInternalServerError)
+		writeError(w, r, err, "无法获取文章")
 		return
 	}
-	defer store.Close()
 
-	// 获取文章
-	post, err := store.FindPostByID(id)
-	if err != nil {
+	a.showPost(w, r, post)
+}
+
+// showPost 显示文章页面，请求路径不是文章当前的固定链接时永久重定向
+func (a *App) showPost(w http.ResponseWriter, r *http.Request, post *models.Post) {
+	// 获取当前用户，未发布的文章只有作者可见，检查可见性后再重定向，避免泄露别名
+	user := utils.GetUserFromSession(r)
+	if !post.CanView(user) {
 		http.NotFound(w, r)
 		return
 	}
 
-	// 获取当前用户
-	user := utils.GetUserFromSession(r)
+	if permalink := a.postURL(post); r.URL.EscapedPath() != permalink {
+		if r.URL.RawQuery != "" {
+			permalink += "?" + r.URL.RawQuery
+		}
+		http.Redirect(w, r, permalink, http.StatusMovedPermanently)
+		return
+	}
 
-	// 渲染模板
-	tmpl, err := template.ParseFiles(
-		"templates/base.html",
-		"templates/posts/show.html",
-	)
+	// 获取评论并组织为讨论树
+	comments, err := a.Comments.ListByPost(r.Context(), post.ID)
 	if err != nil {
-		http.Error(w, "模板解析错误", http.StatusInternalServerError)
+		writeError(w, r, err, "无法获取评论")
 		return
 	}
+	commentCount := 0
+	for _, comment := range comments {
+		if comment.DeletedAt == nil {
+			commentCount++
+		}
+	}
 
-	// 获取当前年份
-	currentYear := time.Now().Year()
+	// 渲染正文
+	content, err := a.postContent(r.Context(), post)
+	if err != nil {
+		http.Error(w, "无法渲染文章", http.StatusInternalServerError)
+		return
+	}
 
 	data := map[string]interface{}{
-		"Title":       post.Title,
-		"Post":        post,
-		"User":        user,
-		"CurrentYear": currentYear,
+		"Title":        post.Title,
+		"Post":         post,
+		"Content":      content,
+		"User":         user,
+		"CanHistory":   post.CanManageRevisions(user),
+		"Comments":     models.BuildCommentTree(comments, post, user),
+		"CommentCount": commentCount,
 	}
 
-	if err := tmpl.ExecuteTemplate(w, "base", data); err != nil {
-		http.Error(w, "模板渲染错误", http.StatusInternalServerError)
+	a.render(w, "posts/show.html", data)
+}
+
+// slugFromForm 根据表单中填写的别名生成文章别名，未填写时根据标题生成
+func (a *App) slugFromForm(r *http.Request, title string) string {
+	source := strings.TrimSpace(r.FormValue("slug"))
+	if source == "" {
+		source = title
 	}
+	return models.MakeSlug(source, a.slugFallback)
 }
 
 // NewPostFormHandler 处理新文章表单请求
-func NewPostFormHandler(w http.ResponseWriter, r *http.Request) {
+func (a *App) NewPostFormHandler(w http.ResponseWriter, r *http.Request) {
 	// 检查用户是否已登录
 	user := utils.GetUserFromSession(r)
 	if user == nil {
@@ -118,32 +180,17 @@ func NewPostFormHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 渲染模板
-	tmpl, err := template.ParseFiles(
-		"templates/base.html",
-		"templates/posts/new.html",
-	)
-	if err != nil {
-		http.Error(w, "模板解析错误", http.StatusInternalServerError)
-		return
-	}
-
-	// 获取当前年份
-	currentYear := time.Now().Year()
-
 	data := map[string]interface{}{
-		"Title":       "创建新文章",
-		"User":        user,
-		"CurrentYear": currentYear,
+		"Title": "创建新文章",
+		"Post":  &models.Post{},
+		"User":  user,
 	}
 
-	if err := tmpl.ExecuteTemplate(w, "base", data); err != nil {
-		http.Error(w, "模板渲染错误", http.StatusInternalServerError)
-	}
+	a.render(w, "posts/new.html", data)
 }
 
 // CreatePostHandler 处理创建文章请求
-func CreatePostHandler(w http.ResponseWriter, r *http.Request) {
+func (a *App) CreatePostHandler(w http.ResponseWriter, r *http.Request) {
 	// 检查用户是否已登录
 	user := utils.GetUserFromSession(r)
 	if user == nil {
@@ -166,34 +213,38 @@ func CreatePostHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "标题和内容不能为空", http.StatusBadRequest)
 		return
 	}
+	format, err := models.ParsePostFormat(r.FormValue("format"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	// 创建文章
 	post := &models.Post{
 		Title:   title,
+		Slug:    a.slugFromForm(r, title),
 		Content: content,
+		Format:  format,
 		UserID:  user.ID,
 	}
-
-	// 获取存储实例
-	store, err := db.NewSQLiteStore("./goblog.db")
-	if err != nil {
-		http.Error(w, "服务器内部错误", http.StatusInternalServerError)
+	setTaxonomy(post, r)
+	if err := setStatus(post, r); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	defer store.Close()
 
 	// 保存文章
-	if err := store.CreatePost(post); err != nil {
-		http.Error(w, "无法创建文章", http.StatusInternalServerError)
+	if err := a.Posts.Create(r.Context(), post); err != nil {
+		writeError(w, r, err, "无法创建文章")
 		return
 	}
 
 	// 重定向到文章页面
-	http.Redirect(w, r, "/posts/"+strconv.Itoa(post.ID), http.StatusSeeOther)
+	http.Redirect(w, r, a.postURL(post), http.StatusSeeOther)
 }
 
 // EditPostFormHandler 处理编辑文章表单请求
-func EditPostFormHandler(w http.ResponseWriter, r *http.Request) {
+func (a *App) EditPostFormHandler(w http.ResponseWriter, r *http.Request) {
 	// 检查用户是否已登录
 	user := utils.GetUserFromSession(r)
 	if user == nil {
@@ -209,18 +260,10 @@ func EditPostFormHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 获取存储实例
-	store, err := db.NewSQLiteStore("./goblog.db")
-	if err != nil {
-		http.Error(w, "服务器内部错误", http.StatusInternalServerError)
-		return
-	}
-	defer store.Close()
-
 	// 获取文章
-	post, err := store.FindPostByID(id)
+	post, err := a.Posts.FindByID(r.Context(), id)
 	if err != nil {
-		http.NotFound(w, r)
+		writeError(w, r, err, "无法获取文章")
 		return
 	}
 
@@ -230,33 +273,17 @@ func EditPostFormHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 渲染模板
-	tmpl, err := template.ParseFiles(
-		"templates/base.html",
-		"templates/posts/edit.html",
-	)
-	if err != nil {
-		http.Error(w, "模板解析错误", http.StatusInternalServerError)
-		return
-	}
-
-	// 获取当前年份
-	currentYear := time.Now().Year()
-
 	data := map[string]interface{}{
-		"Title":       "编辑文章",
-		"Post":        post,
-		"User":        user,
-		"CurrentYear": currentYear,
+		"Title": "编辑文章",
+		"Post":  post,
+		"User":  user,
 	}
 
-	if err := tmpl.ExecuteTemplate(w, "base", data); err != nil {
-		http.Error(w, "模板渲染错误", http.StatusInternalServerError)
-	}
+	a.render(w, "posts/edit.html", data)
 }
 
 // UpdatePostHandler 处理更新文章请求
-func UpdatePostHandler(w http.ResponseWriter, r *http.Request) {
+func (a *App) UpdatePostHandler(w http.ResponseWriter, r *http.Request) {
 	// 检查用户是否已登录
 	user := utils.GetUserFromSession(r)
 	if user == nil {
@@ -272,18 +299,10 @@ func UpdatePostHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 获取存储实例
-	store, err := db.NewSQLiteStore("./goblog.db")
-	if err != nil {
-		http.Error(w, "服务器内部错误", http.StatusInternalServerError)
-		return
-	}
-	defer store.Close()
-
 	// 获取文章
-	post, err := store.FindPostByID(id)
+	post, err := a.Posts.FindByID(r.Context(), id)
 	if err != nil {
-		http.NotFound(w, r)
+		writeError(w, r, err, "无法获取文章")
 		return
 	}
 
@@ -308,23 +327,75 @@ func UpdatePostHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "标题和内容不能为空", http.StatusBadRequest)
 		return
 	}
+	format, err := models.ParsePostFormat(r.FormValue("format"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// 表单中的版本号是打开编辑页面时的版本，编辑表单总会提交，缺少时拒绝，避免直接覆盖他人的修改
+	version, err := strconv.Atoi(r.FormValue("version"))
+	if err != nil || version < 1 {
+		http.Error(w, "无效的版本号", http.StatusBadRequest)
+		return
+	}
+	post.Version = version
 
 	// 更新文章数据
 	post.Title = title
+	post.Slug = a.slugFromForm(r, title)
 	post.Content = content
+	post.Format = format
+	setTaxonomy(post, r)
+	if err := setStatus(post, r); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	// 保存文章
-	if err := store.UpdatePost(post); err != nil {
-		http.Error(w, "无法更新文章", http.StatusInternalServerError)
+	if err := a.Posts.Update(r.Context(), post, user.ID); err != nil {
+		if errors.Is(err, db.ErrConflict) {
+			a.renderConflict(w, r, user, id)
+			return
+		}
+		writeError(w, r, err, "无法更新文章")
 		return
 	}
 
 	// 重定向到文章页面
-	http.Redirect(w, r, "/posts/"+strconv.Itoa(post.ID), http.StatusSeeOther)
+	http.Redirect(w, r, a.postURL(post), http.StatusSeeOther)
 }
 
-// DeletePostHandler 处理删除文章请求
-func DeletePostHandler(w http.ResponseWriter, r *http.Request) {
+// renderConflict 文章在编辑期间已被其他人修改时，以409状态码把用户提交的内容和当前保存的内容并排显示，
+// 用户合并后以当前版本号重新提交
+func (a *App) renderConflict(w http.ResponseWriter, r *http.Request, user *models.User, id int) {
+	current, err := a.Posts.FindByID(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err, "无法获取文章")
+		return
+	}
+
+	data := map[string]interface{}{
+		"Title": "编辑冲突: " + current.Title,
+		"Post":  current,
+		"Mine": map[string]string{
+			"Title":     r.FormValue("title"),
+			"Slug":      r.FormValue("slug"),
+			"Category":  r.FormValue("category"),
+			"Tags":      r.FormValue("tags"),
+			"Content":   r.FormValue("content"),
+			"Format":    r.FormValue("format"),
+			"Status":    r.FormValue("status"),
+			"PublishAt": r.FormValue("publish_at"),
+		},
+		"User": user,
+	}
+
+	a.renderStatus(w, http.StatusConflict, "posts/conflict.html", data)
+}
+
+// DeletePostHandler 处理删除文章请求，文章移入回收站
+func (a *App) DeletePostHandler(w http.ResponseWriter, r *http.Request) {
 	// 检查用户是否已登录
 	user := utils.GetUserFromSession(r)
 	if user == nil {
@@ -340,18 +411,10 @@ func DeletePostHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 获取存储实例
-	store, err := db.NewSQLiteStore("./goblog.db")
-	if err != nil {
-		http.Error(w, "服务器内部错误", http.StatusInternalServerError)
-		return
-	}
-	defer store.Close()
-
 	// 获取文章
-	post, err := store.FindPostByID(id)
+	post, err := a.Posts.FindByID(r.Context(), id)
 	if err != nil {
-		http.NotFound(w, r)
+		writeError(w, r, err, "无法获取文章")
 		return
 	}
 
@@ -361,12 +424,12 @@ func DeletePostHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 删除文章
-	if err := store.DeletePost(id); err != nil {
-		http.Error(w, "无法删除文章", http.StatusInternalServerError)
+	// 移入回收站
+	if err := a.Posts.Delete(r.Context(), id); err != nil {
+		writeError(w, r, err, "无法删除文章")
 		return
 	}
 
-	// 重定向到文章列表
-	http.Redirect(w, r, "/posts", http.StatusSeeOther)
+	// 重定向到回收站，便于撤销
+	http.Redirect(w, r, "/trash", http.StatusSeeOther)
 }
@@ -0,0 +1,126 @@
+package controllers
+
+import (
+	"goblog/models"
+	"goblog/utils"
+	"net/http"
+	"strconv"
+)
+
+// findPostForHistory 获取文章并检查当前用户能否查看其历史版本，失败时已写入响应
+func (a *App) findPostForHistory(w http.ResponseWriter, r *http.Request, id int) (*models.Post, *models.User, bool) {
+	// 检查用户是否已登录
+	user := utils.GetUserFromSession(r)
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return nil, nil, false
+	}
+
+	// 获取文章
+	post, err := a.Posts.FindByID(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err, "无法获取文章")
+		return nil, nil, false
+	}
+
+	// 只有作者和管理员可以查看历史版本
+	if !post.CanManageRevisions(user) {
+		http.Error(w, "没有权限查看该文章的历史版本", http.StatusForbidden)
+		return nil, nil, false
+	}
+
+	return post, user, true
+}
+
+// RevisionsHandler 处理文章历史版本列表请求
+func (a *App) RevisionsHandler(w http.ResponseWriter, r *http.Request, id int) {
+	post, user, ok := a.findPostForHistory(w, r, id)
+	if !ok {
+		return
+	}
+
+	revisions, err := a.Posts.Revisions(r.Context(), post.ID)
+	if err != nil {
+		writeError(w, r, err, "无法获取历史版本")
+		return
+	}
+
+	data := map[string]interface{}{
+		"Title":     "历史版本: " + post.Title,
+		"Post":      post,
+		"Revisions": revisions,
+		"User":      user,
+	}
+
+	a.render(w, "posts/revisions.html", data)
+}
+
+// RevisionDiffHandler 处理两个版本之间的差异请求，参数 from 和 to 为版本ID
+func (a *App) RevisionDiffHandler(w http.ResponseWriter, r *http.Request, id int) {
+	post, user, ok := a.findPostForHistory(w, r, id)
+	if !ok {
+		return
+	}
+
+	fromID, err1 := strconv.Atoi(r.URL.Query().Get("from"))
+	toID, err2 := strconv.Atoi(r.URL.Query().Get("to"))
+	if err1 != nil || err2 != nil {
+		http.Error(w, "无效的版本参数", http.StatusBadRequest)
+		return
+	}
+
+	// 旧版本总是显示在左侧
+	if fromID > toID {
+		fromID, toID = toID, fromID
+	}
+
+	from, err := a.Posts.FindRevision(r.Context(), post.ID, fromID)
+	if err != nil {
+		writeError(w, r, err, "无法获取历史版本")
+		return
+	}
+	to, err := a.Posts.FindRevision(r.Context(), post.ID, toID)
+	if err != nil {
+		writeError(w, r, err, "无法获取历史版本")
+		return
+	}
+
+	data := map[string]interface{}{
+		"Title": "版本差异: " + post.Title,
+		"Post":  post,
+		"Diff":  models.DiffRevisions(from, to),
+		"User":  user,
+	}
+
+	a.render(w, "posts/diff.html", data)
+}
+
+// RestoreRevisionHandler 处理恢复历史版本请求，恢复本身会保存为一个新版本
+func (a *App) RestoreRevisionHandler(w http.ResponseWriter, r *http.Request, id, revisionID int) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "方法不允许", http.StatusMethodNotAllowed)
+		return
+	}
+
+	post, user, ok := a.findPostForHistory(w, r, id)
+	if !ok {
+		return
+	}
+
+	revision, err := a.Posts.FindRevision(r.Context(), post.ID, revisionID)
+	if err != nil {
+		writeError(w, r, err, "无法获取历史版本")
+		return
+	}
+
+	// 以当前用户的身份写入旧版本的内容
+	post.Title = revision.Title
+	post.Content = revision.Content
+	post.Format = revision.Format
+	if err := a.Posts.Update(r.Context(), post, user.ID); err != nil {
+		writeError(w, r, err, "无法恢复历史版本")
+		return
+	}
+
+	http.Redirect(w, r, a.postURL(post), http.StatusSeeOther)
+}
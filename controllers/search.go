@@ -0,0 +1,73 @@
+package controllers
+
+import (
+	"goblog/models"
+	"goblog/utils"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// SearchHandler 处理搜索请求，参数 q 为搜索词，page 为页码
+func (a *App) SearchHandler(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+
+	page := 1
+	if p := r.URL.Query().Get("page"); p != "" {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 1 {
+			http.Error(w, "无效的页码", http.StatusBadRequest)
+			return
+		}
+		page = n
+	}
+
+	pageSize := a.Config.Blog.PageSize
+	result := &models.SearchResult{}
+	if query != "" {
+		var err error
+		result, err = a.Posts.Search(r.Context(), models.SearchOptions{
+			Query:  query,
+			Limit:  pageSize,
+			Offset: (page - 1) * pageSize,
+		})
+		if err != nil {
+			writeError(w, r, err, "搜索失败")
+			return
+		}
+	}
+
+	// 获取当前用户
+	user := utils.GetUserFromSession(r)
+
+	pageURL := func(n int) string {
+		values := url.Values{"q": {query}, "page": {strconv.Itoa(n)}}
+		return "/search?" + values.Encode()
+	}
+
+	var prevURL, nextURL string
+	if page > 1 {
+		prevURL = pageURL(page - 1)
+	}
+	if page*pageSize < result.Total {
+		nextURL = pageURL(page + 1)
+	}
+
+	title := "搜索"
+	if query != "" {
+		title = "搜索: " + query
+	}
+
+	data := map[string]interface{}{
+		"Title":   title,
+		"Query":   query,
+		"Hits":    result.Hits,
+		"Total":   result.Total,
+		"User":    user,
+		"PrevURL": prevURL,
+		"NextURL": nextURL,
+	}
+
+	a.render(w, "search.html", data)
+}
@@ -0,0 +1,40 @@
+package controllers
+
+import (
+	"encoding/json"
+	"goblog/db"
+	"log"
+	"net"
+	"net/http"
+)
+
+// StatsHandler 以JSON返回运行统计，供监控系统采集。只有管理员可以访问，
+// 开启 server.statsAllowLoopback 时本机的请求不需要登录
+func (a *App) StatsHandler(w http.ResponseWriter, r *http.Request) {
+	if !a.Config.Server.StatsAllowLoopback || !isLoopback(r) {
+		if _, ok := a.requireAdmin(w, r); !ok {
+			return
+		}
+	}
+
+	stats := map[string]interface{}{}
+	if cached, ok := a.Posts.(*db.CachedPostStore); ok {
+		stats["post_cache"] = cached.Stats()
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	if err := json.NewEncoder(w).Encode(stats); err != nil {
+		log.Printf("写入运行统计失败: %v", err)
+	}
+}
+
+// isLoopback 判断请求是否来自本机
+func isLoopback(r *http.Request) bool {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return false
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,136 @@
+package controllers
+
+import (
+	"goblog/models"
+	"goblog/utils"
+	"net/http"
+	"strings"
+)
+
+// setTaxonomy 从表单读取标签和分类写入文章。分类只记录路径，
+// 保存文章时在同一事务中查找或创建，保存失败时不会留下新建的分类
+func setTaxonomy(post *models.Post, r *http.Request) {
+	post.Tags = models.ParseTags(r.FormValue("tags"))
+
+	post.CategoryID = 0
+	post.Category = nil
+	if path := strings.Join(models.SplitCategoryPath(r.FormValue("category")), "/"); path != "" {
+		post.Category = &models.Category{Path: path}
+	}
+}
+
+// TagsHandler 处理标签列表请求
+func (a *App) TagsHandler(w http.ResponseWriter, r *http.Request) {
+	tags, err := a.Taxonomy.Tags(r.Context())
+	if err != nil {
+		writeError(w, r, err, "无法获取标签")
+		return
+	}
+
+	data := map[string]interface{}{
+		"Title": "标签",
+		"Tags":  tags,
+		"User":  utils.GetUserFromSession(r),
+	}
+
+	a.render(w, "tags/index.html", data)
+}
+
+// TagHandler 处理 /tags/{name} 请求，分页列出带有该标签的文章
+func (a *App) TagHandler(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/tags/")
+	if name == "" {
+		a.TagsHandler(w, r)
+		return
+	}
+
+	tag, err := a.Taxonomy.FindTag(r.Context(), name)
+	if err != nil {
+		writeError(w, r, err, "无法获取标签")
+		return
+	}
+
+	opts, err := listOptionsFromRequest(r, a.Config.Blog.PageSize)
+	if err != nil {
+		http.Error(w, "无效的分页参数", http.StatusBadRequest)
+		return
+	}
+
+	page, err := a.Posts.ListByTag(r.Context(), tag.Name, opts)
+	if err != nil {
+		writeError(w, r, err, "无法获取文章")
+		return
+	}
+
+	a.attachCommentCounts(r.Context(), page.Posts)
+	prevURL, nextURL := pageURLs(r, page)
+
+	data := map[string]interface{}{
+		"Title":   "标签: " + tag.Name,
+		"Tag":     tag,
+		"Posts":   page.Posts,
+		"User":    utils.GetUserFromSession(r),
+		"PrevURL": prevURL,
+		"NextURL": nextURL,
+	}
+
+	a.render(w, "tags/show.html", data)
+}
+
+// CategoriesHandler 处理分类列表请求
+func (a *App) CategoriesHandler(w http.ResponseWriter, r *http.Request) {
+	categories, err := a.Taxonomy.Categories(r.Context())
+	if err != nil {
+		writeError(w, r, err, "无法获取分类")
+		return
+	}
+
+	data := map[string]interface{}{
+		"Title":      "分类",
+		"Categories": categories,
+		"User":       utils.GetUserFromSession(r),
+	}
+
+	a.render(w, "categories/index.html", data)
+}
+
+// CategoryHandler 处理 /categories/{slug} 请求，分页列出该分类及其下级分类中的文章
+func (a *App) CategoryHandler(w http.ResponseWriter, r *http.Request) {
+	slug := strings.Trim(strings.TrimPrefix(r.URL.Path, "/categories/"), "/")
+	if slug == "" {
+		a.CategoriesHandler(w, r)
+		return
+	}
+
+	category, err := a.Taxonomy.FindCategory(r.Context(), slug)
+	if err != nil {
+		writeError(w, r, err, "无法获取分类")
+		return
+	}
+
+	opts, err := listOptionsFromRequest(r, a.Config.Blog.PageSize)
+	if err != nil {
+		http.Error(w, "无效的分页参数", http.StatusBadRequest)
+		return
+	}
+
+	page, err := a.Posts.ListByCategory(r.Context(), category.Slug, opts)
+	if err != nil {
+		writeError(w, r, err, "无法获取文章")
+		return
+	}
+
+	a.attachCommentCounts(r.Context(), page.Posts)
+	prevURL, nextURL := pageURLs(r, page)
+
+	data := map[string]interface{}{
+		"Title":    "分类: " + category.Path,
+		"Category": category,
+		"Posts":    page.Posts,
+		"User":     utils.GetUserFromSession(r),
+		"PrevURL":  prevURL,
+		"NextURL":  nextURL,
+	}
+
+	a.render(w, "categories/show.html", data)
+}
@@ -0,0 +1,102 @@
+package controllers
+
+import (
+	"goblog/models"
+	"goblog/utils"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// TrashHandler 处理回收站页面请求，只列出当前用户的文章
+func (a *App) TrashHandler(w http.ResponseWriter, r *http.Request) {
+	// 检查用户是否已登录
+	user := utils.GetUserFromSession(r)
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	posts, err := a.Posts.Trash(r.Context(), user.ID)
+	if err != nil {
+		writeError(w, r, err, "无法获取回收站")
+		return
+	}
+
+	data := map[string]interface{}{
+		"Title":         "回收站",
+		"Posts":         posts,
+		"User":          user,
+		"RetentionDays": a.Config.Blog.TrashRetentionDays,
+	}
+
+	a.render(w, "trash.html", data)
+}
+
+// RestoreTrashHandler 处理从回收站恢复文章请求
+func (a *App) RestoreTrashHandler(w http.ResponseWriter, r *http.Request) {
+	post, ok := a.findTrashedPost(w, r, "/trash/restore/")
+	if !ok {
+		return
+	}
+
+	if err := a.Posts.Restore(r.Context(), post.ID); err != nil {
+		writeError(w, r, err, "无法恢复文章")
+		return
+	}
+
+	// 重定向到恢复的文章
+	http.Redirect(w, r, a.postURL(post), http.StatusSeeOther)
+}
+
+// PurgeTrashHandler 处理永久删除回收站中文章的请求
+func (a *App) PurgeTrashHandler(w http.ResponseWriter, r *http.Request) {
+	post, ok := a.findTrashedPost(w, r, "/trash/purge/")
+	if !ok {
+		return
+	}
+
+	if err := a.Posts.Purge(r.Context(), post.ID); err != nil {
+		writeError(w, r, err, "无法删除文章")
+		return
+	}
+
+	http.Redirect(w, r, "/trash", http.StatusSeeOther)
+}
+
+// findTrashedPost 从URL中解析文章ID，获取回收站中的文章并检查当前用户是否为作者，失败时已写入响应
+func (a *App) findTrashedPost(w http.ResponseWriter, r *http.Request, prefix string) (*models.Post, bool) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "方法不允许", http.StatusMethodNotAllowed)
+		return nil, false
+	}
+
+	// 检查用户是否已登录
+	user := utils.GetUserFromSession(r)
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return nil, false
+	}
+
+	// 从URL中提取文章ID
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, prefix))
+	if err != nil {
+		http.NotFound(w, r)
+		return nil, false
+	}
+
+	// 获取回收站中的文章
+	post, err := a.Posts.FindTrashed(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err, "无法获取文章")
+		return nil, false
+	}
+
+	// 检查是否是文章作者
+	if post.UserID != user.ID {
+		http.Error(w, "没有权限操作该文章", http.StatusForbidden)
+		return nil, false
+	}
+
+	return post, true
+}
@@ -1,16 +1,15 @@
 package controllers
 
 import (
+	"errors"
 	"goblog/db"
 	"goblog/models"
 	"goblog/utils"
-	"html/template"
 	"net/http"
-	"time"
 )
 
 // LoginFormHandler 处理登录表单请求
-func LoginFormHandler(w http.ResponseWriter, r *http.Request) {
+func (a *App) LoginFormHandler(w http.ResponseWriter, r *http.Request) {
 	// 检查用户是否已登录
 	user := utils.GetUserFromSession(r)
 	if user != nil {
@@ -18,31 +17,15 @@ func LoginFormHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 渲染模板
-	tmpl, err := template.ParseFiles(
-		"templates/base.html",
-		"templates/users/login.html",
-	)
-	if err != nil {
-		http.Error(w, "模板解析错误", http.StatusInternalServerError)
-		return
-	}
-
-	// 获取当前年份
-	currentYear := time.Now().Year()
-
 	data := map[string]interface{}{
-		"Title":       "用户登录",
-		"CurrentYear": currentYear,
+		"Title": "用户登录",
 	}
 
-	if err := tmpl.ExecuteTemplate(w, "base", data); err != nil {
-		http.Error(w, "模板渲染错误", http.StatusInternalServerError)
-	}
+	a.render(w, "users/login.html", data)
 }
 
 // LoginProcessHandler 处理登录请求
-func LoginProcessHandler(w http.ResponseWriter, r *http.Request) {
+func (a *App) LoginProcessHandler(w http.ResponseWriter, r *http.Request) {
 	// 检查请求方法
 	if r.Method != http.MethodPost {
 		http.Error(w, "方法不允许", http.StatusMethodNotAllowed)
@@ -65,18 +48,14 @@ func LoginProcessHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 获取存储实例
-	store, err := db.NewSQLiteStore("./goblog.db")
-	if err != nil {
-		http.Error(w, "服务器内部错误", http.StatusInternalServerError)
+	// 认证用户
+	user, err := a.Users.Authenticate(r.Context(), username, password)
+	if errors.Is(err, db.ErrNotFound) {
+		http.Error(w, "用户名或密码错误", http.StatusUnauthorized)
 		return
 	}
-	defer store.Close()
-
-	// 认证用户
-	user, err := store.Authenticate(username, password)
 	if err != nil {
-		http.Error(w, "用户名或密码错误", http.StatusUnauthorized)
+		writeError(w, r, err, "登录失败")
 		return
 	}
 
@@ -91,7 +70,7 @@ func LoginProcessHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // LogoutHandler 处理登出请求
-func LogoutHandler(w http.ResponseWriter, r *http.Request) {
+func (a *App) LogoutHandler(w http.ResponseWriter, r *http.Request) {
 	// 清除会话
 	utils.ClearUserSession(w, r)
 
@@ -100,7 +79,7 @@ func LogoutHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // RegisterFormHandler 处理注册表单请求
-func RegisterFormHandler(w http.ResponseWriter, r *http.Request) {
+func (a *App) RegisterFormHandler(w http.ResponseWriter, r *http.Request) {
 	// 检查用户是否已登录
 	user := utils.GetUserFromSession(r)
 	if user != nil {
@@ -108,31 +87,15 @@ func RegisterFormHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// 渲染模板
-	tmpl, err := template.ParseFiles(
-		"templates/base.html",
-		"templates/users/register.html",
-	)
-	if err != nil {
-		http.Error(w, "模板解析错误", http.StatusInternalServerError)
-		return
-	}
-
-	// 获取当前年份
-	currentYear := time.Now().Year()
-
 	data := map[string]interface{}{
-		"Title":       "用户注册",
-		"CurrentYear": currentYear,
+		"Title": "用户注册",
 	}
 
-	if err := tmpl.ExecuteTemplate(w, "base", data); err != nil {
-		http.Error(w, "模板渲染错误", http.StatusInternalServerError)
-	}
+	a.render(w, "users/register.html", data)
 }
 
 // RegisterProcessHandler 处理注册请求
-func RegisterProcessHandler(w http.ResponseWriter, r *http.Request) {
+func (a *App) RegisterProcessHandler(w http.ResponseWriter, r *http.Request) {
 	// 检查请求方法
 	if r.Method != http.MethodPost {
 		http.Error(w, "方法不允许", http.StatusMethodNotAllowed)
@@ -169,31 +132,9 @@ func RegisterProcessHandler(w http.ResponseWriter, r *http.Request) {
 		Password: password,
 	}
 
-	// 获取存储实例
-	store, err := db.NewSQLiteStore("./goblog.db")
-	if err != nil {
-		http.Error(w, "服务器内部错误", http.StatusInternalServerError)
-		return
-	}
-	defer store.Close()
-
-	// 检查用户名是否已存在
-	_, err = store.FindUserByUsername(username)
-	if err == nil {
-		http.Error(w, "用户名已存在", http.StatusConflict)
-		return
-	}
-
-	// 检查邮箱是否已存在
-	_, err = store.FindUserByEmail(email)
-	if err == nil {
-		http.Error(w, "邮箱已存在", http.StatusConflict)
-		return
-	}
-
-	// 保存用户
-	if err := store.CreateUser(user); err != nil {
-		http.Error(w, "无法创建用户", http.StatusInternalServerError)
+	// 保存用户，用户名或邮箱已被使用时由唯一约束检查，并发注册同一用户名也只有一个成功
+	if err := a.Users.Create(r.Context(), user); err != nil {
+		writeError(w, r, err, "无法创建用户")
 		return
 	}
 
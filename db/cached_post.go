@@ -0,0 +1,398 @@
+package db
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"goblog/config"
+	"goblog/models"
+	"sync"
+	"time"
+)
+
+// errPostCacheLoad 缓存未命中时的查询异常中止
+var errPostCacheLoad = errors.New("查询文章失败")
+
+// CacheStats 文章缓存的统计数据，用于监控命中率
+type CacheStats struct {
+	Enabled       bool    `json:"enabled"`
+	Capacity      int     `json:"capacity"` // 最多缓存的查询结果数量
+	Entries       int     `json:"entries"`  // 当前缓存的查询结果数量
+	TTLSeconds    int     `json:"ttl_seconds"`
+	Hits          uint64  `json:"hits"`          // 直接从缓存返回的次数
+	Misses        uint64  `json:"misses"`        // 查询数据库的次数
+	Shared        uint64  `json:"shared"`        // 等待同一查询的结果而没有重复查询数据库的次数
+	Evictions     uint64  `json:"evictions"`     // 因数量超出上限或过期被移除的结果数量
+	Invalidations uint64  `json:"invalidations"` // 因文章修改而清空缓存的次数
+	HitRate       float64 `json:"hit_rate"`      // 命中率，等待同一查询的次数计为命中
+}
+
+// CachedPostStore 在文章存储前加上进程内的LRU读取缓存，缓存已发布文章列表、
+// 分页列表和按ID、别名查找的结果。任何修改文章的操作都会清空缓存，
+// 缓存未命中时同一查询的并发请求只查询一次数据库。返回的文章都是副本，调用方可以修改
+type CachedPostStore struct {
+	models.PostStore
+
+	capacity int
+	ttl      time.Duration
+
+	mu      sync.Mutex
+	items   map[string]*list.Element
+	order   *list.List // 最近使用的在前
+	flights map[flightKey]*flight
+
+	// generation 每次清空缓存时递增，清空前开始的查询结果不再写入缓存
+	generation uint64
+
+	hits, misses, shared, evictions, invalidations uint64
+}
+
+// cacheEntry 一个缓存的查询结果
+type cacheEntry struct {
+	key     string
+	value   interface{}
+	expires time.Time
+}
+
+// flightKey 进行中的查询，清空缓存后开始的同一查询不与之前的合并
+type flightKey struct {
+	generation uint64
+	key        string
+}
+
+// flight 一次进行中的数据库查询，done 关闭后结果可用
+type flight struct {
+	done  chan struct{}
+	value interface{}
+	err   error
+}
+
+// NewCachedPostStore 创建文章存储的缓存，cfg.Size 为0时不缓存，只统计查询次数
+func NewCachedPostStore(store models.PostStore, cfg config.CacheConfig) *CachedPostStore {
+	return &CachedPostStore{
+		PostStore: store,
+		capacity:  cfg.Size,
+		ttl:       time.Duration(cfg.TTLSeconds) * time.Second,
+		items:     make(map[string]*list.Element),
+		order:     list.New(),
+		flights:   make(map[flightKey]*flight),
+	}
+}
+
+// Stats 返回缓存的统计数据
+func (c *CachedPostStore) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := CacheStats{
+		Enabled:       c.capacity > 0,
+		Capacity:      c.capacity,
+		Entries:       c.order.Len(),
+		TTLSeconds:    int(c.ttl / time.Second),
+		Hits:          c.hits,
+		Misses:        c.misses,
+		Shared:        c.shared,
+		Evictions:     c.evictions,
+		Invalidations: c.invalidations,
+	}
+	if total := c.hits + c.shared + c.misses; total > 0 {
+		stats.HitRate = float64(c.hits+c.shared) / float64(total)
+	}
+	return stats
+}
+
+// get 返回缓存的查询结果，未命中或过期时调用load查询并缓存结果，出错的结果不缓存。
+// 等待其他请求的查询时，ctx 取消后立即返回；对方的查询因对方的ctx取消而失败时，重新查询
+func (c *CachedPostStore) get(ctx context.Context, key string, load func() (interface{}, error)) (interface{}, error) {
+	if c.capacity <= 0 {
+		c.mu.Lock()
+		c.misses++
+		c.mu.Unlock()
+		return load()
+	}
+
+	for {
+		c.mu.Lock()
+		if elem, ok := c.items[key]; ok {
+			entry := elem.Value.(*cacheEntry)
+			if c.ttl <= 0 || time.Now().Before(entry.expires) {
+				c.order.MoveToFront(elem)
+				c.hits++
+				c.mu.Unlock()
+				return entry.value, nil
+			}
+			c.remove(elem)
+		}
+
+		fk := flightKey{generation: c.generation, key: key}
+		f, ok := c.flights[fk]
+		if !ok {
+			return c.fetch(fk, load)
+		}
+		c.shared++
+		c.mu.Unlock()
+
+		select {
+		case <-f.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if isContextError(f.err) && ctx.Err() == nil {
+			continue
+		}
+		return f.value, f.err
+	}
+}
+
+// isContextError 判断错误是否由ctx取消或超时引起
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// fetch 作为fk的唯一查询调用load并缓存结果，调用时持有锁，返回前释放
+func (c *CachedPostStore) fetch(fk flightKey, load func() (interface{}, error)) (interface{}, error) {
+	f := &flight{done: make(chan struct{})}
+	c.flights[fk] = f
+	c.misses++
+	c.mu.Unlock()
+
+	// load 发生panic时等待的请求也返回错误，结果不缓存
+	completed := false
+	defer func() {
+		if !completed {
+			f.err = errPostCacheLoad
+		}
+		c.mu.Lock()
+		delete(c.flights, fk)
+		if completed && f.err == nil && c.generation == fk.generation {
+			c.add(fk.key, f.value)
+		}
+		c.mu.Unlock()
+		close(f.done)
+	}()
+	f.value, f.err = load()
+	completed = true
+	return f.value, f.err
+}
+
+// add 缓存查询结果，超出数量上限时移除最久未使用的结果，调用方持有锁
+func (c *CachedPostStore) add(key string, value interface{}) {
+	if elem, ok := c.items[key]; ok {
+		c.order.Remove(elem)
+	}
+	c.items[key] = c.order.PushFront(&cacheEntry{key: key, value: value, expires: time.Now().Add(c.ttl)})
+	for c.order.Len() > c.capacity {
+		c.remove(c.order.Back())
+	}
+}
+
+// remove 移除一个缓存的结果，调用方持有锁
+func (c *CachedPostStore) remove(elem *list.Element) {
+	c.order.Remove(elem)
+	delete(c.items, elem.Value.(*cacheEntry).key)
+	c.evictions++
+}
+
+// invalidate 清空缓存，进行中的查询结果也不再写入
+func (c *CachedPostStore) invalidate() {
+	c.mu.Lock()
+	c.items = make(map[string]*list.Element)
+	c.order.Init()
+	c.generation++
+	c.invalidations++
+	c.mu.Unlock()
+}
+
+// listKey 分页参数组成的缓存键
+func listKey(prefix, name string, opts models.ListOptions) string {
+	return fmt.Sprintf("%s:%s:%d:%s:%s:%s", prefix, name, opts.Limit, cursorKey(opts.After), cursorKey(opts.Before), opts.Sort)
+}
+
+// cursorKey 游标的缓存键，nil 时为空字符串
+func cursorKey(cursor *models.Cursor) string {
+	if cursor == nil {
+		return ""
+	}
+	return cursor.String()
+}
+
+// copyPost 复制文章，调用方修改标签等字段时不会影响缓存的结果
+func copyPost(post *models.Post) *models.Post {
+	if post == nil {
+		return nil
+	}
+	copied := *post
+	if post.Tags != nil {
+		copied.Tags = append([]string{}, post.Tags...)
+	}
+	if post.PublishAt != nil {
+		t := *post.PublishAt
+		copied.PublishAt = &t
+	}
+	if post.DeletedAt != nil {
+		t := *post.DeletedAt
+		copied.DeletedAt = &t
+	}
+	return &copied
+}
+
+// copyPosts 复制文章列表
+func copyPosts(posts []*models.Post) []*models.Post {
+	if posts == nil {
+		return nil
+	}
+	copied := make([]*models.Post, len(posts))
+	for i, post := range posts {
+		copied[i] = copyPost(post)
+	}
+	return copied
+}
+
+// copyPage 复制分页结果
+func copyPage(page *models.PostPage) *models.PostPage {
+	copied := *page
+	copied.Posts = copyPosts(page.Posts)
+	return &copied
+}
+
+// FindAll 查找所有已发布的文章
+func (c *CachedPostStore) FindAll(ctx context.Context) ([]*models.Post, error) {
+	value, err := c.get(ctx, "all", func() (interface{}, error) {
+		return c.PostStore.FindAll(ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return copyPosts(value.([]*models.Post)), nil
+}
+
+// List 按键集分页查询已发布的文章列表
+func (c *CachedPostStore) List(ctx context.Context, opts models.ListOptions) (*models.PostPage, error) {
+	value, err := c.get(ctx, listKey("list", "", opts), func() (interface{}, error) {
+		return c.PostStore.List(ctx, opts)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return copyPage(value.(*models.PostPage)), nil
+}
+
+// ListByTag 按键集分页查询带有某个标签的文章
+func (c *CachedPostStore) ListByTag(ctx context.Context, tag string, opts models.ListOptions) (*models.PostPage, error) {
+	value, err := c.get(ctx, listKey("tag", tag, opts), func() (interface{}, error) {
+		return c.PostStore.ListByTag(ctx, tag, opts)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return copyPage(value.(*models.PostPage)), nil
+}
+
+// ListByCategory 按键集分页查询某个分类及其下级分类中的文章
+func (c *CachedPostStore) ListByCategory(ctx context.Context, slug string, opts models.ListOptions) (*models.PostPage, error) {
+	value, err := c.get(ctx, listKey("category", slug, opts), func() (interface{}, error) {
+		return c.PostStore.ListByCategory(ctx, slug, opts)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return copyPage(value.(*models.PostPage)), nil
+}
+
+// FindByID 根据ID查找文章
+func (c *CachedPostStore) FindByID(ctx context.Context, id int) (*models.Post, error) {
+	value, err := c.get(ctx, fmt.Sprintf("id:%d", id), func() (interface{}, error) {
+		return c.PostStore.FindByID(ctx, id)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return copyPost(value.(*models.Post)), nil
+}
+
+// FindBySlug 根据别名或旧别名查找文章
+func (c *CachedPostStore) FindBySlug(ctx context.Context, slug string) (*models.Post, error) {
+	value, err := c.get(ctx, "slug:"+slug, func() (interface{}, error) {
+		return c.PostStore.FindBySlug(ctx, slug)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return copyPost(value.(*models.Post)), nil
+}
+
+// Create 创建文章并清空缓存
+func (c *CachedPostStore) Create(ctx context.Context, post *models.Post) error {
+	defer c.invalidate()
+	return c.PostStore.Create(ctx, post)
+}
+
+// Update 更新文章并清空缓存
+func (c *CachedPostStore) Update(ctx context.Context, post *models.Post, editorID int) error {
+	defer c.invalidate()
+	return c.PostStore.Update(ctx, post, editorID)
+}
+
+// Delete 把文章移入回收站并清空缓存
+func (c *CachedPostStore) Delete(ctx context.Context, id int) error {
+	defer c.invalidate()
+	return c.PostStore.Delete(ctx, id)
+}
+
+// PublishDue 发布到期的定时文章，有文章发布时清空缓存
+func (c *CachedPostStore) PublishDue(ctx context.Context, now time.Time) (int, error) {
+	n, err := c.PostStore.PublishDue(ctx, now)
+	if n > 0 {
+		c.invalidate()
+	}
+	return n, err
+}
+
+// Restore 从回收站恢复文章并清空缓存
+func (c *CachedPostStore) Restore(ctx context.Context, id int) error {
+	defer c.invalidate()
+	return c.PostStore.Restore(ctx, id)
+}
+
+// Purge 永久删除回收站中的文章并清空缓存，文章的旧别名随之删除
+func (c *CachedPostStore) Purge(ctx context.Context, id int) error {
+	defer c.invalidate()
+	return c.PostStore.Purge(ctx, id)
+}
+
+// PurgeDeletedBefore 永久删除回收站中过期的文章，有文章被删除时清空缓存
+func (c *CachedPostStore) PurgeDeletedBefore(ctx context.Context, before time.Time) (int, error) {
+	n, err := c.PostStore.PurgeDeletedBefore(ctx, before)
+	if n > 0 {
+		c.invalidate()
+	}
+	return n, err
+}
+
+// cachedTaxonomyStore 经过文章缓存的分类存储，新建分类后清空缓存
+type cachedTaxonomyStore struct {
+	models.TaxonomyStore
+	cache *CachedPostStore
+}
+
+// EnsureCategory 按路径查找分类，不存在的分类会被创建，然后清空缓存
+func (t *cachedTaxonomyStore) EnsureCategory(ctx context.Context, path string) (*models.Category, error) {
+	defer t.cache.invalidate()
+	return t.TaxonomyStore.EnsureCategory(ctx, path)
+}
+
+// cachedSiteStore 经过文章缓存的整站数据存储，导入的文章、分类和别名会改变缓存的列表，导入后清空缓存
+type cachedSiteStore struct {
+	models.SiteStore
+	cache *CachedPostStore
+}
+
+// Load 写入整站数据，实际保存了修改时清空缓存
+func (s *cachedSiteStore) Load(ctx context.Context, data *models.SiteData, existing map[int]int, dryRun bool) (*models.LoadResult, error) {
+	result, err := s.SiteStore.Load(ctx, data, existing, dryRun)
+	if err == nil && !dryRun {
+		s.cache.invalidate()
+	}
+	return result, err
+}
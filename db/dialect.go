@@ -0,0 +1,140 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"goblog/config"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// dialect 描述不同数据库之间的SQL差异，存储实现中的SQL统一使用 ? 占位符书写
+type dialect struct {
+	// name 方言名称，与 db/migrate 中的迁移目录同名
+	name string
+
+	// driver database/sql 驱动名
+	driver string
+
+	// numbered 占位符使用 $1, $2 形式（PostgreSQL）
+	numbered bool
+
+	// returning 插入时通过 RETURNING 子句取得自增ID，而不是 LastInsertId
+	returning bool
+}
+
+var (
+	sqliteDialect   = &dialect{name: "sqlite3", driver: "sqlite3"}
+	postgresDialect = &dialect{name: "postgres", driver: "postgres", numbered: true, returning: true}
+	mysqlDialect    = &dialect{name: "mysql", driver: "mysql"}
+)
+
+// dialectFor 根据配置中的数据库类型选择方言
+func dialectFor(dbType string) (*dialect, error) {
+	switch dbType {
+	case "sqlite3", "sqlite", "":
+		return sqliteDialect, nil
+	case "postgres", "postgresql":
+		return postgresDialect, nil
+	case "mysql":
+		return mysqlDialect, nil
+	default:
+		return nil, fmt.Errorf("不支持的数据库类型: %s", dbType)
+	}
+}
+
+// dsn 根据配置生成驱动连接字符串
+func (d *dialect) dsn(cfg config.DatabaseConfig) string {
+	switch d {
+	case postgresDialect:
+		u := url.URL{
+			Scheme: "postgres",
+			User:   url.UserPassword(cfg.User, cfg.Password),
+			Host:   net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port)),
+			Path:   "/" + cfg.DBName,
+		}
+		sslMode := cfg.SSLMode
+		if sslMode == "" {
+			sslMode = "disable"
+		}
+		u.RawQuery = url.Values{"sslmode": {sslMode}}.Encode()
+		return u.String()
+	case mysqlDialect:
+		mc := mysql.NewConfig()
+		mc.User = cfg.User
+		mc.Passwd = cfg.Password
+		mc.Net = "tcp"
+		mc.Addr = net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port))
+		mc.DBName = cfg.DBName
+		mc.ParseTime = true
+		mc.Loc = time.UTC
+		mc.MultiStatements = true // 迁移脚本包含多条语句
+		mc.ClientFoundRows = true // 影响的行数按匹配的行计算，值没有变化的更新不会被 expectAffected 当作记录不存在
+		mc.Params = map[string]string{"charset": "utf8mb4"}
+		return mc.FormatDSN()
+	default:
+		return cfg.Path
+	}
+}
+
+// rebind 把 ? 占位符转换为方言使用的形式
+func (d *dialect) rebind(query string) string {
+	if !d.numbered {
+		return query
+	}
+
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// queryer 是 *sql.DB 和 *sql.Tx 的公共部分，使同一段SQL可以在事务内外执行
+type queryer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// insert 执行INSERT语句并返回新记录的自增ID，违反唯一约束时返回 *DuplicateError
+func (d *dialect) insert(ctx context.Context, db queryer, query string, args ...interface{}) (int64, error) {
+	if d.returning {
+		var id int64
+		err := db.QueryRowContext(ctx, d.rebind(query+" RETURNING id"), args...).Scan(&id)
+		return id, translateError(err)
+	}
+
+	result, err := db.ExecContext(ctx, d.rebind(query), args...)
+	if err != nil {
+		return 0, translateError(err)
+	}
+	return result.LastInsertId()
+}
+
+// now 返回写入数据库使用的当前时间，统一为UTC并截断到秒，保证各数据库中的时间可比较
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Second)
+}
+
+// nullableTime 把空时间转换为NULL，其余统一为UTC保存
+func nullableTime(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return t.UTC()
+}
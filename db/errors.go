@@ -0,0 +1,124 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
+)
+
+// 存储返回的错误，调用方通过 errors.Is 判断，不需要了解具体的数据库驱动
+var (
+	// ErrNotFound 要查找或修改的记录不存在
+	ErrNotFound = errors.New("记录不存在")
+
+	// ErrDuplicate 记录违反唯一约束，具体的字段通过 errors.As 取得 *DuplicateError
+	ErrDuplicate = errors.New("记录已存在")
+
+	// ErrConflict 记录在读取后已被其他人修改，如文章的版本号不一致
+	ErrConflict = errors.New("记录已被其他人修改")
+
+	// ErrInvalidCategory 分类路径中有无法生成slug的分类名，错误信息中包含具体的分类名
+	ErrInvalidCategory = errors.New("无效的分类")
+)
+
+// DuplicateError 违反唯一约束的错误，errors.Is(err, ErrDuplicate) 为真
+type DuplicateError struct {
+	// Field 重复的列名，如 username；联合唯一约束的多个列以 ", " 分隔，无法确定时为空
+	Field string
+
+	// Err 驱动返回的原始错误，内存存储中为空
+	Err error
+}
+
+// Error 实现 error 接口
+func (e *DuplicateError) Error() string {
+	if e.Field == "" {
+		return ErrDuplicate.Error()
+	}
+	return fmt.Sprintf("%s: %s", ErrDuplicate.Error(), e.Field)
+}
+
+// Is 使 errors.Is(err, ErrDuplicate) 成立
+func (e *DuplicateError) Is(target error) bool {
+	return target == ErrDuplicate
+}
+
+// Unwrap 返回驱动的原始错误
+func (e *DuplicateError) Unwrap() error {
+	return e.Err
+}
+
+// uniqueIndexColumns 迁移中创建的命名唯一索引对应的列，MySQL和PostgreSQL的错误中只有索引名时使用
+var uniqueIndexColumns = map[string]string{
+	"idx_posts_slug":      "slug",
+	"idx_media_user_hash": "user_id, hash",
+}
+
+// translateError 把驱动返回的错误转换为存储的错误：sql.ErrNoRows 转换为 ErrNotFound，
+// 违反唯一约束转换为 *DuplicateError，其他错误原样返回。已经转换过的错误不受影响
+func translateError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		if sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique || sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey {
+			// 形如 UNIQUE constraint failed: media.user_id, media.hash
+			_, columns, _ := strings.Cut(sqliteErr.Error(), ": ")
+			return &DuplicateError{Field: stripTables(columns), Err: err}
+		}
+		return err
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		if pqErr.Code == "23505" {
+			// 形如 Key (user_id, hash)=(1, abc) already exists.
+			field := uniqueIndexColumns[pqErr.Constraint]
+			if start := strings.Index(pqErr.Detail, "("); field == "" && start >= 0 {
+				if end := strings.Index(pqErr.Detail, ")="); end > start {
+					field = pqErr.Detail[start+1 : end]
+				}
+			}
+			return &DuplicateError{Field: field, Err: err}
+		}
+		return err
+	}
+
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		if mysqlErr.Number == 1062 {
+			// 形如 Duplicate entry 'bob' for key 'users.username'，MySQL 8 之前的键名不带表名
+			key := mysqlErr.Message[strings.LastIndex(mysqlErr.Message, " ")+1:]
+			key = strings.Trim(key, "'")
+			key = key[strings.LastIndex(key, ".")+1:]
+			field, ok := uniqueIndexColumns[key]
+			if !ok && key != "PRIMARY" {
+				field = key
+			}
+			return &DuplicateError{Field: field, Err: err}
+		}
+		return err
+	}
+
+	return err
+}
+
+// stripTables 去掉以逗号分隔的列名中的表名前缀
+func stripTables(columns string) string {
+	parts := strings.Split(columns, ",")
+	for i, part := range parts {
+		part = strings.TrimSpace(part)
+		parts[i] = part[strings.LastIndex(part, ".")+1:]
+	}
+	return strings.Join(parts, ", ")
+}
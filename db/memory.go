@@ -0,0 +1,784 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"goblog/models"
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// MemoryUserStore 基于内存的用户存储，进程退出后数据即丢失，适用于测试和演示模式
+type MemoryUserStore struct {
+	mu     sync.RWMutex
+	users  map[int]*models.User
+	nextID int
+}
+
+// 编译期检查是否实现了用户存储接口
+var _ models.UserStore = (*MemoryUserStore)(nil)
+
+// NewMemoryUserStore 创建内存用户存储
+func NewMemoryUserStore() *MemoryUserStore {
+	return &MemoryUserStore{
+		users:  make(map[int]*models.User),
+		nextID: 1,
+	}
+}
+
+// FindByID 根据ID查找用户
+func (s *MemoryUserStore) FindByID(ctx context.Context, id int) (*models.User, error) {
+	return s.find(id)
+}
+
+// find 根据ID查找用户并返回副本，供其他内存存储填充作者信息
+func (s *MemoryUserStore) find(id int) (*models.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	user, ok := s.users[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	copied := *user
+	return &copied, nil
+}
+
+// FindByUsername 根据用户名查找用户
+func (s *MemoryUserStore) FindByUsername(ctx context.Context, username string) (*models.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, user := range s.users {
+		if user.Username == username {
+			copied := *user
+			return &copied, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// FindByEmail 根据邮箱查找用户
+func (s *MemoryUserStore) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, user := range s.users {
+		if user.Email == email {
+			copied := *user
+			return &copied, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// Create 创建用户，密码与SQLite实现一样使用bcrypt哈希后保存，用户名或邮箱已被使用时返回 *DuplicateError
+func (s *MemoryUserStore) Create(ctx context.Context, user *models.User) error {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkUnique(0, user.Username, user.Email); err != nil {
+		return err
+	}
+
+	now := time.Now().Truncate(time.Second)
+	user.ID = s.nextID
+	user.CreatedAt = now
+	user.UpdatedAt = now
+	s.nextID++
+
+	stored := *user
+	stored.Password = string(hashedPassword)
+	s.users[stored.ID] = &stored
+
+	return nil
+}
+
+// Update 更新用户
+func (s *MemoryUserStore) Update(ctx context.Context, user *models.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.users[user.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	if err := s.checkUnique(user.ID, user.Username, user.Email); err != nil {
+		return err
+	}
+
+	stored.Username = user.Username
+	stored.Email = user.Email
+	stored.IsAdmin = user.IsAdmin
+	stored.UpdatedAt = time.Now().Truncate(time.Second)
+	user.UpdatedAt = stored.UpdatedAt
+
+	return nil
+}
+
+// Delete 删除用户
+func (s *MemoryUserStore) Delete(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.users, id)
+	return nil
+}
+
+// Authenticate 认证用户
+func (s *MemoryUserStore) Authenticate(ctx context.Context, username, password string) (*models.User, error) {
+	user, err := s.FindByUsername(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+
+	// 比较密码，密码错误与用户不存在返回相同的错误
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+		return nil, ErrNotFound
+	}
+
+	return user, nil
+}
+
+// checkUnique 检查用户名和邮箱是否被其他用户占用，调用方需持有锁
+func (s *MemoryUserStore) checkUnique(id int, username, email string) error {
+	for _, other := range s.users {
+		if other.ID == id {
+			continue
+		}
+		if other.Username == username {
+			return &DuplicateError{Field: "username"}
+		}
+		if other.Email == email {
+			return &DuplicateError{Field: "email"}
+		}
+	}
+	return nil
+}
+
+// MemoryPostStore 基于内存的文章存储，作者信息从关联的用户存储中读取
+type MemoryPostStore struct {
+	mu     sync.RWMutex
+	posts  map[int]*models.Post
+	nextID int
+
+	// revisions 按文章ID保存历史版本，按时间正序
+	revisions      map[int][]*models.Revision
+	nextRevisionID int
+
+	// categories 所有分类，标签直接保存在文章的 Tags 中
+	categories     map[int]*models.Category
+	nextCategoryID int
+
+	// slugRedirects 文章以前使用过的别名，值为文章ID
+	slugRedirects map[string]int
+
+	// comments 关联的评论存储，文章被永久删除时清理其评论
+	comments *MemoryCommentStore
+
+	users *MemoryUserStore
+}
+
+// 编译期检查是否实现了文章存储接口
+var _ models.PostStore = (*MemoryPostStore)(nil)
+
+// NewMemoryPostStore 创建内存文章存储
+func NewMemoryPostStore(users *MemoryUserStore) *MemoryPostStore {
+	return &MemoryPostStore{
+		posts:          make(map[int]*models.Post),
+		nextID:         1,
+		revisions:      make(map[int][]*models.Revision),
+		nextRevisionID: 1,
+		categories:     make(map[int]*models.Category),
+		nextCategoryID: 1,
+		slugRedirects:  make(map[string]int),
+		users:          users,
+	}
+}
+
+// FindAll 查找所有未删除的已发布文章，按创建时间倒序排列
+func (s *MemoryPostStore) FindAll(ctx context.Context) ([]*models.Post, error) {
+	s.mu.RLock()
+	posts := make([]*models.Post, 0, len(s.posts))
+	for _, post := range s.posts {
+		if post.DeletedAt != nil || !post.IsPublished() {
+			continue
+		}
+		copied := *post
+		posts = append(posts, &copied)
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(posts, func(i, j int) bool {
+		if posts[i].CreatedAt.Equal(posts[j].CreatedAt) {
+			return posts[i].ID > posts[j].ID
+		}
+		return posts[i].CreatedAt.After(posts[j].CreatedAt)
+	})
+
+	// 与SQLite实现的JOIN保持一致，跳过作者不存在的文章
+	result := posts[:0]
+	for _, post := range posts {
+		if s.attachRelations(post) {
+			result = append(result, post)
+		}
+	}
+
+	return result, nil
+}
+
+// List 按键集分页查询文章列表
+func (s *MemoryPostStore) List(ctx context.Context, opts models.ListOptions) (*models.PostPage, error) {
+	all, err := s.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return paginate(all, opts), nil
+}
+
+// ListByTag 按键集分页查询带有某个标签的文章
+func (s *MemoryPostStore) ListByTag(ctx context.Context, tag string, opts models.ListOptions) (*models.PostPage, error) {
+	all, err := s.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tag = models.NormalizeTag(tag)
+	matched := all[:0]
+	for _, post := range all {
+		for _, t := range post.Tags {
+			if t == tag {
+				matched = append(matched, post)
+				break
+			}
+		}
+	}
+	return paginate(matched, opts), nil
+}
+
+// ListByCategory 按键集分页查询某个分类及其下级分类中的文章
+func (s *MemoryPostStore) ListByCategory(ctx context.Context, slug string, opts models.ListOptions) (*models.PostPage, error) {
+	all, err := s.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	matched := all[:0]
+	for _, post := range all {
+		if post.Category != nil && models.InCategory(post.Category.Slug, slug) {
+			matched = append(matched, post)
+		}
+	}
+	return paginate(matched, opts), nil
+}
+
+// paginate 对已按发布时间倒序排列的文章做键集分页，返回的文章只包含摘要
+func paginate(all []*models.Post, opts models.ListOptions) *models.PostPage {
+	opts.Normalize()
+
+	if opts.Sort == models.SortOldest {
+		for i, j := 0, len(all)-1; i < j; i, j = i+1, j-1 {
+			all[i], all[j] = all[j], all[i]
+		}
+	}
+
+	// 找出游标范围内的文章
+	start, end := 0, len(all)
+	switch {
+	case opts.After != nil:
+		start = sort.Search(len(all), func(i int) bool {
+			return opts.Sort.Less(opts.After, models.CursorFor(all[i]))
+		})
+	case opts.Before != nil:
+		end = sort.Search(len(all), func(i int) bool {
+			return !opts.Sort.Less(models.CursorFor(all[i]), opts.Before)
+		})
+	}
+
+	// 向前翻页时保留紧挨着游标的一页
+	hasMore := end-start > opts.Limit
+	if hasMore {
+		if opts.Before != nil {
+			start = end - opts.Limit
+		} else {
+			end = start + opts.Limit
+		}
+	}
+
+	posts := all[start:end]
+	for _, post := range posts {
+		post.Excerpt = models.Excerpt(post.Content)
+		post.Content = ""
+	}
+
+	return models.NewPostPage(posts, opts, hasMore)
+}
+
+// Search 搜索标题或正文包含所有关键词的文章，标题命中的排在前面
+func (s *MemoryPostStore) Search(ctx context.Context, opts models.SearchOptions) (*models.SearchResult, error) {
+	opts.Normalize()
+
+	result := &models.SearchResult{Hits: []*models.SearchHit{}}
+	terms := models.SearchTerms(opts.Query)
+	if len(terms) == 0 {
+		return result, nil
+	}
+
+	all, err := s.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var matched, titleMatched []*models.Post
+	for _, post := range all {
+		if !models.MatchesAll(post.Title+"\n"+post.Content, terms) {
+			continue
+		}
+		if models.MatchesAll(post.Title, terms[:1]) {
+			titleMatched = append(titleMatched, post)
+		} else {
+			matched = append(matched, post)
+		}
+	}
+	matched = append(titleMatched, matched...)
+	result.Total = len(matched)
+
+	if opts.Offset >= len(matched) {
+		return result, nil
+	}
+	matched = matched[opts.Offset:]
+	if len(matched) > opts.Limit {
+		matched = matched[:opts.Limit]
+	}
+
+	for _, post := range matched {
+		result.Hits = append(result.Hits, &models.SearchHit{
+			Post:    post,
+			Title:   models.Highlight(post.Title, terms),
+			Snippet: models.Snippet(post.Content, terms),
+		})
+		post.Excerpt = models.Excerpt(post.Content)
+		post.Content = ""
+	}
+
+	return result, nil
+}
+
+// FindByID 根据ID查找文章，回收站中的文章视为不存在
+func (s *MemoryPostStore) FindByID(ctx context.Context, id int) (*models.Post, error) {
+	return s.find(id, false)
+}
+
+// find 根据ID查找文章，trashed指定查找回收站中的还是未删除的文章
+func (s *MemoryPostStore) find(id int, trashed bool) (*models.Post, error) {
+	s.mu.RLock()
+	post, ok := s.posts[id]
+	if ok && (post.DeletedAt != nil) != trashed {
+		ok = false
+	}
+	var copied models.Post
+	if ok {
+		copied = *post
+	}
+	s.mu.RUnlock()
+
+	if !ok || !s.attachRelations(&copied) {
+		return nil, ErrNotFound
+	}
+	return &copied, nil
+}
+
+// Create 创建文章，状态为空时视为已发布
+func (s *MemoryPostStore) Create(ctx context.Context, post *models.Post) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.resolveCategory(post); err != nil {
+		return err
+	}
+
+	now := time.Now().Truncate(time.Second)
+	post.ID = s.nextID
+	post.Slug = s.uniqueSlug(post)
+	post.CreatedAt = now
+	post.UpdatedAt = now
+	post.Version = 1
+	delete(s.slugRedirects, post.Slug)
+	if post.IsPublished() {
+		post.Status = models.StatusPublished
+		post.PublishAt = &now
+	}
+	if post.Format == "" {
+		post.Format = models.FormatMarkdown
+	}
+	s.nextID++
+
+	stored := *post
+	stored.User = nil
+	stored.Category = nil
+	stored.Tags = append([]string{}, post.Tags...)
+	s.posts[stored.ID] = &stored
+	s.addRevision(&stored, post.UserID)
+
+	return nil
+}
+
+// Update 更新文章及其状态，并以editorID为作者保存一个新版本
+func (s *MemoryPostStore) Update(ctx context.Context, post *models.Post, editorID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.posts[post.ID]
+	if !ok || stored.DeletedAt != nil {
+		return ErrNotFound
+	}
+	if post.Version != stored.Version {
+		return ErrConflict
+	}
+	if err := s.resolveCategory(post); err != nil {
+		return err
+	}
+
+	post.Slug = s.uniqueSlug(post)
+	if post.Slug != stored.Slug {
+		delete(s.slugRedirects, post.Slug)
+		s.slugRedirects[stored.Slug] = stored.ID
+	}
+
+	stored.Title = post.Title
+	stored.Slug = post.Slug
+	stored.Content = post.Content
+	stored.Format = post.Format
+	if stored.Format == "" {
+		stored.Format = models.FormatMarkdown
+	}
+	stored.Tags = append([]string{}, post.Tags...)
+	stored.CategoryID = post.CategoryID
+	stored.Status = post.Status
+	if stored.Status == "" {
+		stored.Status = models.StatusPublished
+	}
+	stored.PublishAt = post.PublishAt
+	stored.CreatedAt = post.CreatedAt
+	stored.UpdatedAt = time.Now().Truncate(time.Second)
+	stored.Version++
+	post.UpdatedAt = stored.UpdatedAt
+	post.Version = stored.Version
+	s.addRevision(stored, editorID)
+
+	return nil
+}
+
+// Delete 把文章移入回收站
+func (s *MemoryPostStore) Delete(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	post, ok := s.posts[id]
+	if !ok || post.DeletedAt != nil {
+		return ErrNotFound
+	}
+	now := time.Now().Truncate(time.Second)
+	post.DeletedAt = &now
+	return nil
+}
+
+// Drafts 查找用户的草稿和定时文章，按更新时间倒序
+func (s *MemoryPostStore) Drafts(ctx context.Context, userID int) ([]*models.Post, error) {
+	s.mu.RLock()
+	posts := []*models.Post{}
+	for _, post := range s.posts {
+		if post.UserID != userID || post.DeletedAt != nil || post.IsPublished() {
+			continue
+		}
+		copied := *post
+		posts = append(posts, &copied)
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(posts, func(i, j int) bool {
+		if posts[i].UpdatedAt.Equal(posts[j].UpdatedAt) {
+			return posts[i].ID > posts[j].ID
+		}
+		return posts[i].UpdatedAt.After(posts[j].UpdatedAt)
+	})
+
+	result := posts[:0]
+	for _, post := range posts {
+		if s.attachRelations(post) {
+			post.Excerpt = models.Excerpt(post.Content)
+			post.Content = ""
+			result = append(result, post)
+		}
+	}
+	return result, nil
+}
+
+// PublishDue 发布定时发布时间不晚于now的文章，发布时间取计划的时间而不是实际执行的时间
+func (s *MemoryPostStore) PublishDue(ctx context.Context, now time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	published := 0
+	for _, post := range s.posts {
+		if post.Status != models.StatusScheduled || post.DeletedAt != nil || post.PublishAt.After(now) {
+			continue
+		}
+		post.Status = models.StatusPublished
+		post.CreatedAt = *post.PublishAt
+		if post.UpdatedAt.Before(post.CreatedAt) {
+			post.UpdatedAt = post.CreatedAt
+		}
+		published++
+	}
+	return published, nil
+}
+
+// Trash 查找用户回收站中的文章，按删除时间倒序
+func (s *MemoryPostStore) Trash(ctx context.Context, userID int) ([]*models.Post, error) {
+	s.mu.RLock()
+	posts := []*models.Post{}
+	for _, post := range s.posts {
+		if post.UserID != userID || post.DeletedAt == nil {
+			continue
+		}
+		copied := *post
+		posts = append(posts, &copied)
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(posts, func(i, j int) bool {
+		if posts[i].DeletedAt.Equal(*posts[j].DeletedAt) {
+			return posts[i].ID > posts[j].ID
+		}
+		return posts[i].DeletedAt.After(*posts[j].DeletedAt)
+	})
+
+	result := posts[:0]
+	for _, post := range posts {
+		if s.attachRelations(post) {
+			post.Excerpt = models.Excerpt(post.Content)
+			post.Content = ""
+			result = append(result, post)
+		}
+	}
+	return result, nil
+}
+
+// FindTrashed 根据ID查找回收站中的文章
+func (s *MemoryPostStore) FindTrashed(ctx context.Context, id int) (*models.Post, error) {
+	return s.find(id, true)
+}
+
+// Restore 把文章从回收站中恢复
+func (s *MemoryPostStore) Restore(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	post, ok := s.posts[id]
+	if !ok || post.DeletedAt == nil {
+		return ErrNotFound
+	}
+	post.DeletedAt = nil
+	return nil
+}
+
+// Purge 永久删除回收站中的文章及其历史版本和评论
+func (s *MemoryPostStore) Purge(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	post, ok := s.posts[id]
+	if !ok || post.DeletedAt == nil {
+		return ErrNotFound
+	}
+	s.purge(id)
+	return nil
+}
+
+// PurgeDeletedBefore 永久删除在before之前移入回收站的文章，返回删除的数量
+func (s *MemoryPostStore) PurgeDeletedBefore(ctx context.Context, before time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	purged := 0
+	for id, post := range s.posts {
+		if post.DeletedAt != nil && post.DeletedAt.Before(before) {
+			s.purge(id)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+// purge 删除文章及其历史版本、旧别名和评论，调用方需持有锁
+func (s *MemoryPostStore) purge(id int) {
+	delete(s.posts, id)
+	delete(s.revisions, id)
+	for slug, postID := range s.slugRedirects {
+		if postID == id {
+			delete(s.slugRedirects, slug)
+		}
+	}
+	if s.comments != nil {
+		s.comments.deletePost(id)
+	}
+}
+
+// addRevision 以文章当前内容保存一个版本，调用方需持有锁
+func (s *MemoryPostStore) addRevision(post *models.Post, editorID int) {
+	s.revisions[post.ID] = append(s.revisions[post.ID], &models.Revision{
+		ID:        s.nextRevisionID,
+		PostID:    post.ID,
+		UserID:    editorID,
+		Title:     post.Title,
+		Content:   post.Content,
+		Format:    post.Format,
+		CreatedAt: post.UpdatedAt,
+	})
+	s.nextRevisionID++
+}
+
+// Revisions 查找文章的所有历史版本，按时间倒序
+func (s *MemoryPostStore) Revisions(ctx context.Context, postID int) ([]*models.Revision, error) {
+	s.mu.RLock()
+	stored := s.revisions[postID]
+	revisions := make([]*models.Revision, 0, len(stored))
+	for i := len(stored) - 1; i >= 0; i-- {
+		copied := *stored[i]
+		copied.HTML = ""
+		revisions = append(revisions, &copied)
+	}
+	s.mu.RUnlock()
+
+	for _, revision := range revisions {
+		s.attachRevisionUser(revision)
+	}
+	return revisions, nil
+}
+
+// FindRevision 查找文章的某个历史版本
+func (s *MemoryPostStore) FindRevision(ctx context.Context, postID, revisionID int) (*models.Revision, error) {
+	s.mu.RLock()
+	var found *models.Revision
+	for _, revision := range s.revisions[postID] {
+		if revision.ID == revisionID {
+			copied := *revision
+			copied.HTML = ""
+			found = &copied
+			break
+		}
+	}
+	s.mu.RUnlock()
+
+	if found == nil {
+		return nil, ErrNotFound
+	}
+	s.attachRevisionUser(found)
+	return found, nil
+}
+
+// LatestRevision 查找文章的最新版本，包括渲染结果的缓存
+func (s *MemoryPostStore) LatestRevision(ctx context.Context, postID int) (*models.Revision, error) {
+	s.mu.RLock()
+	stored := s.revisions[postID]
+	var latest *models.Revision
+	if len(stored) > 0 {
+		copied := *stored[len(stored)-1]
+		latest = &copied
+	}
+	s.mu.RUnlock()
+
+	if latest == nil {
+		return nil, ErrNotFound
+	}
+	s.attachRevisionUser(latest)
+	return latest, nil
+}
+
+// SaveRevisionHTML 保存版本的渲染结果
+func (s *MemoryPostStore) SaveRevisionHTML(ctx context.Context, revisionID int, html string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, revisions := range s.revisions {
+		for _, revision := range revisions {
+			if revision.ID == revisionID {
+				revision.HTML = html
+				return nil
+			}
+		}
+	}
+	return ErrNotFound
+}
+
+// attachRevisionUser 填充版本的修改者（不含密码），用户已删除时保持为空
+func (s *MemoryPostStore) attachRevisionUser(revision *models.Revision) {
+	user, err := s.users.find(revision.UserID)
+	if err != nil {
+		return
+	}
+	user.Password = ""
+	revision.User = user
+}
+
+// attachRelations 填充文章作者（不含密码）和分类，作者不存在时返回false
+func (s *MemoryPostStore) attachRelations(post *models.Post) bool {
+	user, err := s.users.find(post.UserID)
+	if err != nil {
+		return false
+	}
+	user.Password = ""
+	post.User = user
+
+	post.Tags = append([]string{}, post.Tags...)
+	post.Category = s.category(post.CategoryID)
+	return true
+}
+
+// FindBySlug 根据别名查找文章，找不到时再查找旧别名
+func (s *MemoryPostStore) FindBySlug(ctx context.Context, slug string) (*models.Post, error) {
+	s.mu.RLock()
+	id, ok := s.slugRedirects[slug]
+	for _, post := range s.posts {
+		if post.Slug == slug && post.DeletedAt == nil {
+			id, ok = post.ID, true
+			break
+		}
+	}
+	s.mu.RUnlock()
+
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return s.FindByID(ctx, id)
+}
+
+// uniqueSlug 返回不与其他文章重复的别名，重复时依次尝试加上 -2、-3 等后缀，调用方需持有锁
+func (s *MemoryPostStore) uniqueSlug(post *models.Post) string {
+	base := post.Slug
+	if base == "" {
+		base = models.MakeSlug(post.Title, models.SlugUnicode)
+	}
+
+	taken := make(map[string]bool, len(s.posts))
+	for _, other := range s.posts {
+		if other.ID != post.ID {
+			taken[other.Slug] = true
+		}
+	}
+
+	slug := base
+	for n := 2; taken[slug]; n++ {
+		slug = fmt.Sprintf("%s-%d", base, n)
+	}
+	return slug
+}
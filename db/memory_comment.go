@@ -0,0 +1,150 @@
+package db
+
+import (
+	"context"
+	"goblog/models"
+	"sort"
+	"sync"
+)
+
+// MemoryCommentStore 基于内存的评论存储，作者信息从关联的用户存储中读取
+type MemoryCommentStore struct {
+	mu       sync.RWMutex
+	comments map[int]*models.Comment
+	nextID   int
+
+	users *MemoryUserStore
+}
+
+// 编译期检查是否实现了评论存储接口
+var _ models.CommentStore = (*MemoryCommentStore)(nil)
+
+// NewMemoryCommentStore 创建内存评论存储，文章被永久删除时其评论一并删除
+func NewMemoryCommentStore(users *MemoryUserStore, posts *MemoryPostStore) *MemoryCommentStore {
+	s := &MemoryCommentStore{
+		comments: make(map[int]*models.Comment),
+		nextID:   1,
+		users:    users,
+	}
+	posts.comments = s
+	return s
+}
+
+// ListByPost 查找文章的所有评论，包括已删除的，按发表时间正序
+func (s *MemoryCommentStore) ListByPost(ctx context.Context, postID int) ([]*models.Comment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	comments := []*models.Comment{}
+	for _, comment := range s.comments {
+		if comment.PostID == postID {
+			comments = append(comments, s.copy(comment))
+		}
+	}
+	sort.Slice(comments, func(i, j int) bool {
+		return comments[i].ID < comments[j].ID
+	})
+	return comments, nil
+}
+
+// CountByPosts 统计每篇文章未删除的评论数
+func (s *MemoryCommentStore) CountByPosts(ctx context.Context, postIDs []int) (map[int]int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	wanted := make(map[int]bool, len(postIDs))
+	for _, id := range postIDs {
+		wanted[id] = true
+	}
+
+	counts := make(map[int]int)
+	for _, comment := range s.comments {
+		if comment.DeletedAt == nil && wanted[comment.PostID] {
+			counts[comment.PostID]++
+		}
+	}
+	return counts, nil
+}
+
+// FindByID 根据ID查找未删除的评论
+func (s *MemoryCommentStore) FindByID(ctx context.Context, id int) (*models.Comment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	comment, ok := s.comments[id]
+	if !ok || comment.DeletedAt != nil {
+		return nil, ErrNotFound
+	}
+	return s.copy(comment), nil
+}
+
+// Create 创建评论
+func (s *MemoryCommentStore) Create(ctx context.Context, comment *models.Comment) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := now().Local()
+	comment.ID = s.nextID
+	comment.CreatedAt = now
+	comment.UpdatedAt = now
+	s.nextID++
+
+	stored := *comment
+	stored.User = nil
+	s.comments[comment.ID] = &stored
+	return nil
+}
+
+// Update 更新评论内容
+func (s *MemoryCommentStore) Update(ctx context.Context, comment *models.Comment) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.comments[comment.ID]
+	if !ok || stored.DeletedAt != nil {
+		return ErrNotFound
+	}
+	stored.Content = comment.Content
+	stored.UpdatedAt = now().Local()
+	comment.UpdatedAt = stored.UpdatedAt
+	return nil
+}
+
+// Delete 删除评论，只标记删除时间，回复仍然保留
+func (s *MemoryCommentStore) Delete(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.comments[id]
+	if !ok || stored.DeletedAt != nil {
+		return ErrNotFound
+	}
+	deletedAt := now().Local()
+	stored.DeletedAt = &deletedAt
+	return nil
+}
+
+// deletePost 删除文章的所有评论，在文章被永久删除时调用
+func (s *MemoryCommentStore) deletePost(postID int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, comment := range s.comments {
+		if comment.PostID == postID {
+			delete(s.comments, id)
+		}
+	}
+}
+
+// copy 复制评论并填充作者（不含密码），已删除评论的内容不返回，调用方需持有锁
+func (s *MemoryCommentStore) copy(comment *models.Comment) *models.Comment {
+	copied := *comment
+	if copied.DeletedAt != nil {
+		copied.Content = ""
+	}
+	if user, err := s.users.find(copied.UserID); err == nil {
+		user.Password = ""
+		copied.User = user
+	}
+	return &copied
+}
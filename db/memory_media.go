@@ -0,0 +1,200 @@
+package db
+
+import (
+	"context"
+	"goblog/models"
+	"sort"
+	"sync"
+)
+
+// MemoryMediaStore 基于内存的上传文件存储，只保存文件信息，文件内容仍保存在上传目录中
+type MemoryMediaStore struct {
+	mu     sync.RWMutex
+	media  map[int]*models.Media
+	nextID int
+}
+
+// 编译期检查是否实现了上传文件存储接口
+var _ models.MediaStore = (*MemoryMediaStore)(nil)
+
+// NewMemoryMediaStore 创建内存上传文件存储
+func NewMemoryMediaStore() *MemoryMediaStore {
+	return &MemoryMediaStore{
+		media:  make(map[int]*models.Media),
+		nextID: 1,
+	}
+}
+
+// FindAll 查找所有用户上传的文件，按ID正序
+func (s *MemoryMediaStore) FindAll(ctx context.Context) ([]*models.Media, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	media := make([]*models.Media, 0, len(s.media))
+	for _, m := range s.media {
+		copied := *m
+		media = append(media, &copied)
+	}
+	sort.Slice(media, func(i, j int) bool {
+		return media[i].ID < media[j].ID
+	})
+	return media, nil
+}
+
+// ListByUser 查找用户上传的所有文件，最新的在前
+func (s *MemoryMediaStore) ListByUser(ctx context.Context, userID int) ([]*models.Media, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	media := []*models.Media{}
+	for _, m := range s.media {
+		if m.UserID == userID {
+			copied := *m
+			media = append(media, &copied)
+		}
+	}
+	sort.Slice(media, func(i, j int) bool {
+		return media[i].ID > media[j].ID
+	})
+	return media, nil
+}
+
+// FindByID 根据ID查找文件
+func (s *MemoryMediaStore) FindByID(ctx context.Context, id int) (*models.Media, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	m, ok := s.media[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	copied := *m
+	return &copied, nil
+}
+
+// FindByHash 查找用户上传过的相同内容的文件
+func (s *MemoryMediaStore) FindByHash(ctx context.Context, userID int, hash string) (*models.Media, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, m := range s.media {
+		if m.UserID == userID && m.Hash == hash {
+			copied := *m
+			return &copied, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// FindByHashes 按内容查找文件，每个内容返回ID最小的一条记录
+func (s *MemoryMediaStore) FindByHashes(ctx context.Context, hashes []string) (map[string]*models.Media, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	wanted := make(map[string]bool, len(hashes))
+	for _, hash := range hashes {
+		wanted[hash] = true
+	}
+
+	media := make(map[string]*models.Media)
+	for _, m := range s.media {
+		if !wanted[m.Hash] {
+			continue
+		}
+		if existing, ok := media[m.Hash]; !ok || m.ID < existing.ID {
+			copied := *m
+			media[m.Hash] = &copied
+		}
+	}
+	return media, nil
+}
+
+// ListByHash 查找引用该内容的所有文件记录，按ID正序
+func (s *MemoryMediaStore) ListByHash(ctx context.Context, hash string) ([]*models.Media, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	media := []*models.Media{}
+	for _, m := range s.media {
+		if m.Hash == hash {
+			copied := *m
+			media = append(media, &copied)
+		}
+	}
+	sort.Slice(media, func(i, j int) bool {
+		return media[i].ID < media[j].ID
+	})
+	return media, nil
+}
+
+// CountByHash 统计引用该内容的文件数
+func (s *MemoryMediaStore) CountByHash(ctx context.Context, hash string) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	count := 0
+	for _, m := range s.media {
+		if m.Hash == hash {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// Create 创建文件记录
+func (s *MemoryMediaStore) Create(ctx context.Context, media *models.Media) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, m := range s.media {
+		if m.UserID == media.UserID && m.Hash == media.Hash {
+			return &DuplicateError{Field: "user_id, hash"}
+		}
+	}
+
+	media.ID = s.nextID
+	media.CreatedAt = now().Local()
+	s.nextID++
+
+	stored := *media
+	s.media[media.ID] = &stored
+	return nil
+}
+
+// SetSkippedVariants 更新引用该内容的所有文件记录中没有生成的版本名称
+func (s *MemoryMediaStore) SetSkippedVariants(ctx context.Context, hash string, skipped []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, m := range s.media {
+		if m.Hash == hash {
+			m.SkippedVariants = append([]string(nil), skipped...)
+		}
+	}
+	return nil
+}
+
+// SetPrivate 修改文件的可见范围
+func (s *MemoryMediaStore) SetPrivate(ctx context.Context, id int, private bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m, ok := s.media[id]
+	if !ok {
+		return ErrNotFound
+	}
+	m.Private = private
+	return nil
+}
+
+// Delete 删除文件记录
+func (s *MemoryMediaStore) Delete(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.media[id]; !ok {
+		return ErrNotFound
+	}
+	delete(s.media, id)
+	return nil
+}
@@ -0,0 +1,459 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"goblog/models"
+	"sort"
+)
+
+// MemorySiteStore 基于内存的整站数据存储，直接读写其他内存存储中的数据
+type MemorySiteStore struct {
+	users    *MemoryUserStore
+	posts    *MemoryPostStore
+	comments *MemoryCommentStore
+	media    *MemoryMediaStore
+}
+
+// 编译期检查是否实现了整站数据存储接口
+var _ models.SiteStore = (*MemorySiteStore)(nil)
+
+// NewMemorySiteStore 创建内存整站数据存储
+func NewMemorySiteStore(users *MemoryUserStore, posts *MemoryPostStore, comments *MemoryCommentStore, media *MemoryMediaStore) *MemorySiteStore {
+	return &MemorySiteStore{users: users, posts: posts, comments: comments, media: media}
+}
+
+// lock 锁定所有存储，顺序与文章存储清理评论、评论存储读取作者时一致，返回解锁函数
+func (s *MemorySiteStore) lock() func() {
+	s.posts.mu.Lock()
+	s.comments.mu.Lock()
+	s.media.mu.Lock()
+	s.users.mu.Lock()
+	return func() {
+		s.users.mu.Unlock()
+		s.media.mu.Unlock()
+		s.comments.mu.Unlock()
+		s.posts.mu.Unlock()
+	}
+}
+
+// Dump 读取整站数据，所有记录按ID正序，作者已被删除的文章和评论不包括在内
+func (s *MemorySiteStore) Dump(ctx context.Context) (*models.SiteData, error) {
+	unlock := s.lock()
+	defer unlock()
+
+	data := &models.SiteData{}
+	for _, user := range s.users.users {
+		copied := *user
+		data.Users = append(data.Users, &copied)
+	}
+	sort.Slice(data.Users, func(i, j int) bool { return data.Users[i].ID < data.Users[j].ID })
+
+	for _, category := range s.posts.categories {
+		copied := *category
+		data.Categories = append(data.Categories, &copied)
+	}
+	sort.Slice(data.Categories, func(i, j int) bool { return data.Categories[i].ID < data.Categories[j].ID })
+
+	for _, post := range s.posts.posts {
+		if _, ok := s.users.users[post.UserID]; !ok {
+			continue
+		}
+		copied := *post
+		copied.Tags = append([]string{}, post.Tags...)
+		data.Posts = append(data.Posts, &copied)
+
+		for _, revision := range s.posts.revisions[post.ID] {
+			copied := *revision
+			copied.HTML = ""
+			data.Revisions = append(data.Revisions, &copied)
+		}
+	}
+	sort.Slice(data.Posts, func(i, j int) bool { return data.Posts[i].ID < data.Posts[j].ID })
+	sort.Slice(data.Revisions, func(i, j int) bool { return data.Revisions[i].ID < data.Revisions[j].ID })
+
+	// 内存存储不记录旧别名的创建时间，使用文章的更新时间
+	for slug, postID := range s.posts.slugRedirects {
+		if post, ok := s.posts.posts[postID]; ok {
+			data.Redirects = append(data.Redirects, &models.SlugRedirect{Slug: slug, PostID: postID, CreatedAt: post.UpdatedAt})
+		}
+	}
+	sort.Slice(data.Redirects, func(i, j int) bool { return data.Redirects[i].Slug < data.Redirects[j].Slug })
+
+	for _, comment := range s.comments.comments {
+		if _, ok := s.users.users[comment.UserID]; !ok {
+			continue
+		}
+		copied := *comment
+		if copied.DeletedAt != nil {
+			copied.Content = ""
+		}
+		data.Comments = append(data.Comments, &copied)
+	}
+	sort.Slice(data.Comments, func(i, j int) bool { return data.Comments[i].ID < data.Comments[j].ID })
+
+	for _, m := range s.media.media {
+		copied := *m
+		data.Media = append(data.Media, &copied)
+	}
+	sort.Slice(data.Media, func(i, j int) bool { return data.Media[i].ID < data.Media[j].ID })
+
+	return data, nil
+}
+
+// Load 写入整站数据，出错或试运行时恢复写入前的状态
+func (s *MemorySiteStore) Load(ctx context.Context, data *models.SiteData, existing map[int]int, dryRun bool) (*models.LoadResult, error) {
+	unlock := s.lock()
+	defer unlock()
+
+	saved := s.save()
+	l := &memoryLoader{store: s, result: &models.LoadResult{RenamedSlugs: map[string]string{}}}
+	err := l.load(data, existing)
+	if err != nil || dryRun {
+		s.restore(saved)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return l.result, nil
+}
+
+// memoryState 导入前各存储的状态，导入只会新增记录、覆盖用户和删除旧别名，
+// 因此除用户外只需复制各个map
+type memoryState struct {
+	users          map[int]models.User
+	nextUserID     int
+	posts          map[int]*models.Post
+	nextPostID     int
+	revisions      map[int][]*models.Revision
+	nextRevisionID int
+	categories     map[int]*models.Category
+	nextCategoryID int
+	slugRedirects  map[string]int
+	comments       map[int]*models.Comment
+	nextCommentID  int
+	media          map[int]*models.Media
+	nextMediaID    int
+}
+
+// save 保存当前状态，调用方需持有锁
+func (s *MemorySiteStore) save() *memoryState {
+	state := &memoryState{
+		users:          make(map[int]models.User, len(s.users.users)),
+		nextUserID:     s.users.nextID,
+		posts:          make(map[int]*models.Post, len(s.posts.posts)),
+		nextPostID:     s.posts.nextID,
+		revisions:      make(map[int][]*models.Revision, len(s.posts.revisions)),
+		nextRevisionID: s.posts.nextRevisionID,
+		categories:     make(map[int]*models.Category, len(s.posts.categories)),
+		nextCategoryID: s.posts.nextCategoryID,
+		slugRedirects:  make(map[string]int, len(s.posts.slugRedirects)),
+		comments:       make(map[int]*models.Comment, len(s.comments.comments)),
+		nextCommentID:  s.comments.nextID,
+		media:          make(map[int]*models.Media, len(s.media.media)),
+		nextMediaID:    s.media.nextID,
+	}
+	for id, user := range s.users.users {
+		state.users[id] = *user
+	}
+	for id, post := range s.posts.posts {
+		state.posts[id] = post
+	}
+	for id, revisions := range s.posts.revisions {
+		state.revisions[id] = revisions
+	}
+	for id, category := range s.posts.categories {
+		state.categories[id] = category
+	}
+	for slug, id := range s.posts.slugRedirects {
+		state.slugRedirects[slug] = id
+	}
+	for id, comment := range s.comments.comments {
+		state.comments[id] = comment
+	}
+	for id, m := range s.media.media {
+		state.media[id] = m
+	}
+	return state
+}
+
+// restore 恢复save保存的状态，调用方需持有锁
+func (s *MemorySiteStore) restore(state *memoryState) {
+	s.users.users = make(map[int]*models.User, len(state.users))
+	for id, user := range state.users {
+		user := user
+		s.users.users[id] = &user
+	}
+	s.users.nextID = state.nextUserID
+	s.posts.posts = state.posts
+	s.posts.nextID = state.nextPostID
+	s.posts.revisions = state.revisions
+	s.posts.nextRevisionID = state.nextRevisionID
+	s.posts.categories = state.categories
+	s.posts.nextCategoryID = state.nextCategoryID
+	s.posts.slugRedirects = state.slugRedirects
+	s.comments.comments = state.comments
+	s.comments.nextID = state.nextCommentID
+	s.media.media = state.media
+	s.media.nextID = state.nextMediaID
+}
+
+// memoryLoader 一次导入的状态，保存导入数据中的ID到新ID的映射
+type memoryLoader struct {
+	store  *MemorySiteStore
+	result *models.LoadResult
+
+	users      map[int]int
+	categories map[int]*models.Category
+	posts      map[int]*models.Post
+	comments   map[int]int
+}
+
+// load 按依赖顺序写入各类记录
+func (l *memoryLoader) load(data *models.SiteData, existing map[int]int) error {
+	if err := l.loadUsers(data.Users, existing); err != nil {
+		return err
+	}
+	if err := l.loadCategories(data.Categories); err != nil {
+		return err
+	}
+	if err := l.loadPosts(data.Posts); err != nil {
+		return err
+	}
+	if err := l.loadRedirects(data.Redirects); err != nil {
+		return err
+	}
+	if err := l.loadRevisions(data.Revisions); err != nil {
+		return err
+	}
+	if err := l.loadComments(data.Comments); err != nil {
+		return err
+	}
+	return l.loadMedia(data.Media)
+}
+
+// loadUsers 创建新用户，或用导入的资料覆盖映射到的现有用户
+func (l *memoryLoader) loadUsers(users []*models.User, existing map[int]int) error {
+	store := l.store.users
+	l.users = make(map[int]int, len(users)+len(existing))
+	for from, to := range existing {
+		l.users[from] = to
+	}
+
+	now := now().Local()
+	for _, user := range users {
+		if id, ok := existing[user.ID]; ok {
+			stored, ok := store.users[id]
+			if !ok {
+				return fmt.Errorf("覆盖用户 %s 失败: 现有用户不存在", user.Username)
+			}
+			if err := store.checkUnique(id, user.Username, user.Email); err != nil {
+				return fmt.Errorf("覆盖用户 %s 失败: %w", user.Username, err)
+			}
+			stored.Username = user.Username
+			stored.Email = user.Email
+			stored.IsAdmin = user.IsAdmin
+			if user.Password != "" {
+				stored.Password = user.Password
+			}
+			stored.UpdatedAt = now
+			l.result.Overwritten++
+			continue
+		}
+
+		if user.Password == "" {
+			return fmt.Errorf("新用户 %s 没有密码哈希", user.Username)
+		}
+		if err := store.checkUnique(0, user.Username, user.Email); err != nil {
+			return fmt.Errorf("创建用户 %s 失败: %w", user.Username, err)
+		}
+		stored := *user
+		stored.ID = store.nextID
+		store.nextID++
+		store.users[stored.ID] = &stored
+		l.users[user.ID] = stored.ID
+		l.result.Users++
+	}
+	return nil
+}
+
+// loadCategories 按名称重建分类树，与现有分类重复的直接使用现有分类
+func (l *memoryLoader) loadCategories(categories []*models.Category) error {
+	l.categories = make(map[int]*models.Category, len(categories))
+	for _, category := range categories {
+		var parent *models.Category
+		if category.ParentID != 0 {
+			var ok bool
+			if parent, ok = l.categories[category.ParentID]; !ok {
+				return fmt.Errorf("分类 %s 的上级分类不在导入数据中", category.Path)
+			}
+		}
+
+		target, created, err := l.store.posts.ensureChild(parent, category.Name)
+		if err != nil {
+			return fmt.Errorf("创建分类 %s 失败: %w", category.Path, err)
+		}
+		l.categories[category.ID] = target
+		if created {
+			l.result.Categories++
+		}
+	}
+	return nil
+}
+
+// loadPosts 创建文章，别名重复时加上后缀
+func (l *memoryLoader) loadPosts(posts []*models.Post) error {
+	store := l.store.posts
+	l.posts = make(map[int]*models.Post, len(posts))
+	for _, p := range posts {
+		post := *p
+		userID, ok := l.users[p.UserID]
+		if !ok {
+			return fmt.Errorf("文章 %s 的作者不在导入数据中", p.Slug)
+		}
+		post.UserID = userID
+		if p.CategoryID != 0 {
+			category, ok := l.categories[p.CategoryID]
+			if !ok {
+				return fmt.Errorf("文章 %s 的分类不在导入数据中", p.Slug)
+			}
+			post.CategoryID = category.ID
+		}
+		if post.Format == "" {
+			post.Format = models.FormatMarkdown
+		}
+		if post.Status == "" {
+			post.Status = models.StatusPublished
+		}
+
+		post.ID = store.nextID
+		post.Version = 1
+		store.nextID++
+		post.Slug = store.uniqueSlug(&post)
+		if post.Slug != p.Slug {
+			l.result.RenamedSlugs[p.Slug] = post.Slug
+		}
+		delete(store.slugRedirects, post.Slug)
+
+		post.User = nil
+		post.Category = nil
+		post.Tags = append([]string{}, p.Tags...)
+		store.posts[post.ID] = &post
+		l.posts[p.ID] = &post
+		l.result.Posts++
+	}
+	return nil
+}
+
+// loadRedirects 保存文章的旧别名，已被其他文章或旧别名占用的跳过
+func (l *memoryLoader) loadRedirects(redirects []*models.SlugRedirect) error {
+	store := l.store.posts
+	taken := make(map[string]bool, len(store.posts))
+	for _, post := range store.posts {
+		taken[post.Slug] = true
+	}
+
+	for _, redirect := range redirects {
+		post, ok := l.posts[redirect.PostID]
+		if !ok {
+			return fmt.Errorf("旧别名 %s 对应的文章不在导入数据中", redirect.Slug)
+		}
+		if _, ok := store.slugRedirects[redirect.Slug]; ok || taken[redirect.Slug] {
+			continue
+		}
+		store.slugRedirects[redirect.Slug] = post.ID
+	}
+	return nil
+}
+
+// loadRevisions 保存文章的历史版本，修改者不在导入数据中时记为文章作者
+func (l *memoryLoader) loadRevisions(revisions []*models.Revision) error {
+	store := l.store.posts
+	for _, revision := range revisions {
+		post, ok := l.posts[revision.PostID]
+		if !ok {
+			return fmt.Errorf("历史版本 %d 对应的文章不在导入数据中", revision.ID)
+		}
+		userID, ok := l.users[revision.UserID]
+		if !ok {
+			userID = post.UserID
+		}
+
+		store.revisions[post.ID] = append(store.revisions[post.ID], &models.Revision{
+			ID:        store.nextRevisionID,
+			PostID:    post.ID,
+			UserID:    userID,
+			Title:     revision.Title,
+			Content:   revision.Content,
+			Format:    revision.Format,
+			CreatedAt: revision.CreatedAt,
+		})
+		store.nextRevisionID++
+		l.result.Revisions++
+	}
+	return nil
+}
+
+// loadComments 保存评论，保持回复关系
+func (l *memoryLoader) loadComments(comments []*models.Comment) error {
+	store := l.store.comments
+	l.comments = make(map[int]int, len(comments))
+	for _, comment := range comments {
+		post, ok := l.posts[comment.PostID]
+		if !ok {
+			return fmt.Errorf("评论 %d 对应的文章不在导入数据中", comment.ID)
+		}
+		userID, ok := l.users[comment.UserID]
+		if !ok {
+			return fmt.Errorf("评论 %d 的作者不在导入数据中", comment.ID)
+		}
+		var parentID int
+		if comment.ParentID != 0 {
+			if parentID, ok = l.comments[comment.ParentID]; !ok {
+				return fmt.Errorf("评论 %d 回复的评论不在导入数据中", comment.ID)
+			}
+		}
+
+		stored := *comment
+		stored.ID = store.nextID
+		store.nextID++
+		stored.PostID = post.ID
+		stored.UserID = userID
+		stored.ParentID = parentID
+		stored.User = nil
+		store.comments[stored.ID] = &stored
+		l.comments[comment.ID] = stored.ID
+		l.result.Comments++
+	}
+	return nil
+}
+
+// loadMedia 保存上传文件的记录，用户已有相同内容的文件时跳过
+func (l *memoryLoader) loadMedia(media []*models.Media) error {
+	store := l.store.media
+	for _, m := range media {
+		userID, ok := l.users[m.UserID]
+		if !ok {
+			return fmt.Errorf("文件 %s 的上传者不在导入数据中", m.FileName)
+		}
+
+		duplicate := false
+		for _, other := range store.media {
+			if other.UserID == userID && other.Hash == m.Hash {
+				duplicate = true
+				break
+			}
+		}
+		if duplicate {
+			continue
+		}
+
+		stored := *m
+		stored.ID = store.nextID
+		store.nextID++
+		stored.UserID = userID
+		store.media[stored.ID] = &stored
+		l.result.Media++
+	}
+	return nil
+}
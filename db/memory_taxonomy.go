@@ -0,0 +1,193 @@
+package db
+
+import (
+	"context"
+	"goblog/models"
+	"sort"
+)
+
+// 编译期检查是否实现了标签和分类存储接口
+var _ models.TaxonomyStore = (*MemoryPostStore)(nil)
+
+// category 返回分类的副本，id为0或分类不存在时返回nil
+func (s *MemoryPostStore) category(id int) *models.Category {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	category, ok := s.categories[id]
+	if !ok {
+		return nil
+	}
+	copied := *category
+	return &copied
+}
+
+// Tags 查找所有至少有一篇文章的标签，按文章数倒序
+func (s *MemoryPostStore) Tags(ctx context.Context) ([]*models.Tag, error) {
+	posts, err := s.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int)
+	for _, post := range posts {
+		for _, tag := range post.Tags {
+			counts[tag]++
+		}
+	}
+
+	tags := make([]*models.Tag, 0, len(counts))
+	for name, count := range counts {
+		tags = append(tags, &models.Tag{Name: name, PostCount: count})
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		if tags[i].PostCount != tags[j].PostCount {
+			return tags[i].PostCount > tags[j].PostCount
+		}
+		return tags[i].Name < tags[j].Name
+	})
+
+	return tags, nil
+}
+
+// FindTag 根据名称查找标签，没有文章使用的标签视为不存在
+func (s *MemoryPostStore) FindTag(ctx context.Context, name string) (*models.Tag, error) {
+	tags, err := s.Tags(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	name = models.NormalizeTag(name)
+	for _, tag := range tags {
+		if tag.Name == name {
+			return tag, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// Categories 查找所有分类，按树形结构深度优先排列，文章数包括下级分类中的文章
+func (s *MemoryPostStore) Categories(ctx context.Context) ([]*models.Category, error) {
+	posts, err := s.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	categories := make([]*models.Category, 0, len(s.categories))
+	byID := make(map[int]*models.Category, len(s.categories))
+	for _, category := range s.categories {
+		copied := *category
+		categories = append(categories, &copied)
+		byID[copied.ID] = &copied
+	}
+	s.mu.RUnlock()
+
+	for _, post := range posts {
+		for c := byID[post.CategoryID]; c != nil; c = byID[c.ParentID] {
+			c.PostCount++
+		}
+	}
+
+	return models.SortCategoryTree(categories), nil
+}
+
+// FindCategory 根据slug查找分类
+func (s *MemoryPostStore) FindCategory(ctx context.Context, slug string) (*models.Category, error) {
+	categories, err := s.Categories(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, category := range categories {
+		if category.Slug == slug {
+			return category, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// EnsureCategory 按路径查找分类，不存在的各级分类会被创建，path为空时返回nil
+func (s *MemoryPostStore) EnsureCategory(ctx context.Context, path string) (*models.Category, error) {
+	names := models.SplitCategoryPath(path)
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	category, err := s.ensurePath(names)
+	if err != nil {
+		return nil, err
+	}
+	copied := *category
+	return &copied, nil
+}
+
+// ensurePath 依次查找names中的各级分类，不存在的分类会被创建，返回最后一级分类，调用方需持有锁。
+// 先检查所有分类名，有无效的分类名时不会创建其上级分类
+func (s *MemoryPostStore) ensurePath(names []string) (*models.Category, error) {
+	for _, name := range names {
+		if _, err := categoryChild(nil, name); err != nil {
+			return nil, err
+		}
+	}
+
+	var parent *models.Category
+	for _, name := range names {
+		next, _, err := s.ensureChild(parent, name)
+		if err != nil {
+			return nil, err
+		}
+		parent = next
+	}
+	return parent, nil
+}
+
+// resolveCategory 把 post.Category 中按路径指定的新分类转换为已保存的分类并设置 post.CategoryID，
+// 分类为nil或已有ID时不做任何处理。调用方需持有锁，并在此之后不再返回错误
+func (s *MemoryPostStore) resolveCategory(post *models.Post) error {
+	if post.Category == nil || post.Category.ID != 0 {
+		return nil
+	}
+	category, err := s.ensurePath(models.SplitCategoryPath(post.Category.Path))
+	if err != nil {
+		return err
+	}
+	post.Category = nil
+	post.CategoryID = 0
+	if category != nil {
+		copied := *category
+		post.Category = &copied
+		post.CategoryID = category.ID
+	}
+	return nil
+}
+
+// ensureChild 查找parent下名为name的分类，不存在时创建，created表示是否新建了分类，调用方需持有锁
+func (s *MemoryPostStore) ensureChild(parent *models.Category, name string) (category *models.Category, created bool, err error) {
+	next, err := categoryChild(parent, name)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if existing := s.categoryBySlug(next.Slug); existing != nil {
+		return existing, false, nil
+	}
+
+	next.ID = s.nextCategoryID
+	s.nextCategoryID++
+	s.categories[next.ID] = next
+	return next, true, nil
+}
+
+// categoryBySlug 根据slug查找分类，调用方需持有锁
+func (s *MemoryPostStore) categoryBySlug(slug string) *models.Category {
+	for _, category := range s.categories {
+		if category.Slug == slug {
+			return category
+		}
+	}
+	return nil
+}
@@ -0,0 +1,388 @@
+// Package migrate 实现带版本号的数据库结构迁移
+//
+// 迁移脚本以 "<版本号>_<名称>.up.sql" 和 "<版本号>_<名称>.down.sql" 命名，
+// 按数据库方言放在对应子目录中，并在编译时嵌入程序。已执行的迁移记录在
+// schema_migrations 表中，同时保存脚本校验和，用于发现被修改过的已执行迁移。
+//
+// 每个方言目录必须包含相同的版本号序列；某个版本在部分数据库上无需变更时，
+// 对应脚本可以只包含注释。注意MySQL的DDL语句会隐式提交事务，无法随事务回滚。
+package migrate
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed sqlite3/*.sql postgres/*.sql mysql/*.sql
+var files embed.FS
+
+// migrationsTableDDL 各方言下迁移记录表的建表语句
+var migrationsTableDDL = map[string]string{
+	"sqlite3": `
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		checksum TEXT NOT NULL,
+		applied_at DATETIME NOT NULL
+	)`,
+	"postgres": `
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		checksum TEXT NOT NULL,
+		applied_at TIMESTAMPTZ NOT NULL
+	)`,
+	"mysql": `
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INT PRIMARY KEY,
+		name VARCHAR(255) NOT NULL,
+		checksum CHAR(64) NOT NULL,
+		applied_at DATETIME NOT NULL
+	) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4`,
+}
+
+// fileNamePattern 迁移文件名格式
+var fileNamePattern = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migration 一个版本的迁移
+type Migration struct {
+	Version  int
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+}
+
+// Status 迁移的执行状态
+type Status struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// Migrator 迁移执行器
+type Migrator struct {
+	db         *sql.DB
+	dialect    string
+	migrations []Migration
+}
+
+// New 创建指定方言的迁移执行器
+func New(db *sql.DB, dialect string) (*Migrator, error) {
+	if _, ok := migrationsTableDDL[dialect]; !ok {
+		return nil, fmt.Errorf("不支持的迁移方言: %s", dialect)
+	}
+
+	migrations, err := load(dialect)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{
+		db:         db,
+		dialect:    dialect,
+		migrations: migrations,
+	}, nil
+}
+
+// load 读取嵌入的迁移脚本并按版本号排序
+func load(dialect string) ([]Migration, error) {
+	dir, err := fs.Sub(files, dialect)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := fs.ReadDir(dir, ".")
+	if err != nil {
+		return nil, fmt.Errorf("不支持的迁移方言 %s: %w", dialect, err)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		match := fileNamePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("迁移文件名格式错误: %s", entry.Name())
+		}
+
+		version, _ := strconv.Atoi(match[1])
+		content, err := fs.ReadFile(dir, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("迁移版本 %d 存在重复的名称: %s, %s", version, m.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			m.Up = string(content)
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("迁移版本 %d 缺少up脚本", m.Version)
+		}
+		sum := sha256.Sum256([]byte(m.Up))
+		m.Checksum = hex.EncodeToString(sum[:])
+		migrations = append(migrations, *m)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// appliedMigration schema_migrations 表中的一条记录
+type appliedMigration struct {
+	checksum  string
+	appliedAt time.Time
+}
+
+// ensureTable 创建迁移记录表
+func (m *Migrator) ensureTable() error {
+	_, err := m.db.Exec(migrationsTableDDL[m.dialect])
+	return err
+}
+
+// applied 读取已执行的迁移
+func (m *Migrator) applied() (map[int]appliedMigration, error) {
+	if err := m.ensureTable(); err != nil {
+		return nil, err
+	}
+
+	rows, err := m.db.Query(`SELECT version, checksum, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int]appliedMigration)
+	for rows.Next() {
+		var version int
+		var a appliedMigration
+		if err := rows.Scan(&version, &a.checksum, &a.appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = a
+	}
+
+	return applied, rows.Err()
+}
+
+// verify 检查已执行迁移的校验和，以及数据库中是否存在程序不认识的版本
+func (m *Migrator) verify(applied map[int]appliedMigration) error {
+	known := make(map[int]bool, len(m.migrations))
+	for _, migration := range m.migrations {
+		known[migration.Version] = true
+
+		a, ok := applied[migration.Version]
+		if ok && a.checksum != migration.Checksum {
+			return fmt.Errorf("已执行的迁移 %s 被修改过，校验和不一致", migration.Describe())
+		}
+	}
+
+	for version := range applied {
+		if !known[version] {
+			return fmt.Errorf("数据库中存在未知的迁移版本 %d，程序版本可能过旧", version)
+		}
+	}
+
+	return nil
+}
+
+// Up 按顺序执行所有未执行的迁移，返回本次执行的迁移
+func (m *Migrator) Up() ([]Migration, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+	if err := m.verify(applied); err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+
+		log.Printf("执行迁移 %s", migration.Describe())
+		err := m.inTx(func(tx *sql.Tx) error {
+			if err := execScript(tx, migration.Up); err != nil {
+				return err
+			}
+			_, err := tx.Exec(
+				m.rebind(`INSERT INTO schema_migrations (version, name, checksum, applied_at) VALUES (?, ?, ?, ?)`),
+				migration.Version, migration.Name, migration.Checksum, time.Now().UTC().Truncate(time.Second),
+			)
+			return err
+		})
+		if err != nil {
+			return done, fmt.Errorf("迁移 %s 执行失败: %w", migration.Describe(), err)
+		}
+		done = append(done, migration)
+	}
+
+	return done, nil
+}
+
+// Down 回滚最近执行的steps个迁移，返回本次回滚的迁移
+func (m *Migrator) Down(steps int) ([]Migration, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+	if err := m.verify(applied); err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for i := len(m.migrations) - 1; i >= 0 && len(done) < steps; i-- {
+		migration := m.migrations[i]
+		if _, ok := applied[migration.Version]; !ok {
+			continue
+		}
+		if migration.Down == "" {
+			return done, fmt.Errorf("迁移 %s 没有down脚本，无法回滚", migration.Describe())
+		}
+
+		log.Printf("回滚迁移 %s", migration.Describe())
+		err := m.inTx(func(tx *sql.Tx) error {
+			if err := execScript(tx, migration.Down); err != nil {
+				return err
+			}
+			_, err := tx.Exec(m.rebind(`DELETE FROM schema_migrations WHERE version = ?`), migration.Version)
+			return err
+		})
+		if err != nil {
+			return done, fmt.Errorf("迁移 %s 回滚失败: %w", migration.Describe(), err)
+		}
+		done = append(done, migration)
+	}
+
+	return done, nil
+}
+
+// Status 返回所有迁移的执行状态
+func (m *Migrator) Status() ([]Status, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+	if err := m.verify(applied); err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		a, ok := applied[migration.Version]
+		statuses = append(statuses, Status{
+			Migration: migration,
+			Applied:   ok,
+			AppliedAt: a.appliedAt,
+		})
+	}
+
+	return statuses, nil
+}
+
+// Version 返回当前数据库结构版本，即已执行的最大迁移版本号，未执行任何迁移时为0
+func (m *Migrator) Version() (int, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return 0, err
+	}
+
+	version := 0
+	for v := range applied {
+		if v > version {
+			version = v
+		}
+	}
+	return version, nil
+}
+
+// Latest 返回程序内置的最新迁移版本号
+func (m *Migrator) Latest() int {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// inTx 在事务中执行一次迁移
+func (m *Migrator) inTx(fn func(tx *sql.Tx) error) error {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// execScript 执行迁移脚本，只包含注释的脚本直接跳过
+func execScript(tx *sql.Tx, script string) error {
+	empty := true
+	for _, line := range strings.Split(script, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "--") {
+			empty = false
+			break
+		}
+	}
+	if empty {
+		return nil
+	}
+
+	_, err := tx.Exec(script)
+	return err
+}
+
+// rebind 把 ? 占位符转换为PostgreSQL的 $n 形式
+func (m *Migrator) rebind(query string) string {
+	if m.dialect != "postgres" {
+		return query
+	}
+
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// Describe 返回迁移的可读名称，如 "0001_create_users"
+func (m Migration) Describe() string {
+	return fmt.Sprintf("%04d_%s", m.Version, m.Name)
+}
@@ -0,0 +1 @@
+-- 无需回滚
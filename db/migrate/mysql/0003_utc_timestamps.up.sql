@@ -0,0 +1 @@
+-- 原生时间类型无需转换，此版本仅在SQLite上修改数据
@@ -0,0 +1 @@
+DROP INDEX idx_posts_created_at ON posts;
@@ -0,0 +1,2 @@
+-- 支持按 (created_at, id) 的键集分页
+CREATE INDEX idx_posts_created_at ON posts (created_at, id);
@@ -0,0 +1,16 @@
+-- 文章每次创建和更新都保存一个版本，user_id 为做出修改的用户
+CREATE TABLE IF NOT EXISTS post_revisions (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	post_id INT NOT NULL,
+	user_id INT NOT NULL,
+	title VARCHAR(255) NOT NULL,
+	content LONGTEXT NOT NULL,
+	created_at DATETIME NOT NULL,
+	INDEX idx_post_revisions_post_id (post_id, id),
+	FOREIGN KEY (post_id) REFERENCES posts (id),
+	FOREIGN KEY (user_id) REFERENCES users (id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- 已有文章以当前内容作为第一个版本
+INSERT INTO post_revisions (post_id, user_id, title, content, created_at)
+SELECT id, user_id, title, content, updated_at FROM posts;
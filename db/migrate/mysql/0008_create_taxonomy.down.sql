@@ -0,0 +1,5 @@
+ALTER TABLE posts DROP FOREIGN KEY fk_posts_category;
+ALTER TABLE posts DROP COLUMN category_id;
+DROP TABLE IF EXISTS categories;
+DROP TABLE IF EXISTS post_tags;
+DROP TABLE IF EXISTS tags;
@@ -0,0 +1,29 @@
+-- 标签与文章多对多关联，标签名统一保存为小写
+CREATE TABLE IF NOT EXISTS tags (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	name VARCHAR(100) NOT NULL UNIQUE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS post_tags (
+	post_id INT NOT NULL,
+	tag_id INT NOT NULL,
+	PRIMARY KEY (post_id, tag_id),
+	INDEX idx_post_tags_tag_id (tag_id),
+	FOREIGN KEY (post_id) REFERENCES posts (id),
+	FOREIGN KEY (tag_id) REFERENCES tags (id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- 分类为树形结构，path 为从根开始以 / 连接的名称，slug 为对应的URL路径
+CREATE TABLE IF NOT EXISTS categories (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	parent_id INT NULL,
+	name VARCHAR(100) NOT NULL,
+	path VARCHAR(255) NOT NULL UNIQUE,
+	slug VARCHAR(255) NOT NULL UNIQUE,
+	FOREIGN KEY (parent_id) REFERENCES categories (id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- 每篇文章最多属于一个分类，外键会自动创建索引
+ALTER TABLE posts
+	ADD COLUMN category_id INT NULL,
+	ADD CONSTRAINT fk_posts_category FOREIGN KEY (category_id) REFERENCES categories (id);
@@ -0,0 +1,17 @@
+-- 评论可以回复其他评论，parent_id 为空表示直接评论文章。
+-- 删除评论时只设置 deleted_at，保留被回复的评论在讨论树中的位置。
+-- InnoDB 逐行检查外键，parent_id 需要级联删除，才能在一条语句中删除整篇文章的评论
+CREATE TABLE IF NOT EXISTS comments (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	post_id INT NOT NULL,
+	user_id INT NOT NULL,
+	parent_id INT NULL,
+	content TEXT NOT NULL,
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL,
+	deleted_at DATETIME NULL,
+	INDEX idx_comments_post_id (post_id, id),
+	FOREIGN KEY (post_id) REFERENCES posts (id),
+	FOREIGN KEY (user_id) REFERENCES users (id),
+	FOREIGN KEY (parent_id) REFERENCES comments (id) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
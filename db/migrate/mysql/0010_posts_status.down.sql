@@ -0,0 +1,4 @@
+-- 回滚后草稿和定时文章会直接公开显示
+DROP INDEX idx_posts_status_publish_at ON posts;
+ALTER TABLE posts DROP COLUMN publish_at;
+ALTER TABLE posts DROP COLUMN status;
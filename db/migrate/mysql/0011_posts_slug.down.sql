@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS post_slug_redirects;
+DROP INDEX idx_posts_slug ON posts;
+ALTER TABLE posts DROP COLUMN slug;
@@ -0,0 +1,12 @@
+-- 文章别名用于固定链接，已有文章的别名为 post-{ID}，作者可以在编辑页面修改
+ALTER TABLE posts ADD COLUMN slug VARCHAR(255) NOT NULL DEFAULT '';
+UPDATE posts SET slug = CONCAT('post-', id);
+CREATE UNIQUE INDEX idx_posts_slug ON posts (slug);
+
+-- 文章以前使用过的别名，访问时重定向到文章当前的固定链接
+CREATE TABLE IF NOT EXISTS post_slug_redirects (
+	slug VARCHAR(255) PRIMARY KEY,
+	post_id INT NOT NULL,
+	created_at DATETIME NOT NULL,
+	FOREIGN KEY (post_id) REFERENCES posts (id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
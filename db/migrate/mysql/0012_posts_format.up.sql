@@ -0,0 +1,6 @@
+-- 正文格式：markdown 或 text（纯文本），已有文章保持纯文本，新文章默认使用Markdown
+ALTER TABLE posts ADD COLUMN format VARCHAR(20) NOT NULL DEFAULT 'text';
+ALTER TABLE post_revisions ADD COLUMN format VARCHAR(20) NOT NULL DEFAULT 'text';
+
+-- Markdown 版本渲染并清理后的HTML缓存，为空表示尚未渲染
+ALTER TABLE post_revisions ADD COLUMN html LONGTEXT NULL;
@@ -0,0 +1,15 @@
+-- 上传的文件按内容的SHA-256保存，同一用户重复上传相同内容时复用已有记录
+CREATE TABLE IF NOT EXISTS media (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	user_id INT NOT NULL,
+	file_name VARCHAR(255) NOT NULL,
+	hash CHAR(64) NOT NULL,
+	mime_type VARCHAR(100) NOT NULL,
+	size BIGINT NOT NULL,
+	width INT NOT NULL DEFAULT 0,
+	height INT NOT NULL DEFAULT 0,
+	created_at DATETIME NOT NULL,
+	UNIQUE INDEX idx_media_user_hash (user_id, hash),
+	INDEX idx_media_hash (hash),
+	FOREIGN KEY (user_id) REFERENCES users (id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
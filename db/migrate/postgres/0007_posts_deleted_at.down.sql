@@ -0,0 +1,2 @@
+-- 回滚后回收站中的文章重新显示
+ALTER TABLE posts DROP COLUMN deleted_at;
@@ -0,0 +1,2 @@
+-- 删除的文章先移入回收站，deleted_at 为移入时间，为空表示未删除
+ALTER TABLE posts ADD COLUMN deleted_at TIMESTAMPTZ NULL;
@@ -0,0 +1,27 @@
+-- 标签与文章多对多关联，标签名统一保存为小写
+CREATE TABLE IF NOT EXISTS tags (
+	id SERIAL PRIMARY KEY,
+	name TEXT NOT NULL UNIQUE
+);
+
+CREATE TABLE IF NOT EXISTS post_tags (
+	post_id INTEGER NOT NULL REFERENCES posts (id),
+	tag_id INTEGER NOT NULL REFERENCES tags (id),
+	PRIMARY KEY (post_id, tag_id)
+);
+
+CREATE INDEX idx_post_tags_tag_id ON post_tags (tag_id);
+
+-- 分类为树形结构，path 为从根开始以 / 连接的名称，slug 为对应的URL路径
+CREATE TABLE IF NOT EXISTS categories (
+	id SERIAL PRIMARY KEY,
+	parent_id INTEGER NULL REFERENCES categories (id),
+	name TEXT NOT NULL,
+	path TEXT NOT NULL UNIQUE,
+	slug TEXT NOT NULL UNIQUE
+);
+
+-- 每篇文章最多属于一个分类
+ALTER TABLE posts ADD COLUMN category_id INTEGER NULL REFERENCES categories (id);
+
+CREATE INDEX idx_posts_category_id ON posts (category_id);
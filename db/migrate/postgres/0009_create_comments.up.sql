@@ -0,0 +1,14 @@
+-- 评论可以回复其他评论，parent_id 为空表示直接评论文章。
+-- 删除评论时只设置 deleted_at，保留被回复的评论在讨论树中的位置
+CREATE TABLE IF NOT EXISTS comments (
+	id SERIAL PRIMARY KEY,
+	post_id INTEGER NOT NULL REFERENCES posts (id),
+	user_id INTEGER NOT NULL REFERENCES users (id),
+	parent_id INTEGER NULL REFERENCES comments (id) ON DELETE CASCADE,
+	content TEXT NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL,
+	updated_at TIMESTAMPTZ NOT NULL,
+	deleted_at TIMESTAMPTZ NULL
+);
+
+CREATE INDEX idx_comments_post_id ON comments (post_id, id);
@@ -0,0 +1,11 @@
+-- 文章别名用于固定链接，已有文章的别名为 post-{ID}，作者可以在编辑页面修改
+ALTER TABLE posts ADD COLUMN slug TEXT NOT NULL DEFAULT '';
+UPDATE posts SET slug = 'post-' || id;
+CREATE UNIQUE INDEX idx_posts_slug ON posts (slug);
+
+-- 文章以前使用过的别名，访问时重定向到文章当前的固定链接
+CREATE TABLE IF NOT EXISTS post_slug_redirects (
+	slug TEXT PRIMARY KEY,
+	post_id INTEGER NOT NULL REFERENCES posts (id),
+	created_at TIMESTAMPTZ NOT NULL
+);
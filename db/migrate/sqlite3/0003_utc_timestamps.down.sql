@@ -0,0 +1 @@
+-- 时间格式转换不可逆，回滚时保留UTC格式
@@ -0,0 +1,9 @@
+-- 早期版本以本地时区的RFC3339字符串保存时间，统一转换为UTC，
+-- 与驱动写入 time.Time 的格式一致，保证按时间排序和比较的正确性
+UPDATE users SET
+	created_at = strftime('%Y-%m-%d %H:%M:%S+00:00', created_at),
+	updated_at = strftime('%Y-%m-%d %H:%M:%S+00:00', updated_at);
+
+UPDATE posts SET
+	created_at = strftime('%Y-%m-%d %H:%M:%S+00:00', created_at),
+	updated_at = strftime('%Y-%m-%d %H:%M:%S+00:00', updated_at);
@@ -0,0 +1,2 @@
+-- 管理员可以查看和恢复所有文章的历史版本
+ALTER TABLE users ADD COLUMN is_admin BOOLEAN NOT NULL DEFAULT 0;
@@ -0,0 +1,17 @@
+-- 文章每次创建和更新都保存一个版本，user_id 为做出修改的用户
+CREATE TABLE IF NOT EXISTS post_revisions (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	post_id INTEGER NOT NULL,
+	user_id INTEGER NOT NULL,
+	title TEXT NOT NULL,
+	content TEXT NOT NULL,
+	created_at DATETIME NOT NULL,
+	FOREIGN KEY (post_id) REFERENCES posts (id),
+	FOREIGN KEY (user_id) REFERENCES users (id)
+);
+
+CREATE INDEX idx_post_revisions_post_id ON post_revisions (post_id, id);
+
+-- 已有文章以当前内容作为第一个版本
+INSERT INTO post_revisions (post_id, user_id, title, content, created_at)
+SELECT id, user_id, title, content, updated_at FROM posts;
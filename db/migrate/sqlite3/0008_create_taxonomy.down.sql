@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_posts_category_id;
+ALTER TABLE posts DROP COLUMN category_id;
+DROP TABLE IF EXISTS categories;
+DROP TABLE IF EXISTS post_tags;
+DROP TABLE IF EXISTS tags;
@@ -0,0 +1,30 @@
+-- 标签与文章多对多关联，标签名统一保存为小写
+CREATE TABLE IF NOT EXISTS tags (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name TEXT NOT NULL UNIQUE
+);
+
+CREATE TABLE IF NOT EXISTS post_tags (
+	post_id INTEGER NOT NULL,
+	tag_id INTEGER NOT NULL,
+	PRIMARY KEY (post_id, tag_id),
+	FOREIGN KEY (post_id) REFERENCES posts (id),
+	FOREIGN KEY (tag_id) REFERENCES tags (id)
+);
+
+CREATE INDEX idx_post_tags_tag_id ON post_tags (tag_id);
+
+-- 分类为树形结构，path 为从根开始以 / 连接的名称，slug 为对应的URL路径
+CREATE TABLE IF NOT EXISTS categories (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	parent_id INTEGER NULL,
+	name TEXT NOT NULL,
+	path TEXT NOT NULL UNIQUE,
+	slug TEXT NOT NULL UNIQUE,
+	FOREIGN KEY (parent_id) REFERENCES categories (id)
+);
+
+-- 每篇文章最多属于一个分类。SQLite 无法删除带外键约束的列，这里不声明外键以便回滚
+ALTER TABLE posts ADD COLUMN category_id INTEGER NULL;
+
+CREATE INDEX idx_posts_category_id ON posts (category_id);
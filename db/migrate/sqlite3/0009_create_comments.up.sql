@@ -0,0 +1,17 @@
+-- 评论可以回复其他评论，parent_id 为空表示直接评论文章。
+-- 删除评论时只设置 deleted_at，保留被回复的评论在讨论树中的位置
+CREATE TABLE IF NOT EXISTS comments (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	post_id INTEGER NOT NULL,
+	user_id INTEGER NOT NULL,
+	parent_id INTEGER NULL,
+	content TEXT NOT NULL,
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL,
+	deleted_at DATETIME NULL,
+	FOREIGN KEY (post_id) REFERENCES posts (id),
+	FOREIGN KEY (user_id) REFERENCES users (id),
+	FOREIGN KEY (parent_id) REFERENCES comments (id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_comments_post_id ON comments (post_id, id);
@@ -0,0 +1,6 @@
+-- 文章状态：draft 草稿、scheduled 定时发布、published 已发布，只有已发布的文章公开可见。
+-- publish_at 为定时发布或实际发布的时间，已有文章视为在创建时发布
+ALTER TABLE posts ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'published';
+ALTER TABLE posts ADD COLUMN publish_at DATETIME NULL;
+UPDATE posts SET publish_at = created_at;
+CREATE INDEX idx_posts_status_publish_at ON posts (status, publish_at);
@@ -0,0 +1,4 @@
+-- 回滚后Markdown文章按纯文本显示
+ALTER TABLE post_revisions DROP COLUMN html;
+ALTER TABLE post_revisions DROP COLUMN format;
+ALTER TABLE posts DROP COLUMN format;
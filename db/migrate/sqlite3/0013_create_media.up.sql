@@ -0,0 +1,16 @@
+-- 上传的文件按内容的SHA-256保存，同一用户重复上传相同内容时复用已有记录
+CREATE TABLE IF NOT EXISTS media (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id INTEGER NOT NULL,
+	file_name TEXT NOT NULL,
+	hash CHAR(64) NOT NULL,
+	mime_type VARCHAR(100) NOT NULL,
+	size INTEGER NOT NULL,
+	width INTEGER NOT NULL DEFAULT 0,
+	height INTEGER NOT NULL DEFAULT 0,
+	created_at DATETIME NOT NULL,
+	FOREIGN KEY (user_id) REFERENCES users (id)
+);
+
+CREATE UNIQUE INDEX idx_media_user_hash ON media (user_id, hash);
+CREATE INDEX idx_media_hash ON media (hash);
@@ -0,0 +1,2 @@
+-- 文章版本号，每次编辑加一，用于检测并发编辑的冲突
+ALTER TABLE posts ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
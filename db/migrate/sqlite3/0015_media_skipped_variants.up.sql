@@ -0,0 +1,2 @@
+-- 因为比原图大而没有生成的图片版本名称，以空格分隔，页面中改用原图
+ALTER TABLE media ADD COLUMN skipped_variants TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+-- 私有文件只有上传者和管理员可以访问
+ALTER TABLE media ADD COLUMN private BOOLEAN NOT NULL DEFAULT 0;
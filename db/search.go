@@ -0,0 +1,217 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"goblog/models"
+	"log"
+	"strings"
+	"unicode/utf8"
+)
+
+// 全文索引使用FTS5的trigram分词器，可以匹配中文等不以空格分词的文本，
+// 但要求每个关键词至少3个字符，更短的关键词退回LIKE搜索。
+// 索引表由存储层在写入文章时同步维护，而不是由迁移和触发器维护，
+// 这样未启用FTS5编译的程序（go build 时未加 -tags sqlite_fts5）仍能正常读写同一个数据库。
+const trigramMinLength = 3
+
+// setupFullText 在支持FTS5的SQLite上创建全文索引表并在必要时重建索引，
+// 返回是否启用全文索引
+func setupFullText(db *sql.DB) bool {
+	ctx := context.Background()
+	_, err := db.ExecContext(ctx, `
+		CREATE VIRTUAL TABLE IF NOT EXISTS posts_fts
+		USING fts5(title, content, tokenize = 'trigram')`)
+	if err != nil {
+		log.Printf("当前SQLite不支持FTS5，搜索将使用LIKE匹配: %v", err)
+		return false
+	}
+
+	// 索引由存储层维护，数据库可能被未启用FTS5的程序修改过，修改和删除不会改变文章数量，
+	// 无法可靠地判断索引是否过期，因此每次启动都在一个事务中重建
+	var indexed int64
+	err = withTx(ctx, db, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM posts_fts`); err != nil {
+			return err
+		}
+		result, err := tx.ExecContext(ctx, `INSERT INTO posts_fts (rowid, title, content) SELECT id, title, content FROM posts WHERE deleted_at IS NULL`)
+		if err != nil {
+			return err
+		}
+		indexed, err = result.RowsAffected()
+		return err
+	})
+	if err != nil {
+		log.Printf("重建全文索引失败: %v", err)
+		return false
+	}
+
+	log.Printf("已重建全文索引: %d 篇文章", indexed)
+	log.Println("全文索引已启用")
+	return true
+}
+
+// indexPost 写入或更新文章的全文索引
+func (s *SQLPostStore) indexPost(ctx context.Context, q queryer, post *models.Post) error {
+	if !s.fullText {
+		return nil
+	}
+	if _, err := q.ExecContext(ctx, `DELETE FROM posts_fts WHERE rowid = ?`, post.ID); err != nil {
+		return err
+	}
+	_, err := q.ExecContext(ctx, `INSERT INTO posts_fts (rowid, title, content) VALUES (?, ?, ?)`, post.ID, post.Title, post.Content)
+	return err
+}
+
+// unindexPost 删除文章的全文索引
+func (s *SQLPostStore) unindexPost(ctx context.Context, q queryer, id int) error {
+	if !s.fullText {
+		return nil
+	}
+	_, err := q.ExecContext(ctx, `DELETE FROM posts_fts WHERE rowid = ?`, id)
+	return err
+}
+
+// Search 全文搜索文章。启用FTS5时按bm25相关度排序，否则使用LIKE匹配，标题命中的排在前面
+func (s *SQLPostStore) Search(ctx context.Context, opts models.SearchOptions) (*models.SearchResult, error) {
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+
+	opts.Normalize()
+
+	terms := models.SearchTerms(opts.Query)
+	if len(terms) == 0 {
+		return &models.SearchResult{Hits: []*models.SearchHit{}}, nil
+	}
+
+	if s.fullText && canUseTrigram(terms) {
+		return s.searchFullText(ctx, terms, opts)
+	}
+	return s.searchLike(ctx, terms, opts)
+}
+
+// canUseTrigram 判断所有关键词是否都满足trigram分词的最小长度
+func canUseTrigram(terms []string) bool {
+	for _, term := range terms {
+		if utf8.RuneCountInString(term) < trigramMinLength {
+			return false
+		}
+	}
+	return true
+}
+
+// ftsQuery 把关键词转换为FTS5查询，每个词作为短语匹配，词之间为AND关系
+func ftsQuery(terms []string) string {
+	quoted := make([]string, len(terms))
+	for i, term := range terms {
+		quoted[i] = `"` + strings.ReplaceAll(term, `"`, `""`) + `"`
+	}
+	return strings.Join(quoted, " ")
+}
+
+// searchFullText 使用FTS5索引搜索
+func (s *SQLPostStore) searchFullText(ctx context.Context, terms []string, opts models.SearchOptions) (*models.SearchResult, error) {
+	match := ftsQuery(terms)
+
+	var total int
+	err := s.db.QueryRowContext(ctx, `
+		SELECT COUNT(*)
+		FROM posts_fts
+		JOIN posts p ON p.id = posts_fts.rowid
+		WHERE posts_fts MATCH ? AND `+publishedCondition, match).Scan(&total)
+	if err != nil {
+		log.Printf("统计搜索结果失败: %v", err)
+		return nil, err
+	}
+
+	// 标题命中的权重是正文的10倍。FTS5的snippet()按trigram切分，会截断关键词，
+	// 因此高亮和摘要片段与LIKE搜索一样在读取后生成
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+postColumns+`
+		FROM posts_fts
+		JOIN posts p ON p.id = posts_fts.rowid
+		JOIN users u ON p.user_id = u.id
+		WHERE posts_fts MATCH ? AND `+publishedCondition+`
+		ORDER BY bm25(posts_fts, 10.0, 1.0), p.id DESC
+		LIMIT ? OFFSET ?`,
+		match, opts.Limit, opts.Offset)
+	if err != nil {
+		log.Printf("全文搜索失败: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	return s.scanHits(ctx, rows, terms, total)
+}
+
+// scanHits 读取搜索结果并生成带高亮标记的标题和正文片段
+func (s *SQLPostStore) scanHits(ctx context.Context, rows *sql.Rows, terms []string, total int) (*models.SearchResult, error) {
+	result := &models.SearchResult{Hits: []*models.SearchHit{}, Total: total}
+	var posts []*models.Post
+	for rows.Next() {
+		post, err := scanPost(rows)
+		if err != nil {
+			return nil, err
+		}
+		result.Hits = append(result.Hits, &models.SearchHit{
+			Post:    post,
+			Title:   models.Highlight(post.Title, terms),
+			Snippet: models.Snippet(post.Content, terms),
+		})
+		post.Excerpt = models.Excerpt(post.Content)
+		post.Content = ""
+		posts = append(posts, post)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := s.attachTaxonomy(ctx, posts); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// likeEscaper 以 ! 转义LIKE中的通配符（反斜杠在MySQL字符串中有特殊含义），配合 ESCAPE '!' 使用
+var likeEscaper = strings.NewReplacer(`!`, `!!`, `%`, `!%`, `_`, `!_`)
+
+// likePattern 生成不区分大小写的LIKE匹配模式
+func likePattern(term string) string {
+	return "%" + likeEscaper.Replace(strings.ToLower(term)) + "%"
+}
+
+// searchLike 使用LIKE逐词匹配标题或正文，适用于所有数据库
+func (s *SQLPostStore) searchLike(ctx context.Context, terms []string, opts models.SearchOptions) (*models.SearchResult, error) {
+	conditions := []string{publishedCondition}
+	var args []interface{}
+	for _, term := range terms {
+		pattern := likePattern(term)
+		conditions = append(conditions, `(LOWER(p.title) LIKE ? ESCAPE '!' OR LOWER(p.content) LIKE ? ESCAPE '!')`)
+		args = append(args, pattern, pattern)
+	}
+	where := strings.Join(conditions, " AND ")
+
+	var total int
+	err := s.db.QueryRowContext(ctx, s.dialect.rebind(`SELECT COUNT(*) FROM posts p WHERE `+where), args...).Scan(&total)
+	if err != nil {
+		log.Printf("统计搜索结果失败: %v", err)
+		return nil, err
+	}
+
+	// 标题包含第一个关键词的排在前面，其余按发布时间倒序
+	orderArgs := append(args, likePattern(terms[0]), opts.Limit, opts.Offset)
+	rows, err := s.db.QueryContext(ctx, s.dialect.rebind(`
+		SELECT `+postColumns+`
+		FROM posts p
+		JOIN users u ON p.user_id = u.id
+		WHERE `+where+`
+		ORDER BY CASE WHEN LOWER(p.title) LIKE ? ESCAPE '!' THEN 0 ELSE 1 END, p.created_at DESC, p.id DESC
+		LIMIT ? OFFSET ?`), orderArgs...)
+	if err != nil {
+		log.Printf("搜索文章失败: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	return s.scanHits(ctx, rows, terms, total)
+}
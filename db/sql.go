@@ -0,0 +1,189 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"goblog/config"
+	"goblog/db/migrate"
+	"log"
+	"time"
+)
+
+// SQLStore 基于database/sql的数据库连接，支持SQLite、PostgreSQL和MySQL，
+// 文章和用户存储共享同一个连接池
+type SQLStore struct {
+	db      *sql.DB
+	dialect *dialect
+	timeout time.Duration // 快照的超时，0表示不限制
+
+	posts    *SQLPostStore
+	users    *SQLUserStore
+	comments *SQLCommentStore
+	media    *SQLMediaStore
+	site     *SQLSiteStore
+}
+
+// openSQL 按方言打开数据库连接并检查连通性，不执行迁移
+func openSQL(d *dialect, cfg config.DatabaseConfig) (*sql.DB, error) {
+	log.Printf("尝试打开%s数据库: %s", d.name, describeTarget(d, cfg))
+
+	db, err := sql.Open(d.driver, d.dsn(cfg))
+	if err != nil {
+		log.Printf("打开数据库失败: %v", err)
+		return nil, err
+	}
+
+	log.Println("尝试Ping数据库连接...")
+	if err := db.Ping(); err != nil {
+		log.Printf("Ping数据库失败: %v", err)
+		db.Close()
+		return nil, err
+	}
+	log.Println("数据库连接成功")
+
+	return db, nil
+}
+
+// describeTarget 返回用于日志的连接目标，不包含密码
+func describeTarget(d *dialect, cfg config.DatabaseConfig) string {
+	if d == sqliteDialect {
+		return cfg.Path
+	}
+	return cfg.User + "@" + cfg.Host + "/" + cfg.DBName
+}
+
+// NewSQLStore 按配置创建数据库存储，并执行尚未执行的数据库迁移
+func NewSQLStore(cfg config.DatabaseConfig) (*SQLStore, error) {
+	d, err := dialectFor(cfg.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := openSQL(d, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := time.Duration(cfg.QueryTimeoutSeconds) * time.Second
+	bulkTimeout := time.Duration(cfg.BulkTimeoutSeconds) * time.Second
+	store := &SQLStore{
+		db:       db,
+		dialect:  d,
+		timeout:  bulkTimeout,
+		posts:    &SQLPostStore{db: db, dialect: d, timeout: timeout},
+		users:    &SQLUserStore{db: db, dialect: d, timeout: timeout},
+		comments: &SQLCommentStore{db: db, dialect: d, timeout: timeout},
+		media:    &SQLMediaStore{db: db, dialect: d, timeout: timeout},
+	}
+	store.site = &SQLSiteStore{db: db, dialect: d, timeout: bulkTimeout, posts: store.posts}
+
+	if err := store.Migrate(); err != nil {
+		log.Printf("迁移数据库结构失败: %v", err)
+		db.Close()
+		return nil, err
+	}
+
+	if d == sqliteDialect {
+		store.posts.fullText = setupFullText(db)
+	}
+
+	return store, nil
+}
+
+// Migrate 执行所有尚未执行的数据库迁移
+func (s *SQLStore) Migrate() error {
+	migrator, err := migrate.New(s.db, s.dialect.name)
+	if err != nil {
+		return err
+	}
+
+	applied, err := migrator.Up()
+	if err != nil {
+		return err
+	}
+	if len(applied) > 0 {
+		log.Printf("数据库结构已更新，执行了 %d 个迁移", len(applied))
+	}
+
+	return nil
+}
+
+// withTx 在事务中执行fn，fn返回错误时回滚，返回的错误经过 translateError 转换。
+// ctx 取消时事务随之回滚
+func withTx(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return translateError(err)
+	}
+
+	return translateError(tx.Commit())
+}
+
+// withTimeout 为一次存储操作设置超时，操作中的所有查询和事务共用这个期限，timeout 为0时只随ctx取消
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// expectAffected 检查语句是否影响了记录，没有时返回 ErrNotFound
+func expectAffected(result sql.Result) error {
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Close 关闭数据库连接
+func (s *SQLStore) Close() error {
+	return s.db.Close()
+}
+
+// Posts 返回基于该连接的文章存储
+func (s *SQLStore) Posts() *SQLPostStore {
+	return s.posts
+}
+
+// Users 返回基于该连接的用户存储
+func (s *SQLStore) Users() *SQLUserStore {
+	return s.users
+}
+
+// Comments 返回基于该连接的评论存储
+func (s *SQLStore) Comments() *SQLCommentStore {
+	return s.comments
+}
+
+// Media 返回基于该连接的上传文件存储
+func (s *SQLStore) Media() *SQLMediaStore {
+	return s.media
+}
+
+// Site 返回基于该连接的整站数据存储
+func (s *SQLStore) Site() *SQLSiteStore {
+	return s.site
+}
+
+// Snapshot 把数据库的一致性快照写入新文件path，只支持SQLite。
+// 使用 VACUUM INTO 在一个读事务中复制，服务运行时也不会得到写了一半的文件。
+// ctx 取消或超时后复制中止，path 可能留下不完整的文件，由调用方删除
+func (s *SQLStore) Snapshot(ctx context.Context, path string) error {
+	if s.dialect != sqliteDialect {
+		return ErrSnapshotUnsupported
+	}
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, `VACUUM INTO ?`, path)
+	return err
+}
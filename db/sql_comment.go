@@ -0,0 +1,197 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"goblog/models"
+	"log"
+	"time"
+)
+
+// SQLCommentStore 基于SQL数据库的评论存储
+type SQLCommentStore struct {
+	db      *sql.DB
+	dialect *dialect
+	timeout time.Duration // 每次操作的超时，0表示不限制
+}
+
+// 编译期检查是否实现了评论存储接口
+var _ models.CommentStore = (*SQLCommentStore)(nil)
+
+// commentColumns 查询评论及作者时选择的列，顺序与 scanComment 一致
+const commentColumns = `
+	c.id, c.post_id, c.user_id, c.parent_id, c.content, c.created_at, c.updated_at, c.deleted_at,
+	u.id, u.username, u.email, u.created_at, u.updated_at`
+
+// scanComment 扫描一行评论及作者数据
+func scanComment(row scanner) (*models.Comment, error) {
+	var comment models.Comment
+	var user models.User
+	var parentID sql.NullInt64
+	var deletedAt sql.NullTime
+
+	err := row.Scan(
+		&comment.ID, &comment.PostID, &comment.UserID, &parentID, &comment.Content,
+		&comment.CreatedAt, &comment.UpdatedAt, &deletedAt,
+		&user.ID, &user.Username, &user.Email, &user.CreatedAt, &user.UpdatedAt,
+	)
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	comment.ParentID = int(parentID.Int64)
+	comment.CreatedAt = comment.CreatedAt.Local()
+	comment.UpdatedAt = comment.UpdatedAt.Local()
+	user.CreatedAt = user.CreatedAt.Local()
+	user.UpdatedAt = user.UpdatedAt.Local()
+	comment.User = &user
+	if deletedAt.Valid {
+		deletedAt := deletedAt.Time.Local()
+		comment.DeletedAt = &deletedAt
+		comment.Content = ""
+	}
+
+	return &comment, nil
+}
+
+// ListByPost 查找文章的所有评论，包括已删除的，按发表时间正序
+func (s *SQLCommentStore) ListByPost(ctx context.Context, postID int) ([]*models.Comment, error) {
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, s.dialect.rebind(`
+		SELECT `+commentColumns+`
+		FROM comments c
+		JOIN users u ON c.user_id = u.id
+		WHERE c.post_id = ?
+		ORDER BY c.id
+	`), postID)
+	if err != nil {
+		log.Printf("查询评论失败: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	comments := []*models.Comment{}
+	for rows.Next() {
+		comment, err := scanComment(rows)
+		if err != nil {
+			return nil, err
+		}
+		comments = append(comments, comment)
+	}
+
+	return comments, rows.Err()
+}
+
+// CountByPosts 统计每篇文章未删除的评论数
+func (s *SQLCommentStore) CountByPosts(ctx context.Context, postIDs []int) (map[int]int, error) {
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+
+	counts := make(map[int]int)
+	if len(postIDs) == 0 {
+		return counts, nil
+	}
+
+	args := make([]interface{}, len(postIDs))
+	for i, id := range postIDs {
+		args[i] = id
+	}
+
+	rows, err := s.db.QueryContext(ctx, s.dialect.rebind(`
+		SELECT post_id, COUNT(*)
+		FROM comments
+		WHERE deleted_at IS NULL AND post_id IN (`+placeholders(len(args))+`)
+		GROUP BY post_id
+	`), args...)
+	if err != nil {
+		log.Printf("统计评论数失败: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var postID, count int
+		if err := rows.Scan(&postID, &count); err != nil {
+			return nil, err
+		}
+		counts[postID] = count
+	}
+
+	return counts, rows.Err()
+}
+
+// FindByID 根据ID查找未删除的评论
+func (s *SQLCommentStore) FindByID(ctx context.Context, id int) (*models.Comment, error) {
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+
+	row := s.db.QueryRowContext(ctx, s.dialect.rebind(`
+		SELECT `+commentColumns+`
+		FROM comments c
+		JOIN users u ON c.user_id = u.id
+		WHERE c.id = ? AND c.deleted_at IS NULL
+	`), id)
+
+	return scanComment(row)
+}
+
+// Create 创建评论
+func (s *SQLCommentStore) Create(ctx context.Context, comment *models.Comment) error {
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+
+	now := now()
+	id, err := s.dialect.insert(ctx, s.db, `
+		INSERT INTO comments (post_id, user_id, parent_id, content, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		comment.PostID, comment.UserID, nullableID(comment.ParentID), comment.Content, now, now)
+	if err != nil {
+		log.Printf("创建评论失败: %v", err)
+		return err
+	}
+
+	comment.ID = int(id)
+	comment.CreatedAt = now.Local()
+	comment.UpdatedAt = comment.CreatedAt
+
+	return nil
+}
+
+// Update 更新评论内容
+func (s *SQLCommentStore) Update(ctx context.Context, comment *models.Comment) error {
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+
+	now := now()
+	result, err := s.db.ExecContext(ctx, s.dialect.rebind(`
+		UPDATE comments
+		SET content = ?, updated_at = ?
+		WHERE id = ? AND deleted_at IS NULL
+	`), comment.Content, now, comment.ID)
+	if err != nil {
+		return err
+	}
+	if err := expectAffected(result); err != nil {
+		return err
+	}
+
+	comment.UpdatedAt = now.Local()
+	return nil
+}
+
+// Delete 删除评论，只标记删除时间，回复仍然保留
+func (s *SQLCommentStore) Delete(ctx context.Context, id int) error {
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, s.dialect.rebind(`
+		UPDATE comments SET deleted_at = ?
+		WHERE id = ? AND deleted_at IS NULL
+	`), now(), id)
+	if err != nil {
+		return err
+	}
+	return expectAffected(result)
+}
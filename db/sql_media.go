@@ -0,0 +1,223 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"goblog/models"
+	"log"
+	"strings"
+	"time"
+)
+
+// SQLMediaStore 基于SQL数据库的上传文件存储
+type SQLMediaStore struct {
+	db      *sql.DB
+	dialect *dialect
+	timeout time.Duration // 每次操作的超时，0表示不限制
+}
+
+// 编译期检查是否实现了上传文件存储接口
+var _ models.MediaStore = (*SQLMediaStore)(nil)
+
+// mediaColumns 查询上传文件时选择的列，顺序与 scanMedia 一致
+const mediaColumns = `id, user_id, file_name, hash, mime_type, size, width, height, private, created_at, skipped_variants`
+
+// scanMedia 扫描一行上传文件数据
+func scanMedia(row scanner) (*models.Media, error) {
+	var media models.Media
+	var skipped string
+	err := row.Scan(
+		&media.ID, &media.UserID, &media.FileName, &media.Hash, &media.MIMEType,
+		&media.Size, &media.Width, &media.Height, &media.Private, &media.CreatedAt, &skipped,
+	)
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	media.CreatedAt = media.CreatedAt.Local()
+	media.SkippedVariants = strings.Fields(skipped)
+	return &media, nil
+}
+
+// FindAll 查找所有用户上传的文件，按ID正序
+func (s *SQLMediaStore) FindAll(ctx context.Context) ([]*models.Media, error) {
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, `SELECT `+mediaColumns+` FROM media ORDER BY id`)
+	if err != nil {
+		log.Printf("查询上传文件失败: %v", err)
+		return nil, err
+	}
+	return scanMediaRows(rows)
+}
+
+// ListByUser 查找用户上传的所有文件，最新的在前
+func (s *SQLMediaStore) ListByUser(ctx context.Context, userID int) ([]*models.Media, error) {
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, s.dialect.rebind(`
+		SELECT `+mediaColumns+`
+		FROM media
+		WHERE user_id = ?
+		ORDER BY id DESC
+	`), userID)
+	if err != nil {
+		log.Printf("查询上传文件失败: %v", err)
+		return nil, err
+	}
+	return scanMediaRows(rows)
+}
+
+// scanMediaRows 扫描所有行并关闭结果集
+func scanMediaRows(rows *sql.Rows) ([]*models.Media, error) {
+	defer rows.Close()
+
+	media := []*models.Media{}
+	for rows.Next() {
+		m, err := scanMedia(rows)
+		if err != nil {
+			return nil, err
+		}
+		media = append(media, m)
+	}
+
+	return media, rows.Err()
+}
+
+// FindByID 根据ID查找文件
+func (s *SQLMediaStore) FindByID(ctx context.Context, id int) (*models.Media, error) {
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+
+	row := s.db.QueryRowContext(ctx, s.dialect.rebind(`SELECT `+mediaColumns+` FROM media WHERE id = ?`), id)
+	return scanMedia(row)
+}
+
+// FindByHash 查找用户上传过的相同内容的文件
+func (s *SQLMediaStore) FindByHash(ctx context.Context, userID int, hash string) (*models.Media, error) {
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+
+	row := s.db.QueryRowContext(ctx, s.dialect.rebind(`
+		SELECT `+mediaColumns+` FROM media WHERE user_id = ? AND hash = ?
+	`), userID, hash)
+	return scanMedia(row)
+}
+
+// FindByHashes 按内容查找文件，每个内容返回一条记录
+func (s *SQLMediaStore) FindByHashes(ctx context.Context, hashes []string) (map[string]*models.Media, error) {
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+
+	media := make(map[string]*models.Media)
+	if len(hashes) == 0 {
+		return media, nil
+	}
+
+	args := make([]interface{}, len(hashes))
+	for i, hash := range hashes {
+		args[i] = hash
+	}
+	rows, err := s.db.QueryContext(ctx, s.dialect.rebind(`
+		SELECT `+mediaColumns+` FROM media WHERE hash IN (`+placeholders(len(hashes))+`) ORDER BY id
+	`), args...)
+	if err != nil {
+		log.Printf("查询上传文件失败: %v", err)
+		return nil, err
+	}
+
+	list, err := scanMediaRows(rows)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range list {
+		if _, ok := media[m.Hash]; !ok {
+			media[m.Hash] = m
+		}
+	}
+	return media, nil
+}
+
+// ListByHash 查找引用该内容的所有文件记录，按ID正序
+func (s *SQLMediaStore) ListByHash(ctx context.Context, hash string) ([]*models.Media, error) {
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, s.dialect.rebind(`SELECT `+mediaColumns+` FROM media WHERE hash = ? ORDER BY id`), hash)
+	if err != nil {
+		log.Printf("查询上传文件失败: %v", err)
+		return nil, err
+	}
+	return scanMediaRows(rows)
+}
+
+// CountByHash 统计引用该内容的文件数
+func (s *SQLMediaStore) CountByHash(ctx context.Context, hash string) (int, error) {
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+
+	var count int
+	err := s.db.QueryRowContext(ctx, s.dialect.rebind(`SELECT COUNT(*) FROM media WHERE hash = ?`), hash).Scan(&count)
+	return count, err
+}
+
+// Create 创建文件记录
+func (s *SQLMediaStore) Create(ctx context.Context, media *models.Media) error {
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+
+	now := now()
+	id, err := s.dialect.insert(ctx, s.db, `
+		INSERT INTO media (user_id, file_name, hash, mime_type, size, width, height, private, created_at, skipped_variants)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		media.UserID, media.FileName, media.Hash, media.MIMEType, media.Size, media.Width, media.Height, media.Private, now,
+		strings.Join(media.SkippedVariants, " "))
+	if err != nil {
+		log.Printf("创建上传文件记录失败: %v", err)
+		return err
+	}
+
+	media.ID = int(id)
+	media.CreatedAt = now.Local()
+	return nil
+}
+
+// SetSkippedVariants 更新引用该内容的所有文件记录中没有生成的版本名称
+func (s *SQLMediaStore) SetSkippedVariants(ctx context.Context, hash string, skipped []string) error {
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, s.dialect.rebind(`UPDATE media SET skipped_variants = ? WHERE hash = ?`),
+		strings.Join(skipped, " "), hash)
+	if err != nil {
+		log.Printf("更新上传文件记录失败: %v", err)
+	}
+	return err
+}
+
+// SetPrivate 修改文件的可见范围
+func (s *SQLMediaStore) SetPrivate(ctx context.Context, id int, private bool) error {
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, s.dialect.rebind(`UPDATE media SET private = ? WHERE id = ?`), private, id)
+	if err != nil {
+		log.Printf("更新上传文件记录失败: %v", err)
+		return err
+	}
+	return expectAffected(result)
+}
+
+// Delete 删除文件记录
+func (s *SQLMediaStore) Delete(ctx context.Context, id int) error {
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, s.dialect.rebind(`DELETE FROM media WHERE id = ?`), id)
+	if err != nil {
+		return err
+	}
+	return expectAffected(result)
+}
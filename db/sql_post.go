@@ -0,0 +1,591 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"goblog/models"
+	"log"
+	"strings"
+	"time"
+)
+
+// SQLPostStore 基于SQL数据库的文章存储
+type SQLPostStore struct {
+	db      *sql.DB
+	dialect *dialect
+	timeout time.Duration // 每次操作的超时，0表示不限制
+
+	// fullText 是否启用了SQLite FTS5全文索引
+	fullText bool
+}
+
+// 编译期检查是否实现了文章存储接口
+var _ models.PostStore = (*SQLPostStore)(nil)
+
+// publishedCondition 公开可见的文章：未移入回收站且已发布
+const publishedCondition = "p.deleted_at IS NULL AND p.status = 'published'"
+
+// postColumns 查询文章及作者时选择的列，顺序与 scanPost 一致
+const postColumns = `
+	p.id, p.title, p.slug, p.content, p.format, p.user_id, p.category_id, p.status, p.publish_at, p.created_at, p.updated_at, p.deleted_at, p.version,
+	u.id, u.username, u.email, u.created_at, u.updated_at`
+
+// postListColumns 列表查询选择的列，正文只截取摘要所需的部分
+var postListColumns = fmt.Sprintf(`
+	p.id, p.title, p.slug, substr(p.content, 1, %d), p.format, p.user_id, p.category_id, p.status, p.publish_at, p.created_at, p.updated_at, p.deleted_at, p.version,
+	u.id, u.username, u.email, u.created_at, u.updated_at`, models.ExcerptLength+1)
+
+// scanner 是 *sql.Row 和 *sql.Rows 的公共部分
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanFunc 把函数适配为 scanner，用于在公共的扫描函数之后追加额外的列
+type scanFunc func(dest ...interface{}) error
+
+// Scan 实现 scanner 接口
+func (f scanFunc) Scan(dest ...interface{}) error {
+	return f(dest...)
+}
+
+// scanPost 扫描一行文章及作者数据
+func scanPost(row scanner) (*models.Post, error) {
+	var post models.Post
+	var user models.User
+	var categoryID sql.NullInt64
+	var format, status string
+	var publishAt, deletedAt sql.NullTime
+
+	err := row.Scan(
+		&post.ID, &post.Title, &post.Slug, &post.Content, &format, &post.UserID, &categoryID, &status, &publishAt, &post.CreatedAt, &post.UpdatedAt, &deletedAt, &post.Version,
+		&user.ID, &user.Username, &user.Email, &user.CreatedAt, &user.UpdatedAt,
+	)
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	// 数据库中统一保存UTC时间，展示时使用本地时区
+	post.CreatedAt = post.CreatedAt.Local()
+	post.UpdatedAt = post.UpdatedAt.Local()
+	user.CreatedAt = user.CreatedAt.Local()
+	user.UpdatedAt = user.UpdatedAt.Local()
+	post.User = &user
+	post.CategoryID = int(categoryID.Int64)
+	post.Format = models.PostFormat(format)
+	post.Status = models.PostStatus(status)
+	if publishAt.Valid {
+		publishAt := publishAt.Time.Local()
+		post.PublishAt = &publishAt
+	}
+	if deletedAt.Valid {
+		deletedAt := deletedAt.Time.Local()
+		post.DeletedAt = &deletedAt
+	}
+
+	return &post, nil
+}
+
+// FindAll 查找所有已发布的文章
+func (s *SQLPostStore) FindAll(ctx context.Context) ([]*models.Post, error) {
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+
+	log.Println("正在查询所有文章...")
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+postColumns+`
+		FROM posts p
+		JOIN users u ON p.user_id = u.id
+		WHERE `+publishedCondition+`
+		ORDER BY p.created_at DESC, p.id DESC
+	`)
+	if err != nil {
+		log.Printf("查询文章失败: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	posts := []*models.Post{}
+	for rows.Next() {
+		post, err := scanPost(rows)
+		if err != nil {
+			log.Printf("扫描文章行失败: %v", err)
+			return nil, err
+		}
+		posts = append(posts, post)
+	}
+
+	// 检查遍历过程中是否有错误
+	if err = rows.Err(); err != nil {
+		log.Printf("遍历结果集时出错: %v", err)
+		return nil, err
+	}
+
+	if err := s.attachTaxonomy(ctx, posts); err != nil {
+		return nil, err
+	}
+
+	log.Printf("查询到 %d 篇文章", len(posts))
+	return posts, nil
+}
+
+// List 按键集分页查询文章列表
+func (s *SQLPostStore) List(ctx context.Context, opts models.ListOptions) (*models.PostPage, error) {
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+
+	return s.listPosts(ctx, "", nil, opts)
+}
+
+// ListByTag 按键集分页查询带有某个标签的文章
+func (s *SQLPostStore) ListByTag(ctx context.Context, tag string, opts models.ListOptions) (*models.PostPage, error) {
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+
+	return s.listPosts(ctx, `p.id IN (
+			SELECT pt.post_id FROM post_tags pt
+			JOIN tags t ON pt.tag_id = t.id
+			WHERE t.name = ?)`, []interface{}{models.NormalizeTag(tag)}, opts)
+}
+
+// ListByCategory 按键集分页查询某个分类及其下级分类中的文章
+func (s *SQLPostStore) ListByCategory(ctx context.Context, slug string, opts models.ListOptions) (*models.PostPage, error) {
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+
+	return s.listPosts(ctx, `p.category_id IN (
+			SELECT id FROM categories
+			WHERE slug = ? OR slug LIKE ? ESCAPE '!')`, []interface{}{slug, subcategoryPattern(slug)}, opts)
+}
+
+// listPosts 在附加条件where下按键集分页查询已发布的文章，where为空表示不加条件
+func (s *SQLPostStore) listPosts(ctx context.Context, where string, args []interface{}, opts models.ListOptions) (*models.PostPage, error) {
+	opts.Normalize()
+
+	// 查询方向：向前翻页时反向查询，取回后再倒序
+	desc := opts.Sort == models.SortNewest
+	cursor := opts.After
+	if opts.Before != nil {
+		desc = !desc
+		cursor = opts.Before
+	}
+
+	conditions := []string{publishedCondition}
+	if where != "" {
+		conditions = append(conditions, where)
+	}
+	if cursor != nil {
+		op := ">"
+		if desc {
+			op = "<"
+		}
+		conditions = append(conditions, fmt.Sprintf("(p.created_at %s ? OR (p.created_at = ? AND p.id %s ?))", op, op))
+		createdAt := cursor.CreatedAt.UTC()
+		args = append(args, createdAt, createdAt, cursor.ID)
+	}
+
+	order := "ASC"
+	if desc {
+		order = "DESC"
+	}
+
+	query := `
+		SELECT ` + postListColumns + `
+		FROM posts p
+		JOIN users u ON p.user_id = u.id
+		WHERE ` + strings.Join(conditions, " AND ")
+	query += fmt.Sprintf("\n\t\tORDER BY p.created_at %s, p.id %s\n\t\tLIMIT ?", order, order)
+	args = append(args, opts.Limit+1)
+
+	rows, err := s.db.QueryContext(ctx, s.dialect.rebind(query), args...)
+	if err != nil {
+		log.Printf("分页查询文章失败: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	posts := []*models.Post{}
+	for rows.Next() {
+		post, err := scanPost(rows)
+		if err != nil {
+			return nil, err
+		}
+		post.Excerpt = models.Excerpt(post.Content)
+		post.Content = ""
+		posts = append(posts, post)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	hasMore := len(posts) > opts.Limit
+	if hasMore {
+		posts = posts[:opts.Limit]
+	}
+	if opts.Before != nil {
+		for i, j := 0, len(posts)-1; i < j; i, j = i+1, j-1 {
+			posts[i], posts[j] = posts[j], posts[i]
+		}
+	}
+
+	if err := s.attachTaxonomy(ctx, posts); err != nil {
+		return nil, err
+	}
+
+	return models.NewPostPage(posts, opts, hasMore), nil
+}
+
+// FindByID 根据ID查找任意状态的文章
+func (s *SQLPostStore) FindByID(ctx context.Context, id int) (*models.Post, error) {
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+
+	row := s.db.QueryRowContext(ctx, s.dialect.rebind(`
+		SELECT `+postColumns+`
+		FROM posts p
+		JOIN users u ON p.user_id = u.id
+		WHERE p.id = ? AND p.deleted_at IS NULL
+	`), id)
+
+	post, err := scanPost(row)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.attachTaxonomy(ctx, []*models.Post{post}); err != nil {
+		return nil, err
+	}
+	return post, nil
+}
+
+// Create 创建文章，状态为空时视为已发布，格式为空时视为Markdown
+func (s *SQLPostStore) Create(ctx context.Context, post *models.Post) error {
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+
+	now := now()
+	if post.Format == "" {
+		post.Format = models.FormatMarkdown
+	}
+	if post.IsPublished() {
+		published := now.Local()
+		post.Status = models.StatusPublished
+		post.PublishAt = &published
+	}
+
+	return withTx(ctx, s.db, func(tx *sql.Tx) error {
+		if err := s.resolveCategory(ctx, tx, post); err != nil {
+			return err
+		}
+		slug, err := s.uniqueSlug(ctx, tx, post)
+		if err != nil {
+			return err
+		}
+		if err := s.claimSlug(ctx, tx, slug); err != nil {
+			return err
+		}
+
+		id, err := s.dialect.insert(ctx, tx, `
+			INSERT INTO posts (title, slug, content, format, user_id, category_id, status, publish_at, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			post.Title, slug, post.Content, string(post.Format), post.UserID, nullableID(post.CategoryID),
+			string(post.Status), nullableTime(post.PublishAt), now, now)
+		if err != nil {
+			return err
+		}
+
+		post.Slug = slug
+
+		post.ID = int(id)
+		post.CreatedAt = now.Local()
+		post.UpdatedAt = post.CreatedAt
+		post.Version = 1
+
+		if err := s.saveTags(ctx, tx, post); err != nil {
+			return err
+		}
+		if err := s.addRevision(ctx, tx, post, post.UserID, now); err != nil {
+			return err
+		}
+		return s.indexPost(ctx, tx, post)
+	})
+}
+
+// Update 更新文章及其状态，并以editorID为作者保存一个新版本。
+// 发布时间由 Post.SetStatus 维护，这里一并写入 created_at
+func (s *SQLPostStore) Update(ctx context.Context, post *models.Post, editorID int) error {
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+
+	now := now()
+	if post.Status == "" {
+		post.Status = models.StatusPublished
+	}
+	if post.Format == "" {
+		post.Format = models.FormatMarkdown
+	}
+
+	return withTx(ctx, s.db, func(tx *sql.Tx) error {
+		// 别名修改后保存旧别名，以便重定向
+		var oldSlug string
+		err := tx.QueryRowContext(ctx, s.dialect.rebind(`SELECT slug FROM posts WHERE id = ?`), post.ID).Scan(&oldSlug)
+		if err != nil {
+			return err
+		}
+		if err := s.resolveCategory(ctx, tx, post); err != nil {
+			return err
+		}
+		slug, err := s.uniqueSlug(ctx, tx, post)
+		if err != nil {
+			return err
+		}
+		if slug != oldSlug {
+			if err := s.claimSlug(ctx, tx, slug); err != nil {
+				return err
+			}
+			_, err := tx.ExecContext(ctx, s.dialect.rebind(`INSERT INTO post_slug_redirects (slug, post_id, created_at) VALUES (?, ?, ?)`),
+				oldSlug, post.ID, now)
+			if err != nil {
+				return err
+			}
+		}
+
+		// 版本号不一致时不更新任何行，别名的修改随事务回滚
+		result, err := tx.ExecContext(ctx, s.dialect.rebind(`
+			UPDATE posts
+			SET title = ?, slug = ?, content = ?, format = ?, category_id = ?, status = ?, publish_at = ?, created_at = ?, updated_at = ?,
+				version = version + 1
+			WHERE id = ? AND version = ?
+		`), post.Title, slug, post.Content, string(post.Format), nullableID(post.CategoryID), string(post.Status), nullableTime(post.PublishAt),
+			post.CreatedAt.UTC(), now, post.ID, post.Version)
+		if err != nil {
+			return err
+		}
+		if err := expectAffected(result); err != nil {
+			if errors.Is(err, ErrNotFound) {
+				return ErrConflict
+			}
+			return err
+		}
+
+		post.Slug = slug
+		post.Version++
+
+		post.UpdatedAt = now.Local()
+
+		if err := s.saveTags(ctx, tx, post); err != nil {
+			return err
+		}
+
+		if err := s.addRevision(ctx, tx, post, editorID, now); err != nil {
+			return err
+		}
+		return s.indexPost(ctx, tx, post)
+	})
+}
+
+// Delete 把文章移入回收站，并从全文索引中移除
+func (s *SQLPostStore) Delete(ctx context.Context, id int) error {
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+
+	return withTx(ctx, s.db, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, s.dialect.rebind(`
+			UPDATE posts SET deleted_at = ?
+			WHERE id = ? AND deleted_at IS NULL
+		`), now(), id)
+		if err != nil {
+			return err
+		}
+		if err := expectAffected(result); err != nil {
+			return err
+		}
+		return s.unindexPost(ctx, tx, id)
+	})
+}
+
+// Drafts 查找用户的草稿和定时文章，按更新时间倒序
+func (s *SQLPostStore) Drafts(ctx context.Context, userID int) ([]*models.Post, error) {
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, s.dialect.rebind(`
+		SELECT `+postListColumns+`
+		FROM posts p
+		JOIN users u ON p.user_id = u.id
+		WHERE p.user_id = ? AND p.status <> 'published' AND p.deleted_at IS NULL
+		ORDER BY p.updated_at DESC, p.id DESC
+	`), userID)
+	if err != nil {
+		log.Printf("查询草稿失败: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	posts := []*models.Post{}
+	for rows.Next() {
+		post, err := scanPost(rows)
+		if err != nil {
+			return nil, err
+		}
+		post.Excerpt = models.Excerpt(post.Content)
+		post.Content = ""
+		posts = append(posts, post)
+	}
+
+	return posts, rows.Err()
+}
+
+// PublishDue 发布定时发布时间不晚于now的文章，发布时间取计划的时间而不是实际执行的时间
+func (s *SQLPostStore) PublishDue(ctx context.Context, now time.Time) (int, error) {
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, s.dialect.rebind(`
+		UPDATE posts
+		SET created_at = publish_at,
+			updated_at = CASE WHEN updated_at < publish_at THEN publish_at ELSE updated_at END,
+			status = 'published'
+		WHERE status = 'scheduled' AND publish_at <= ? AND deleted_at IS NULL
+	`), now.UTC())
+	if err != nil {
+		return 0, err
+	}
+
+	published, err := result.RowsAffected()
+	return int(published), err
+}
+
+// Trash 查找用户回收站中的文章，按删除时间倒序
+func (s *SQLPostStore) Trash(ctx context.Context, userID int) ([]*models.Post, error) {
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, s.dialect.rebind(`
+		SELECT `+postListColumns+`
+		FROM posts p
+		JOIN users u ON p.user_id = u.id
+		WHERE p.user_id = ? AND p.deleted_at IS NOT NULL
+		ORDER BY p.deleted_at DESC, p.id DESC
+	`), userID)
+	if err != nil {
+		log.Printf("查询回收站失败: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	posts := []*models.Post{}
+	for rows.Next() {
+		post, err := scanPost(rows)
+		if err != nil {
+			return nil, err
+		}
+		post.Excerpt = models.Excerpt(post.Content)
+		post.Content = ""
+		posts = append(posts, post)
+	}
+
+	return posts, rows.Err()
+}
+
+// FindTrashed 根据ID查找回收站中的文章
+func (s *SQLPostStore) FindTrashed(ctx context.Context, id int) (*models.Post, error) {
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+
+	row := s.db.QueryRowContext(ctx, s.dialect.rebind(`
+		SELECT `+postColumns+`
+		FROM posts p
+		JOIN users u ON p.user_id = u.id
+		WHERE p.id = ? AND p.deleted_at IS NOT NULL
+	`), id)
+
+	return scanPost(row)
+}
+
+// Restore 把文章从回收站中恢复，并重新写入全文索引
+func (s *SQLPostStore) Restore(ctx context.Context, id int) error {
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+
+	return withTx(ctx, s.db, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, s.dialect.rebind(`
+			UPDATE posts SET deleted_at = NULL
+			WHERE id = ? AND deleted_at IS NOT NULL
+		`), id)
+		if err != nil {
+			return err
+		}
+		if err := expectAffected(result); err != nil {
+			return err
+		}
+
+		if !s.fullText {
+			return nil
+		}
+		var post models.Post
+		err = tx.QueryRowContext(ctx, `SELECT id, title, content FROM posts WHERE id = ?`, id).Scan(&post.ID, &post.Title, &post.Content)
+		if err != nil {
+			return err
+		}
+		return s.indexPost(ctx, tx, &post)
+	})
+}
+
+// purgedTables 永久删除文章时需要一并删除的关联表，表中以 post_id 关联文章
+var purgedTables = []string{"post_revisions", "post_tags", "comments", "post_slug_redirects"}
+
+// Purge 永久删除回收站中的文章及其关联数据
+func (s *SQLPostStore) Purge(ctx context.Context, id int) error {
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+
+	return withTx(ctx, s.db, func(tx *sql.Tx) error {
+		for _, table := range purgedTables {
+			_, err := tx.ExecContext(ctx, s.dialect.rebind(`
+				DELETE FROM `+table+`
+				WHERE post_id IN (SELECT id FROM posts WHERE id = ? AND deleted_at IS NOT NULL)
+			`), id)
+			if err != nil {
+				return err
+			}
+		}
+
+		result, err := tx.ExecContext(ctx, s.dialect.rebind(`DELETE FROM posts WHERE id = ? AND deleted_at IS NOT NULL`), id)
+		if err != nil {
+			return err
+		}
+		return expectAffected(result)
+	})
+}
+
+// PurgeDeletedBefore 永久删除在before之前移入回收站的文章及其关联数据，返回删除的数量
+func (s *SQLPostStore) PurgeDeletedBefore(ctx context.Context, before time.Time) (int, error) {
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+
+	before = before.UTC()
+
+	var purged int64
+	err := withTx(ctx, s.db, func(tx *sql.Tx) error {
+		for _, table := range purgedTables {
+			_, err := tx.ExecContext(ctx, s.dialect.rebind(`
+				DELETE FROM `+table+`
+				WHERE post_id IN (SELECT id FROM posts WHERE deleted_at IS NOT NULL AND deleted_at < ?)
+			`), before)
+			if err != nil {
+				return err
+			}
+		}
+
+		result, err := tx.ExecContext(ctx, s.dialect.rebind(`DELETE FROM posts WHERE deleted_at IS NOT NULL AND deleted_at < ?`), before)
+		if err != nil {
+			return err
+		}
+		purged, err = result.RowsAffected()
+		return err
+	})
+
+	return int(purged), err
+}
@@ -0,0 +1,121 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"goblog/models"
+	"log"
+	"time"
+)
+
+// revisionColumns 查询历史版本时选择的列，顺序与 scanRevision 一致。
+// 修改者可能已被删除，因此使用 LEFT JOIN 并只读取用户名
+const revisionColumns = `r.id, r.post_id, r.user_id, r.title, r.content, r.format, r.created_at, COALESCE(u.username, '')`
+
+// scanRevision 扫描一行历史版本数据
+func scanRevision(row scanner) (*models.Revision, error) {
+	var revision models.Revision
+	var format, username string
+
+	err := row.Scan(&revision.ID, &revision.PostID, &revision.UserID, &revision.Title, &revision.Content, &format, &revision.CreatedAt, &username)
+	if err != nil {
+		return nil, translateError(err)
+	}
+	revision.Format = models.PostFormat(format)
+
+	revision.CreatedAt = revision.CreatedAt.Local()
+	if username != "" {
+		revision.User = &models.User{ID: revision.UserID, Username: username}
+	}
+
+	return &revision, nil
+}
+
+// addRevision 以文章当前内容保存一个版本，与文章的写入在同一事务中执行
+func (s *SQLPostStore) addRevision(ctx context.Context, q queryer, post *models.Post, editorID int, now time.Time) error {
+	_, err := s.dialect.insert(ctx, q, `
+		INSERT INTO post_revisions (post_id, user_id, title, content, format, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		post.ID, editorID, post.Title, post.Content, string(post.Format), now)
+	return err
+}
+
+// Revisions 查找文章的所有历史版本，按时间倒序
+func (s *SQLPostStore) Revisions(ctx context.Context, postID int) ([]*models.Revision, error) {
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, s.dialect.rebind(`
+		SELECT `+revisionColumns+`
+		FROM post_revisions r
+		LEFT JOIN users u ON r.user_id = u.id
+		WHERE r.post_id = ?
+		ORDER BY r.id DESC
+	`), postID)
+	if err != nil {
+		log.Printf("查询文章历史版本失败: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []*models.Revision{}
+	for rows.Next() {
+		revision, err := scanRevision(rows)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, revision)
+	}
+
+	return revisions, rows.Err()
+}
+
+// FindRevision 查找文章的某个历史版本
+func (s *SQLPostStore) FindRevision(ctx context.Context, postID, revisionID int) (*models.Revision, error) {
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+
+	row := s.db.QueryRowContext(ctx, s.dialect.rebind(`
+		SELECT `+revisionColumns+`
+		FROM post_revisions r
+		LEFT JOIN users u ON r.user_id = u.id
+		WHERE r.post_id = ? AND r.id = ?
+	`), postID, revisionID)
+
+	return scanRevision(row)
+}
+
+// LatestRevision 查找文章的最新版本，包括渲染结果的缓存
+func (s *SQLPostStore) LatestRevision(ctx context.Context, postID int) (*models.Revision, error) {
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+
+	var html sql.NullString
+	row := s.db.QueryRowContext(ctx, s.dialect.rebind(`
+		SELECT `+revisionColumns+`, r.html
+		FROM post_revisions r
+		LEFT JOIN users u ON r.user_id = u.id
+		WHERE r.post_id = ?
+		ORDER BY r.id DESC
+		LIMIT 1
+	`), postID)
+
+	revision, err := scanRevision(scanFunc(func(dest ...interface{}) error {
+		return row.Scan(append(dest, &html)...)
+	}))
+	if err != nil {
+		return nil, err
+	}
+
+	revision.HTML = html.String
+	return revision, nil
+}
+
+// SaveRevisionHTML 保存版本的渲染结果
+func (s *SQLPostStore) SaveRevisionHTML(ctx context.Context, revisionID int, html string) error {
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, s.dialect.rebind(`UPDATE post_revisions SET html = ? WHERE id = ?`), html, revisionID)
+	return err
+}
@@ -0,0 +1,546 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"goblog/models"
+	"strings"
+	"time"
+)
+
+// SQLSiteStore 基于SQL数据库的整站数据存储，用于导出和导入
+type SQLSiteStore struct {
+	db      *sql.DB
+	dialect *dialect
+	timeout time.Duration // 整个导出或导入事务的超时，0表示不限制
+
+	// posts 复用文章存储中的别名、标签、分类和全文索引的处理
+	posts *SQLPostStore
+}
+
+// 编译期检查是否实现了整站数据存储接口
+var _ models.SiteStore = (*SQLSiteStore)(nil)
+
+// errDryRun 用于在试运行结束时回滚事务
+var errDryRun = errors.New("试运行")
+
+// Dump 读取整站数据，所有记录按ID正序
+func (s *SQLSiteStore) Dump(ctx context.Context) (*models.SiteData, error) {
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+
+	data := &models.SiteData{}
+
+	// 在一个事务中读取，服务运行时导出也能得到一致的数据
+	err := withTx(ctx, s.db, func(tx *sql.Tx) error {
+		var err error
+		if data.Users, err = s.dumpUsers(ctx, tx); err != nil {
+			return err
+		}
+		if data.Categories, err = s.dumpCategories(ctx, tx); err != nil {
+			return err
+		}
+		if data.Posts, err = s.dumpPosts(ctx, tx); err != nil {
+			return err
+		}
+		if data.Redirects, err = s.dumpRedirects(ctx, tx); err != nil {
+			return err
+		}
+		if data.Revisions, err = s.dumpRevisions(ctx, tx); err != nil {
+			return err
+		}
+		if data.Comments, err = s.dumpComments(ctx, tx); err != nil {
+			return err
+		}
+		data.Media, err = s.dumpMedia(ctx, tx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// dumpUsers 读取所有用户，包括密码哈希
+func (s *SQLSiteStore) dumpUsers(ctx context.Context, q queryer) ([]*models.User, error) {
+	rows, err := q.QueryContext(ctx, `SELECT `+userColumns+` FROM users ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []*models.User{}
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
+
+// dumpCategories 读取所有分类，上级分类总是先于下级分类创建，按ID排序即可保证顺序
+func (s *SQLSiteStore) dumpCategories(ctx context.Context, q queryer) ([]*models.Category, error) {
+	rows, err := q.QueryContext(ctx, `SELECT `+categoryColumns+` FROM categories ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	categories := []*models.Category{}
+	for rows.Next() {
+		category, err := scanCategory(rows)
+		if err != nil {
+			return nil, err
+		}
+		categories = append(categories, category)
+	}
+	return categories, rows.Err()
+}
+
+// dumpPosts 读取任意状态的所有文章及其标签
+func (s *SQLSiteStore) dumpPosts(ctx context.Context, q queryer) ([]*models.Post, error) {
+	rows, err := q.QueryContext(ctx, `
+		SELECT `+postColumns+`
+		FROM posts p
+		JOIN users u ON p.user_id = u.id
+		ORDER BY p.id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	posts := []*models.Post{}
+	byID := make(map[int]*models.Post)
+	for rows.Next() {
+		post, err := scanPost(rows)
+		if err != nil {
+			return nil, err
+		}
+		post.User = nil
+		post.Tags = []string{}
+		posts = append(posts, post)
+		byID[post.ID] = post
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = q.QueryContext(ctx, `
+		SELECT pt.post_id, t.name
+		FROM post_tags pt
+		JOIN tags t ON pt.tag_id = t.id
+		ORDER BY t.name
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var postID int
+		var name string
+		if err := rows.Scan(&postID, &name); err != nil {
+			return nil, err
+		}
+		if post, ok := byID[postID]; ok {
+			post.Tags = append(post.Tags, name)
+		}
+	}
+	return posts, rows.Err()
+}
+
+// dumpRedirects 读取所有文章的旧别名
+func (s *SQLSiteStore) dumpRedirects(ctx context.Context, q queryer) ([]*models.SlugRedirect, error) {
+	rows, err := q.QueryContext(ctx, `SELECT slug, post_id, created_at FROM post_slug_redirects ORDER BY created_at, slug`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	redirects := []*models.SlugRedirect{}
+	for rows.Next() {
+		var redirect models.SlugRedirect
+		if err := rows.Scan(&redirect.Slug, &redirect.PostID, &redirect.CreatedAt); err != nil {
+			return nil, err
+		}
+		redirect.CreatedAt = redirect.CreatedAt.Local()
+		redirects = append(redirects, &redirect)
+	}
+	return redirects, rows.Err()
+}
+
+// dumpRevisions 读取所有文章的历史版本，不包括渲染结果的缓存
+func (s *SQLSiteStore) dumpRevisions(ctx context.Context, q queryer) ([]*models.Revision, error) {
+	rows, err := q.QueryContext(ctx, `
+		SELECT `+revisionColumns+`
+		FROM post_revisions r
+		LEFT JOIN users u ON r.user_id = u.id
+		ORDER BY r.id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []*models.Revision{}
+	for rows.Next() {
+		revision, err := scanRevision(rows)
+		if err != nil {
+			return nil, err
+		}
+		revision.User = nil
+		revisions = append(revisions, revision)
+	}
+	return revisions, rows.Err()
+}
+
+// dumpComments 读取所有评论，包括已删除的，回复总是晚于被回复的评论创建
+func (s *SQLSiteStore) dumpComments(ctx context.Context, q queryer) ([]*models.Comment, error) {
+	rows, err := q.QueryContext(ctx, `
+		SELECT `+commentColumns+`
+		FROM comments c
+		JOIN users u ON c.user_id = u.id
+		ORDER BY c.id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	comments := []*models.Comment{}
+	for rows.Next() {
+		comment, err := scanComment(rows)
+		if err != nil {
+			return nil, err
+		}
+		comment.User = nil
+		comments = append(comments, comment)
+	}
+	return comments, rows.Err()
+}
+
+// dumpMedia 读取所有上传文件的记录
+func (s *SQLSiteStore) dumpMedia(ctx context.Context, q queryer) ([]*models.Media, error) {
+	rows, err := q.QueryContext(ctx, `SELECT `+mediaColumns+` FROM media ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	media := []*models.Media{}
+	for rows.Next() {
+		m, err := scanMedia(rows)
+		if err != nil {
+			return nil, err
+		}
+		media = append(media, m)
+	}
+	return media, rows.Err()
+}
+
+// Load 在一个事务中写入整站数据，试运行时在最后回滚事务
+func (s *SQLSiteStore) Load(ctx context.Context, data *models.SiteData, existing map[int]int, dryRun bool) (*models.LoadResult, error) {
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+
+	var result *models.LoadResult
+	err := withTx(ctx, s.db, func(tx *sql.Tx) error {
+		l := &sqlLoader{store: s, tx: tx, result: &models.LoadResult{RenamedSlugs: map[string]string{}}}
+		if err := l.load(ctx, data, existing); err != nil {
+			return err
+		}
+		result = l.result
+		if dryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errDryRun) {
+		return nil, err
+	}
+	return result, nil
+}
+
+// sqlLoader 一次导入的状态，保存导入数据中的ID到新ID的映射
+type sqlLoader struct {
+	store  *SQLSiteStore
+	tx     *sql.Tx
+	result *models.LoadResult
+
+	users      map[int]int
+	categories map[int]*models.Category
+	posts      map[int]*models.Post
+	comments   map[int]int
+}
+
+// load 按依赖顺序写入各类记录
+func (l *sqlLoader) load(ctx context.Context, data *models.SiteData, existing map[int]int) error {
+	if err := l.loadUsers(ctx, data.Users, existing); err != nil {
+		return err
+	}
+	if err := l.loadCategories(ctx, data.Categories); err != nil {
+		return err
+	}
+	if err := l.loadPosts(ctx, data.Posts); err != nil {
+		return err
+	}
+	if err := l.loadRedirects(ctx, data.Redirects); err != nil {
+		return err
+	}
+	if err := l.loadRevisions(ctx, data.Revisions); err != nil {
+		return err
+	}
+	if err := l.loadComments(ctx, data.Comments); err != nil {
+		return err
+	}
+	return l.loadMedia(ctx, data.Media)
+}
+
+// loadUsers 创建新用户，或用导入的资料覆盖映射到的现有用户
+func (l *sqlLoader) loadUsers(ctx context.Context, users []*models.User, existing map[int]int) error {
+	q, d := l.tx, l.store.dialect
+	l.users = make(map[int]int, len(users)+len(existing))
+	for from, to := range existing {
+		l.users[from] = to
+	}
+
+	now := now()
+	for _, user := range users {
+		if id, ok := existing[user.ID]; ok {
+			result, err := q.ExecContext(ctx, d.rebind(`
+				UPDATE users
+				SET username = ?, email = ?, is_admin = ?, password = CASE WHEN ? = '' THEN password ELSE ? END, updated_at = ?
+				WHERE id = ?
+			`), user.Username, user.Email, user.IsAdmin, user.Password, user.Password, now, id)
+			if err != nil {
+				return fmt.Errorf("覆盖用户 %s 失败: %w", user.Username, err)
+			}
+			if err := expectAffected(result); err != nil {
+				return fmt.Errorf("覆盖用户 %s 失败: %w", user.Username, err)
+			}
+			l.result.Overwritten++
+			continue
+		}
+
+		if user.Password == "" {
+			return fmt.Errorf("新用户 %s 没有密码哈希", user.Username)
+		}
+		id, err := d.insert(ctx, q, `
+			INSERT INTO users (username, email, password, is_admin, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?)`,
+			user.Username, user.Email, user.Password, user.IsAdmin, user.CreatedAt.UTC(), user.UpdatedAt.UTC())
+		if err != nil {
+			return fmt.Errorf("创建用户 %s 失败: %w", user.Username, err)
+		}
+		l.users[user.ID] = int(id)
+		l.result.Users++
+	}
+	return nil
+}
+
+// loadCategories 按名称重建分类树，与现有分类重复的直接使用现有分类
+func (l *sqlLoader) loadCategories(ctx context.Context, categories []*models.Category) error {
+	l.categories = make(map[int]*models.Category, len(categories))
+	for _, category := range categories {
+		var parent *models.Category
+		if category.ParentID != 0 {
+			var ok bool
+			if parent, ok = l.categories[category.ParentID]; !ok {
+				return fmt.Errorf("分类 %s 的上级分类不在导入数据中", category.Path)
+			}
+		}
+
+		target, created, err := l.store.posts.ensureChild(ctx, l.tx, parent, category.Name)
+		if err != nil {
+			return fmt.Errorf("创建分类 %s 失败: %w", category.Path, err)
+		}
+		l.categories[category.ID] = target
+		if created {
+			l.result.Categories++
+		}
+	}
+	return nil
+}
+
+// loadPosts 创建文章及其标签，别名重复时加上后缀
+func (l *sqlLoader) loadPosts(ctx context.Context, posts []*models.Post) error {
+	q, d, store := l.tx, l.store.dialect, l.store.posts
+	l.posts = make(map[int]*models.Post, len(posts))
+	for _, p := range posts {
+		post := *p
+		post.ID = 0
+		userID, ok := l.users[p.UserID]
+		if !ok {
+			return fmt.Errorf("文章 %s 的作者不在导入数据中", p.Slug)
+		}
+		post.UserID = userID
+		if p.CategoryID != 0 {
+			category, ok := l.categories[p.CategoryID]
+			if !ok {
+				return fmt.Errorf("文章 %s 的分类不在导入数据中", p.Slug)
+			}
+			post.CategoryID = category.ID
+		}
+		if post.Format == "" {
+			post.Format = models.FormatMarkdown
+		}
+		if post.Status == "" {
+			post.Status = models.StatusPublished
+		}
+
+		slug, err := store.uniqueSlug(ctx, q, &post)
+		if err != nil {
+			return err
+		}
+		if slug != p.Slug {
+			l.result.RenamedSlugs[p.Slug] = slug
+		}
+		if err := store.claimSlug(ctx, q, slug); err != nil {
+			return err
+		}
+		post.Slug = slug
+
+		id, err := d.insert(ctx, q, `
+			INSERT INTO posts (title, slug, content, format, user_id, category_id, status, publish_at, created_at, updated_at, deleted_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			post.Title, post.Slug, post.Content, string(post.Format), post.UserID, nullableID(post.CategoryID),
+			string(post.Status), nullableTime(post.PublishAt), post.CreatedAt.UTC(), post.UpdatedAt.UTC(), nullableTime(post.DeletedAt))
+		if err != nil {
+			return fmt.Errorf("创建文章 %s 失败: %w", p.Slug, err)
+		}
+		post.ID = int(id)
+
+		if err := store.saveTags(ctx, l.tx, &post); err != nil {
+			return err
+		}
+		if post.DeletedAt == nil {
+			if err := store.indexPost(ctx, q, &post); err != nil {
+				return err
+			}
+		}
+		l.posts[p.ID] = &post
+		l.result.Posts++
+	}
+	return nil
+}
+
+// loadRedirects 保存文章的旧别名，已被其他文章或旧别名占用的跳过
+func (l *sqlLoader) loadRedirects(ctx context.Context, redirects []*models.SlugRedirect) error {
+	q, d := l.tx, l.store.dialect
+	for _, redirect := range redirects {
+		post, ok := l.posts[redirect.PostID]
+		if !ok {
+			return fmt.Errorf("旧别名 %s 对应的文章不在导入数据中", redirect.Slug)
+		}
+
+		var count int
+		err := q.QueryRowContext(ctx, d.rebind(`
+			SELECT (SELECT COUNT(*) FROM posts WHERE slug = ?) + (SELECT COUNT(*) FROM post_slug_redirects WHERE slug = ?)
+		`), redirect.Slug, redirect.Slug).Scan(&count)
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			continue
+		}
+
+		_, err = q.ExecContext(ctx, d.rebind(`INSERT INTO post_slug_redirects (slug, post_id, created_at) VALUES (?, ?, ?)`),
+			redirect.Slug, post.ID, redirect.CreatedAt.UTC())
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadRevisions 保存文章的历史版本，修改者不在导入数据中时记为文章作者
+func (l *sqlLoader) loadRevisions(ctx context.Context, revisions []*models.Revision) error {
+	for _, revision := range revisions {
+		post, ok := l.posts[revision.PostID]
+		if !ok {
+			return fmt.Errorf("历史版本 %d 对应的文章不在导入数据中", revision.ID)
+		}
+		userID, ok := l.users[revision.UserID]
+		if !ok {
+			userID = post.UserID
+		}
+
+		_, err := l.store.dialect.insert(ctx, l.tx, `
+			INSERT INTO post_revisions (post_id, user_id, title, content, format, created_at)
+			VALUES (?, ?, ?, ?, ?, ?)`,
+			post.ID, userID, revision.Title, revision.Content, string(revision.Format), revision.CreatedAt.UTC())
+		if err != nil {
+			return err
+		}
+		l.result.Revisions++
+	}
+	return nil
+}
+
+// loadComments 保存评论，保持回复关系
+func (l *sqlLoader) loadComments(ctx context.Context, comments []*models.Comment) error {
+	l.comments = make(map[int]int, len(comments))
+	for _, comment := range comments {
+		post, ok := l.posts[comment.PostID]
+		if !ok {
+			return fmt.Errorf("评论 %d 对应的文章不在导入数据中", comment.ID)
+		}
+		userID, ok := l.users[comment.UserID]
+		if !ok {
+			return fmt.Errorf("评论 %d 的作者不在导入数据中", comment.ID)
+		}
+		var parentID int
+		if comment.ParentID != 0 {
+			if parentID, ok = l.comments[comment.ParentID]; !ok {
+				return fmt.Errorf("评论 %d 回复的评论不在导入数据中", comment.ID)
+			}
+		}
+
+		id, err := l.store.dialect.insert(ctx, l.tx, `
+			INSERT INTO comments (post_id, user_id, parent_id, content, created_at, updated_at, deleted_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			post.ID, userID, nullableID(parentID), comment.Content, comment.CreatedAt.UTC(), comment.UpdatedAt.UTC(), nullableTime(comment.DeletedAt))
+		if err != nil {
+			return err
+		}
+		l.comments[comment.ID] = int(id)
+		l.result.Comments++
+	}
+	return nil
+}
+
+// loadMedia 保存上传文件的记录，用户已有相同内容的文件时跳过
+func (l *sqlLoader) loadMedia(ctx context.Context, media []*models.Media) error {
+	q, d := l.tx, l.store.dialect
+	for _, m := range media {
+		userID, ok := l.users[m.UserID]
+		if !ok {
+			return fmt.Errorf("文件 %s 的上传者不在导入数据中", m.FileName)
+		}
+
+		var count int
+		err := q.QueryRowContext(ctx, d.rebind(`SELECT COUNT(*) FROM media WHERE user_id = ? AND hash = ?`), userID, m.Hash).Scan(&count)
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			continue
+		}
+
+		_, err = d.insert(ctx, q, `
+			INSERT INTO media (user_id, file_name, hash, mime_type, size, width, height, private, created_at, skipped_variants)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			userID, m.FileName, m.Hash, m.MIMEType, m.Size, m.Width, m.Height, m.Private, m.CreatedAt.UTC(),
+			strings.Join(m.SkippedVariants, " "))
+		if err != nil {
+			return err
+		}
+		l.result.Media++
+	}
+	return nil
+}
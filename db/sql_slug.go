@@ -0,0 +1,71 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"goblog/models"
+)
+
+// uniqueSlug 返回不与其他文章重复的别名，重复时依次尝试加上 -2、-3 等后缀。
+// 回收站中的文章仍然占用别名，以便恢复后链接不变
+func (s *SQLPostStore) uniqueSlug(ctx context.Context, q queryer, post *models.Post) (string, error) {
+	base := post.Slug
+	if base == "" {
+		base = models.MakeSlug(post.Title, models.SlugUnicode)
+	}
+
+	for n := 1; ; n++ {
+		slug := base
+		if n > 1 {
+			slug = fmt.Sprintf("%s-%d", base, n)
+		}
+
+		var count int
+		err := q.QueryRowContext(ctx, s.dialect.rebind(`SELECT COUNT(*) FROM posts WHERE slug = ? AND id <> ?`), slug, post.ID).Scan(&count)
+		if err != nil {
+			return "", err
+		}
+		if count == 0 {
+			return slug, nil
+		}
+	}
+}
+
+// claimSlug 为文章设置别名前调用：别名如果是某篇文章的旧别名，删除该重定向，
+// 保证旧别名与所有文章当前的别名都不重复
+func (s *SQLPostStore) claimSlug(ctx context.Context, q queryer, slug string) error {
+	_, err := q.ExecContext(ctx, s.dialect.rebind(`DELETE FROM post_slug_redirects WHERE slug = ?`), slug)
+	return err
+}
+
+// FindBySlug 根据别名查找文章，找不到时再查找旧别名
+func (s *SQLPostStore) FindBySlug(ctx context.Context, slug string) (*models.Post, error) {
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+
+	row := s.db.QueryRowContext(ctx, s.dialect.rebind(`
+		SELECT `+postColumns+`
+		FROM posts p
+		JOIN users u ON p.user_id = u.id
+		WHERE p.slug = ? AND p.deleted_at IS NULL
+	`), slug)
+
+	post, err := scanPost(row)
+	if errors.Is(err, ErrNotFound) {
+		var postID int
+		err := s.db.QueryRowContext(ctx, s.dialect.rebind(`SELECT post_id FROM post_slug_redirects WHERE slug = ?`), slug).Scan(&postID)
+		if err != nil {
+			return nil, translateError(err)
+		}
+		return s.FindByID(ctx, postID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.attachTaxonomy(ctx, []*models.Post{post}); err != nil {
+		return nil, err
+	}
+	return post, nil
+}
@@ -0,0 +1,388 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"goblog/models"
+	"log"
+	"strings"
+)
+
+// 编译期检查是否实现了标签和分类存储接口
+var _ models.TaxonomyStore = (*SQLPostStore)(nil)
+
+// nullableID 把表示“无”的0转换为NULL
+func nullableID(id int) interface{} {
+	if id == 0 {
+		return nil
+	}
+	return id
+}
+
+// placeholders 生成n个以逗号分隔的 ? 占位符，用于 IN 查询
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+// subcategoryPattern 生成匹配某个分类所有下级分类slug的LIKE模式
+func subcategoryPattern(slug string) string {
+	return likeEscaper.Replace(slug) + "/%"
+}
+
+// attachTaxonomy 为一组文章填充标签和分类，无论文章数量多少都只执行两次查询
+func (s *SQLPostStore) attachTaxonomy(ctx context.Context, posts []*models.Post) error {
+	if len(posts) == 0 {
+		return nil
+	}
+
+	byID := make(map[int]*models.Post, len(posts))
+	ids := make([]interface{}, 0, len(posts))
+	var categoryIDs []interface{}
+	seenCategory := make(map[int]bool)
+	for _, post := range posts {
+		post.Tags = []string{}
+		byID[post.ID] = post
+		ids = append(ids, post.ID)
+		if post.CategoryID != 0 && !seenCategory[post.CategoryID] {
+			seenCategory[post.CategoryID] = true
+			categoryIDs = append(categoryIDs, post.CategoryID)
+		}
+	}
+
+	rows, err := s.db.QueryContext(ctx, s.dialect.rebind(`
+		SELECT pt.post_id, t.name
+		FROM post_tags pt
+		JOIN tags t ON pt.tag_id = t.id
+		WHERE pt.post_id IN (`+placeholders(len(ids))+`)
+		ORDER BY t.name
+	`), ids...)
+	if err != nil {
+		log.Printf("查询文章标签失败: %v", err)
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var postID int
+		var name string
+		if err := rows.Scan(&postID, &name); err != nil {
+			return err
+		}
+		byID[postID].Tags = append(byID[postID].Tags, name)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if len(categoryIDs) == 0 {
+		return nil
+	}
+
+	rows, err = s.db.QueryContext(ctx, s.dialect.rebind(`
+		SELECT `+categoryColumns+`
+		FROM categories
+		WHERE id IN (`+placeholders(len(categoryIDs))+`)
+	`), categoryIDs...)
+	if err != nil {
+		log.Printf("查询文章分类失败: %v", err)
+		return err
+	}
+	defer rows.Close()
+
+	categories := make(map[int]*models.Category)
+	for rows.Next() {
+		category, err := scanCategory(rows)
+		if err != nil {
+			return err
+		}
+		categories[category.ID] = category
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, post := range posts {
+		post.Category = categories[post.CategoryID]
+	}
+	return nil
+}
+
+// saveTags 用post.Tags替换文章的标签，不存在的标签会被创建
+func (s *SQLPostStore) saveTags(ctx context.Context, tx *sql.Tx, post *models.Post) error {
+	if _, err := tx.ExecContext(ctx, s.dialect.rebind(`DELETE FROM post_tags WHERE post_id = ?`), post.ID); err != nil {
+		return err
+	}
+
+	for _, name := range post.Tags {
+		var tagID int64
+		err := tx.QueryRowContext(ctx, s.dialect.rebind(`SELECT id FROM tags WHERE name = ?`), name).Scan(&tagID)
+		if errors.Is(err, sql.ErrNoRows) {
+			tagID, err = s.dialect.insert(ctx, tx, `INSERT INTO tags (name) VALUES (?)`, name)
+		}
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, s.dialect.rebind(`INSERT INTO post_tags (post_id, tag_id) VALUES (?, ?)`), post.ID, tagID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Tags 查找所有至少有一篇文章的标签，按文章数倒序
+func (s *SQLPostStore) Tags(ctx context.Context) ([]*models.Tag, error) {
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT t.name, COUNT(p.id)
+		FROM tags t
+		JOIN post_tags pt ON pt.tag_id = t.id
+		JOIN posts p ON pt.post_id = p.id AND `+publishedCondition+`
+		GROUP BY t.id, t.name
+		ORDER BY COUNT(p.id) DESC, t.name
+	`)
+	if err != nil {
+		log.Printf("查询标签失败: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []*models.Tag{}
+	for rows.Next() {
+		var tag models.Tag
+		if err := rows.Scan(&tag.Name, &tag.PostCount); err != nil {
+			return nil, err
+		}
+		tags = append(tags, &tag)
+	}
+
+	return tags, rows.Err()
+}
+
+// FindTag 根据名称查找标签
+func (s *SQLPostStore) FindTag(ctx context.Context, name string) (*models.Tag, error) {
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+
+	tag := models.Tag{Name: models.NormalizeTag(name)}
+
+	var id int
+	err := s.db.QueryRowContext(ctx, s.dialect.rebind(`SELECT id FROM tags WHERE name = ?`), tag.Name).Scan(&id)
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	err = s.db.QueryRowContext(ctx, s.dialect.rebind(`
+		SELECT COUNT(*)
+		FROM post_tags pt
+		JOIN posts p ON pt.post_id = p.id AND `+publishedCondition+`
+		WHERE pt.tag_id = ?
+	`), id).Scan(&tag.PostCount)
+	if err != nil {
+		return nil, err
+	}
+
+	return &tag, nil
+}
+
+// categoryColumns 查询分类时选择的列，顺序与 scanCategory 一致
+const categoryColumns = `id, parent_id, name, path, slug`
+
+// scanCategory 扫描一行分类数据
+func scanCategory(row scanner) (*models.Category, error) {
+	var category models.Category
+	var parentID sql.NullInt64
+
+	if err := row.Scan(&category.ID, &parentID, &category.Name, &category.Path, &category.Slug); err != nil {
+		return nil, translateError(err)
+	}
+
+	category.ParentID = int(parentID.Int64)
+	category.Depth = strings.Count(category.Slug, "/")
+	return &category, nil
+}
+
+// Categories 查找所有分类，按树形结构深度优先排列，文章数包括下级分类中的文章
+func (s *SQLPostStore) Categories(ctx context.Context) ([]*models.Category, error) {
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, `SELECT `+categoryColumns+` FROM categories`)
+	if err != nil {
+		log.Printf("查询分类失败: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	categories := []*models.Category{}
+	byID := make(map[int]*models.Category)
+	for rows.Next() {
+		category, err := scanCategory(rows)
+		if err != nil {
+			return nil, err
+		}
+		categories = append(categories, category)
+		byID[category.ID] = category
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// 统计每个分类直接包含的文章，再累加到所有上级分类
+	rows, err = s.db.QueryContext(ctx, `
+		SELECT p.category_id, COUNT(*)
+		FROM posts p
+		WHERE p.category_id IS NOT NULL AND `+publishedCondition+`
+		GROUP BY p.category_id
+	`)
+	if err != nil {
+		log.Printf("统计分类文章数失败: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id, count int
+		if err := rows.Scan(&id, &count); err != nil {
+			return nil, err
+		}
+		for c := byID[id]; c != nil; c = byID[c.ParentID] {
+			c.PostCount += count
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return models.SortCategoryTree(categories), nil
+}
+
+// FindCategory 根据slug查找分类，文章数包括下级分类中的文章
+func (s *SQLPostStore) FindCategory(ctx context.Context, slug string) (*models.Category, error) {
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+
+	row := s.db.QueryRowContext(ctx, s.dialect.rebind(`SELECT `+categoryColumns+` FROM categories WHERE slug = ?`), slug)
+	category, err := scanCategory(row)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.db.QueryRowContext(ctx, s.dialect.rebind(`
+		SELECT COUNT(*)
+		FROM posts p
+		WHERE `+publishedCondition+` AND p.category_id IN (
+			SELECT id FROM categories
+			WHERE slug = ? OR slug LIKE ? ESCAPE '!')
+	`), slug, subcategoryPattern(slug)).Scan(&category.PostCount)
+	if err != nil {
+		return nil, err
+	}
+
+	return category, nil
+}
+
+// EnsureCategory 按路径查找分类，不存在的各级分类会被创建，path为空时返回nil
+func (s *SQLPostStore) EnsureCategory(ctx context.Context, path string) (*models.Category, error) {
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+
+	names := models.SplitCategoryPath(path)
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	var category *models.Category
+	err := withTx(ctx, s.db, func(tx *sql.Tx) error {
+		var err error
+		category, err = s.ensurePath(ctx, tx, names)
+		return err
+	})
+	if err != nil {
+		log.Printf("创建分类失败: %v", err)
+		return nil, err
+	}
+
+	return category, nil
+}
+
+// ensurePath 依次查找names中的各级分类，不存在的分类会被创建，返回最后一级分类
+func (s *SQLPostStore) ensurePath(ctx context.Context, q queryer, names []string) (*models.Category, error) {
+	var parent *models.Category
+	for _, name := range names {
+		next, _, err := s.ensureChild(ctx, q, parent, name)
+		if err != nil {
+			return nil, err
+		}
+		parent = next
+	}
+	return parent, nil
+}
+
+// resolveCategory 在文章的事务中把 post.Category 中按路径指定的新分类转换为已保存的分类，
+// 并设置 post.CategoryID。分类为nil或已有ID时不做任何处理，这样事务回滚时不会留下多余的分类
+func (s *SQLPostStore) resolveCategory(ctx context.Context, q queryer, post *models.Post) error {
+	if post.Category == nil || post.Category.ID != 0 {
+		return nil
+	}
+	category, err := s.ensurePath(ctx, q, models.SplitCategoryPath(post.Category.Path))
+	if err != nil {
+		return err
+	}
+	post.Category = category
+	post.CategoryID = 0
+	if category != nil {
+		post.CategoryID = category.ID
+	}
+	return nil
+}
+
+// ensureChild 查找parent下名为name的分类，不存在时创建，created表示是否新建了分类
+func (s *SQLPostStore) ensureChild(ctx context.Context, q queryer, parent *models.Category, name string) (category *models.Category, created bool, err error) {
+	next, err := categoryChild(parent, name)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// 以slug判断分类是否已存在，名称只有大小写或标点不同的分类视为同一个
+	row := q.QueryRowContext(ctx, s.dialect.rebind(`SELECT `+categoryColumns+` FROM categories WHERE slug = ?`), next.Slug)
+	existing, err := scanCategory(row)
+	switch {
+	case err == nil:
+		return existing, false, nil
+	case !errors.Is(err, ErrNotFound):
+		return nil, false, err
+	}
+
+	id, err := s.dialect.insert(ctx, q, `
+		INSERT INTO categories (parent_id, name, path, slug)
+		VALUES (?, ?, ?, ?)`,
+		nullableID(next.ParentID), next.Name, next.Path, next.Slug)
+	if err != nil {
+		return nil, false, err
+	}
+	next.ID = int(id)
+	return next, true, nil
+}
+
+// categoryChild 生成parent下名为name的分类，parent为nil时生成顶级分类
+func categoryChild(parent *models.Category, name string) (*models.Category, error) {
+	slug := models.Slugify(name)
+	if slug == "" {
+		return nil, fmt.Errorf("%w: 分类名必须包含文字或数字: %s", ErrInvalidCategory, name)
+	}
+
+	child := &models.Category{Name: name, Path: name, Slug: slug}
+	if parent != nil {
+		child.ParentID = parent.ID
+		child.Path = parent.Path + "/" + name
+		child.Slug = parent.Slug + "/" + slug
+		child.Depth = parent.Depth + 1
+	}
+	return child, nil
+}
@@ -0,0 +1,148 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"goblog/models"
+	"log"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// SQLUserStore 基于SQL数据库的用户存储
+type SQLUserStore struct {
+	db      *sql.DB
+	dialect *dialect
+	timeout time.Duration // 每次操作的超时，0表示不限制
+}
+
+// 编译期检查是否实现了用户存储接口
+var _ models.UserStore = (*SQLUserStore)(nil)
+
+// userColumns 查询用户时选择的列，顺序与 scanUser 一致
+const userColumns = `id, username, email, password, is_admin, created_at, updated_at`
+
+// scanUser 扫描一行用户数据
+func scanUser(row scanner) (*models.User, error) {
+	var user models.User
+
+	err := row.Scan(&user.ID, &user.Username, &user.Email, &user.Password, &user.IsAdmin, &user.CreatedAt, &user.UpdatedAt)
+	if err != nil {
+		return nil, translateError(err)
+	}
+
+	user.CreatedAt = user.CreatedAt.Local()
+	user.UpdatedAt = user.UpdatedAt.Local()
+
+	return &user, nil
+}
+
+// FindByID 根据ID查找用户
+func (s *SQLUserStore) FindByID(ctx context.Context, id int) (*models.User, error) {
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+
+	row := s.db.QueryRowContext(ctx, s.dialect.rebind(`SELECT `+userColumns+` FROM users WHERE id = ?`), id)
+	return scanUser(row)
+}
+
+// FindByUsername 根据用户名查找用户
+func (s *SQLUserStore) FindByUsername(ctx context.Context, username string) (*models.User, error) {
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+
+	row := s.db.QueryRowContext(ctx, s.dialect.rebind(`SELECT `+userColumns+` FROM users WHERE username = ?`), username)
+	return scanUser(row)
+}
+
+// FindByEmail 根据邮箱查找用户
+func (s *SQLUserStore) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+
+	row := s.db.QueryRowContext(ctx, s.dialect.rebind(`SELECT `+userColumns+` FROM users WHERE email = ?`), email)
+	return scanUser(row)
+}
+
+// Create 创建用户，用户名或邮箱已被使用时返回 *DuplicateError
+func (s *SQLUserStore) Create(ctx context.Context, user *models.User) error {
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+
+	// 对密码进行哈希处理
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	now := now()
+	id, err := s.dialect.insert(ctx, s.db, `
+		INSERT INTO users (username, email, password, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?)`,
+		user.Username, user.Email, string(hashedPassword), now, now)
+	if err != nil {
+		return err
+	}
+
+	user.ID = int(id)
+	user.CreatedAt = now.Local()
+	user.UpdatedAt = user.CreatedAt
+
+	return nil
+}
+
+// Update 更新用户，用户名或邮箱已被使用时返回 *DuplicateError
+func (s *SQLUserStore) Update(ctx context.Context, user *models.User) error {
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+
+	now := now()
+	result, err := s.db.ExecContext(ctx, s.dialect.rebind(`
+		UPDATE users
+		SET username = ?, email = ?, is_admin = ?, updated_at = ?
+		WHERE id = ?
+	`), user.Username, user.Email, user.IsAdmin, now, user.ID)
+	if err != nil {
+		return translateError(err)
+	}
+	if err := expectAffected(result); err != nil {
+		return err
+	}
+
+	user.UpdatedAt = now.Local()
+	return nil
+}
+
+// Delete 删除用户
+func (s *SQLUserStore) Delete(ctx context.Context, id int) error {
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, s.dialect.rebind("DELETE FROM users WHERE id = ?"), id)
+	return err
+}
+
+// Authenticate 认证用户
+func (s *SQLUserStore) Authenticate(ctx context.Context, username, password string) (*models.User, error) {
+	ctx, cancel := withTimeout(ctx, s.timeout)
+	defer cancel()
+
+	log.Printf("尝试验证用户: %s", username)
+	user, err := s.FindByUsername(ctx, username)
+	if err != nil {
+		log.Printf("查找用户错误: %v", err)
+		return nil, err
+	}
+
+	// 比较密码
+	// 密码错误与用户不存在返回相同的错误
+	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
+	if err != nil {
+		log.Printf("密码比较错误: %v", err)
+		return nil, ErrNotFound
+	}
+
+	log.Printf("用户验证成功: %s", user.Username)
+	return user, nil
+}
@@ -0,0 +1,108 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"goblog/config"
+	"goblog/models"
+)
+
+// Store 聚合应用所需的各类存储，具体实现由数据库类型决定
+type Store struct {
+	Posts    models.PostStore
+	Users    models.UserStore
+	Taxonomy models.TaxonomyStore
+	Comments models.CommentStore
+	Media    models.MediaStore
+	Site     models.SiteStore
+
+	// closeFunc 释放底层资源，可以为空
+	closeFunc func() error
+
+	// snapshotFunc 写入数据库快照，不支持在线备份的数据库为空
+	snapshotFunc func(ctx context.Context, path string) error
+}
+
+// ErrSnapshotUnsupported 表示当前数据库不支持在线备份，只有SQLite支持
+var ErrSnapshotUnsupported = errors.New("只有SQLite数据库支持在线备份")
+
+// CanSnapshot 判断数据库是否支持在线备份
+func (s *Store) CanSnapshot() bool {
+	return s.snapshotFunc != nil
+}
+
+// Snapshot 把数据库的一致性快照写入新文件path
+func (s *Store) Snapshot(ctx context.Context, path string) error {
+	if s.snapshotFunc == nil {
+		return ErrSnapshotUnsupported
+	}
+	return s.snapshotFunc(ctx, path)
+}
+
+// EnableCache 在文章存储前加上读取缓存，分类存储和整站导入同样会修改文章相关的数据，
+// 也经过缓存并在修改后清空。只用于服务进程，命令行工具直接读写数据库
+func (s *Store) EnableCache(cfg config.CacheConfig) {
+	cache := NewCachedPostStore(s.Posts, cfg)
+	s.Posts = cache
+	s.Taxonomy = &cachedTaxonomyStore{TaxonomyStore: s.Taxonomy, cache: cache}
+	s.Site = &cachedSiteStore{SiteStore: s.Site, cache: cache}
+}
+
+// Close 关闭底层连接
+func (s *Store) Close() error {
+	if s.closeFunc == nil {
+		return nil
+	}
+	return s.closeFunc()
+}
+
+// Open 根据数据库配置打开对应的存储实现
+func Open(cfg config.DatabaseConfig) (*Store, error) {
+	if cfg.Type == "memory" {
+		// 演示模式：数据只保存在进程内存中
+		users := NewMemoryUserStore()
+		posts := NewMemoryPostStore(users)
+		comments := NewMemoryCommentStore(users, posts)
+		media := NewMemoryMediaStore()
+		return &Store{
+			Posts:    posts,
+			Users:    users,
+			Taxonomy: posts,
+			Comments: comments,
+			Media:    media,
+			Site:     NewMemorySiteStore(users, posts, comments, media),
+		}, nil
+	}
+
+	store, err := NewSQLStore(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &Store{
+		Posts:     store.Posts(),
+		Users:     store.Users(),
+		Taxonomy:  store.Posts(),
+		Comments:  store.Comments(),
+		Media:     store.Media(),
+		Site:      store.Site(),
+		closeFunc: store.Close,
+
+		snapshotFunc: store.Snapshot,
+	}, nil
+}
+
+// OpenSQL 打开配置对应的数据库连接但不执行迁移，返回连接和迁移方言名称，供命令行工具使用
+func OpenSQL(cfg config.DatabaseConfig) (*sql.DB, string, error) {
+	if cfg.Type == "memory" {
+		return nil, "", errors.New("内存数据库没有持久化的数据库结构")
+	}
+
+	d, err := dialectFor(cfg.Type)
+	if err != nil {
+		return nil, "", err
+	}
+
+	db, err := openSQL(d, cfg)
+	return db, d.name, err
+}
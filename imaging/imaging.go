@@ -0,0 +1,265 @@
+// Package imaging 处理上传的图片：校正EXIF方向、删除元数据，生成不同宽度的缩放版本和缩略图。
+// 全部使用纯Go实现，不依赖cgo和外部程序
+package imaging
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"goblog/config"
+	"image"
+	"image/jpeg"
+	"runtime"
+	"sort"
+	"strconv"
+
+	// 注册解码器，用于解码上传的图片
+	_ "image/gif"
+	_ "image/png"
+
+	_ "golang.org/x/image/webp"
+)
+
+// orientedQuality 校正方向时重新编码JPEG原图使用的质量，尽量减少画质损失
+const orientedQuality = 92
+
+// DefaultMaxPixels 没有配置 MaxPixels 时允许的最大像素数，解码为RGBA约需160MB内存
+const DefaultMaxPixels = 40000000
+
+// ErrTooManyPixels 表示图片的像素数超过 MaxPixels，在解码图片数据之前检查
+var ErrTooManyPixels = errors.New("图片尺寸过大")
+
+// Variant 生成的一个图片版本
+type Variant struct {
+	Name     string // 版本名称，缩放版本为宽度加w，如 960w，缩略图为 thumb
+	Width    int
+	Height   int
+	MIMEType string
+	Data     []byte
+}
+
+// Processor 按配置处理上传的图片
+type Processor struct {
+	cfg   config.ImageConfig
+	slots chan struct{} // 同时生成图片版本的数量限制，每个图片占用一个
+}
+
+// NewProcessor 检查配置并创建图片处理器
+func NewProcessor(cfg config.ImageConfig) (*Processor, error) {
+	switch cfg.Format {
+	case "auto", "jpeg", "webp":
+	default:
+		return nil, fmt.Errorf("不支持的图片格式 %q，只能使用 auto、jpeg 或 webp", cfg.Format)
+	}
+	if cfg.Quality < 1 || cfg.Quality > 100 {
+		return nil, errors.New("图片质量必须在1到100之间")
+	}
+	if cfg.ThumbSize < 1 {
+		return nil, errors.New("缩略图边长必须大于0")
+	}
+	for _, width := range cfg.Widths {
+		if width < 1 || width > 1<<14 {
+			return nil, fmt.Errorf("无效的图片宽度 %d", width)
+		}
+	}
+
+	if cfg.MaxPixels <= 0 {
+		cfg.MaxPixels = DefaultMaxPixels
+	}
+	if cfg.Workers <= 0 {
+		cfg.Workers = runtime.NumCPU()
+	}
+
+	widths := append([]int(nil), cfg.Widths...)
+	sort.Ints(widths)
+	cfg.Widths = widths
+	return &Processor{cfg: cfg, slots: make(chan struct{}, cfg.Workers)}, nil
+}
+
+// MaxPixels 返回允许处理的图片最大像素数
+func (p *Processor) MaxPixels() int {
+	return p.cfg.MaxPixels
+}
+
+// checkSize 只读取图片头部的宽高，像素数超过 MaxPixels 时返回 ErrTooManyPixels
+func (p *Processor) checkSize(data []byte) (image.Config, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return cfg, err
+	}
+	if int64(cfg.Width)*int64(cfg.Height) > int64(p.cfg.MaxPixels) {
+		return cfg, fmt.Errorf("%w: %d×%d", ErrTooManyPixels, cfg.Width, cfg.Height)
+	}
+	return cfg, nil
+}
+
+// HasVariants 判断该类型的图片是否生成缩放版本，GIF可能是动画，保留原图
+func (p *Processor) HasVariants(mimeType string) bool {
+	switch mimeType {
+	case "image/jpeg", "image/png", "image/webp":
+		return true
+	}
+	return false
+}
+
+// VariantType 缩放版本的类型，只由原图类型和配置决定，生成页面时不需要读取图片
+func (p *Processor) VariantType(mimeType string) string {
+	switch p.cfg.Format {
+	case "jpeg":
+		return "image/jpeg"
+	case "webp":
+		return "image/webp"
+	}
+	// 照片使用有损的JPEG体积更小，截图和透明图片使用无损的WebP
+	if mimeType == "image/jpeg" {
+		return "image/jpeg"
+	}
+	return "image/webp"
+}
+
+// Widths 返回宽度为width的原图会生成的缩放版本宽度，从小到大
+func (p *Processor) Widths(width int) []int {
+	var widths []int
+	for _, w := range p.cfg.Widths {
+		if w < width && (len(widths) == 0 || widths[len(widths)-1] != w) {
+			widths = append(widths, w)
+		}
+	}
+	return widths
+}
+
+// VariantNames 返回宽度为width的原图会生成的所有版本名称
+func (p *Processor) VariantNames(width int) []string {
+	var names []string
+	for _, w := range p.Widths(width) {
+		names = append(names, WidthName(w))
+	}
+	return append(names, ThumbName)
+}
+
+// ThumbName 缩略图的版本名称
+const ThumbName = "thumb"
+
+// WidthName 缩放版本的名称，如 960w
+func WidthName(width int) string {
+	return strconv.Itoa(width) + "w"
+}
+
+// Clean 校正JPEG图片的EXIF方向并删除图片中的元数据，如拍摄地点、设备信息。
+// 方向正常的图片只删除元数据段，不重新编码；返回处理后的内容和宽高。
+// 像素数超过 MaxPixels 的图片在解码前拒绝，返回 ErrTooManyPixels
+func (p *Processor) Clean(data []byte, mimeType string) ([]byte, int, int, error) {
+	if _, err := p.checkSize(data); err != nil {
+		return nil, 0, 0, err
+	}
+
+	switch mimeType {
+	case "image/jpeg":
+		if orientation := jpegOrientation(data); orientation != 1 {
+			img, err := jpeg.Decode(bytes.NewReader(data))
+			if err != nil {
+				return nil, 0, 0, err
+			}
+			img = orient(img, orientation)
+
+			var buf bytes.Buffer
+			if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: orientedQuality}); err != nil {
+				return nil, 0, 0, err
+			}
+			bounds := img.Bounds()
+			return buf.Bytes(), bounds.Dx(), bounds.Dy(), nil
+		}
+		data = stripJPEG(data)
+	case "image/png":
+		data = stripPNG(data)
+	case "image/webp":
+		data = stripWebP(data)
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	return data, cfg.Width, cfg.Height, nil
+}
+
+// Variants 生成图片的缩放版本和缩略图，GIF等不生成版本的类型返回空列表。
+// 编码后不小于原图的版本不返回，名称列在 skipped 中，页面中改用原图。
+// 同时生成的数量不超过 Workers，等待期间 ctx 结束时返回 ctx.Err()
+func (p *Processor) Variants(ctx context.Context, data []byte, mimeType string) (variants []Variant, skipped []string, err error) {
+	if !p.HasVariants(mimeType) {
+		return nil, nil, nil
+	}
+	if _, err := p.checkSize(data); err != nil {
+		return nil, nil, err
+	}
+
+	select {
+	case p.slots <- struct{}{}:
+		defer func() { <-p.slots }()
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, err
+	}
+	// Clean 处理后的JPEG方向已经正常，这里兼容在此之前上传的原图
+	if mimeType == "image/jpeg" {
+		img = orient(img, jpegOrientation(data))
+	}
+	img = toRGBA(img)
+	outputType := p.VariantType(mimeType)
+
+	// keep 保留比原图小的版本
+	keep := func(variant Variant) {
+		if len(variant.Data) < len(data) {
+			variants = append([]Variant{variant}, variants...)
+		} else {
+			skipped = append([]string{variant.Name}, skipped...)
+		}
+	}
+
+	variant, err := p.encode(ThumbName, thumbnail(img, p.cfg.ThumbSize), outputType)
+	if err != nil {
+		return nil, nil, err
+	}
+	keep(variant)
+
+	// 从大到小生成，较小的版本由上一个版本缩放得到，减少计算量
+	widths := p.Widths(img.Bounds().Dx())
+	for i := len(widths) - 1; i >= 0; i-- {
+		img = resize(img, widths[i])
+		variant, err := p.encode(WidthName(widths[i]), img, outputType)
+		if err != nil {
+			return nil, nil, err
+		}
+		keep(variant)
+	}
+	return variants, skipped, nil
+}
+
+// encode 按输出类型编码一个版本
+func (p *Processor) encode(name string, img image.Image, mimeType string) (Variant, error) {
+	var buf bytes.Buffer
+	var err error
+	if mimeType == "image/jpeg" {
+		err = jpeg.Encode(&buf, flatten(img), &jpeg.Options{Quality: p.cfg.Quality})
+	} else {
+		err = EncodeWebP(&buf, img)
+	}
+	if err != nil {
+		return Variant{}, fmt.Errorf("生成图片版本 %s 失败: %v", name, err)
+	}
+
+	bounds := img.Bounds()
+	return Variant{
+		Name:     name,
+		Width:    bounds.Dx(),
+		Height:   bounds.Dy(),
+		MIMEType: mimeType,
+		Data:     buf.Bytes(),
+	}, nil
+}
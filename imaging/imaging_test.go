@@ -0,0 +1,178 @@
+package imaging
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"goblog/config"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func TestProcessorRejectsTooManyPixels(t *testing.T) {
+	p, err := NewProcessor(config.ImageConfig{Widths: []int{8}, ThumbSize: 4, Format: "auto", Quality: 82, MaxPixels: 400})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		width   int
+		height  int
+		wantErr bool
+	}{
+		{"小于上限", 10, 10, false},
+		{"等于上限", 20, 20, false},
+		{"超过上限", 21, 20, true},
+		{"细长图片", 401, 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, tt.width, tt.height))); err != nil {
+				t.Fatal(err)
+			}
+
+			_, _, _, err := p.Clean(buf.Bytes(), "image/png")
+			if got := errors.Is(err, ErrTooManyPixels); got != tt.wantErr {
+				t.Errorf("Clean 返回 %v", err)
+			}
+			_, _, err = p.Variants(context.Background(), buf.Bytes(), "image/png")
+			if got := errors.Is(err, ErrTooManyPixels); got != tt.wantErr {
+				t.Errorf("Variants 返回 %v", err)
+			}
+		})
+	}
+}
+
+func TestVariantsSkipLargerThanOriginal(t *testing.T) {
+	// 低质量的JPEG原图很小，用高质量重新编码的版本比原图大
+	var original bytes.Buffer
+	if err := jpeg.Encode(&original, noise(600, 400, 8, false), &jpeg.Options{Quality: 5}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		quality     int
+		wantNames   []string
+		wantSkipped []string
+	}{
+		{5, []string{"120w", "480w", "thumb"}, nil},
+		{100, []string{"120w", "thumb"}, []string{"480w"}},
+	}
+	for _, tt := range tests {
+		p, err := NewProcessor(config.ImageConfig{Widths: []int{480, 120, 1600}, ThumbSize: 100, Format: "auto", Quality: tt.quality})
+		if err != nil {
+			t.Fatal(err)
+		}
+		variants, skipped, err := p.Variants(context.Background(), original.Bytes(), "image/jpeg")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var names []string
+		for _, v := range variants {
+			names = append(names, v.Name)
+			if len(v.Data) >= original.Len() {
+				t.Errorf("质量 %d: 版本 %s 为 %d 字节，不小于原图的 %d 字节", tt.quality, v.Name, len(v.Data), original.Len())
+			}
+		}
+		if fmt.Sprint(names) != fmt.Sprint(tt.wantNames) || fmt.Sprint(skipped) != fmt.Sprint(tt.wantSkipped) {
+			t.Errorf("质量 %d: 生成 %v，跳过 %v，期望生成 %v，跳过 %v", tt.quality, names, skipped, tt.wantNames, tt.wantSkipped)
+		}
+	}
+}
+
+func TestVariantsCanceledWhileWaiting(t *testing.T) {
+	p, err := NewProcessor(config.ImageConfig{ThumbSize: 4, Format: "auto", Quality: 82, Workers: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 8, 8))); err != nil {
+		t.Fatal(err)
+	}
+
+	// 占用唯一的名额，等待中的请求在 ctx 结束时返回
+	p.slots <- struct{}{}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, err := p.Variants(ctx, buf.Bytes(), "image/png"); !errors.Is(err, context.Canceled) {
+		t.Errorf("返回 %v，期望 context.Canceled", err)
+	}
+
+	<-p.slots
+	if _, _, err := p.Variants(context.Background(), buf.Bytes(), "image/png"); err != nil {
+		t.Errorf("释放名额后返回 %v", err)
+	}
+}
+
+func TestNewProcessorDefaultMaxPixels(t *testing.T) {
+	p, err := NewProcessor(config.ImageConfig{ThumbSize: 4, Format: "auto", Quality: 82})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.MaxPixels() != DefaultMaxPixels {
+		t.Errorf("MaxPixels 为 %d，期望 %d", p.MaxPixels(), DefaultMaxPixels)
+	}
+}
+
+// riffChunk 编码一个WebP数据块，奇数长度补齐一个字节
+func riffChunk(fourCC string, payload []byte) []byte {
+	chunk := append([]byte(fourCC), byte(len(payload)), byte(len(payload)>>8), byte(len(payload)>>16), byte(len(payload)>>24))
+	chunk = append(chunk, payload...)
+	if len(payload)%2 == 1 {
+		chunk = append(chunk, 0)
+	}
+	return chunk
+}
+
+func TestStripWebP(t *testing.T) {
+	img := fill(3, 2, func(x, y int) color.NRGBA { return color.NRGBA{uint8(x * 80), uint8(y * 100), 7, 0xff} })
+	var simple bytes.Buffer
+	if err := EncodeWebP(&simple, img); err != nil {
+		t.Fatal(err)
+	}
+	bitstream := simple.Bytes()[20:]
+
+	// 扩展格式：VP8X 标志中设置EXIF和XMP，宽高减一以24位小端保存
+	vp8x := []byte{0x08 | 0x04, 0, 0, 0, 2, 0, 0, 1, 0, 0}
+	var body []byte
+	body = append(body, "WEBP"...)
+	body = append(body, riffChunk("VP8X", vp8x)...)
+	body = append(body, riffChunk("VP8L", bitstream)...)
+	body = append(body, riffChunk("EXIF", []byte("Exif\x00\x00GPS secret"))...)
+	body = append(body, riffChunk("XMP ", []byte("<x:xmpmeta>secret</x:xmpmeta>"))...)
+	data := append([]byte("RIFF"), byte(len(body)), byte(len(body)>>8), byte(len(body)>>16), byte(len(body)>>24))
+	data = append(data, body...)
+
+	stripped := stripWebP(data)
+	if bytes.Contains(stripped, []byte("secret")) || bytes.Contains(stripped, []byte("EXIF")) {
+		t.Fatal("元数据没有被删除")
+	}
+	if flags := stripped[20]; flags&(0x08|0x04) != 0 {
+		t.Errorf("VP8X 标志 %#x 仍包含EXIF或XMP", flags)
+	}
+	if size := int(stripped[4]) | int(stripped[5])<<8 | int(stripped[6])<<16 | int(stripped[7])<<24; size != len(stripped)-8 {
+		t.Errorf("RIFF 长度为 %d，期望 %d", size, len(stripped)-8)
+	}
+	if !bytes.Contains(stripped, bitstream) {
+		t.Error("图像数据被修改")
+	}
+	if data[20] != 0x08|0x04 {
+		t.Error("修改了输入的内容")
+	}
+
+	// 简单格式和无法解析的内容原样返回
+	if got := stripWebP(simple.Bytes()); !bytes.Equal(got, simple.Bytes()) {
+		t.Error("简单格式的文件被修改")
+	}
+	truncated := data[:len(data)-3]
+	if got := stripWebP(truncated); !bytes.Equal(got, truncated) {
+		t.Error("截断的文件被修改")
+	}
+}
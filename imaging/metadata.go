@@ -0,0 +1,181 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// jpegOrientation 读取JPEG文件EXIF中的方向标签，没有或无法解析时返回1（正常方向）
+func jpegOrientation(data []byte) int {
+	orientation := 1
+	walkJPEG(data, func(marker byte, segment []byte) bool {
+		if marker != 0xe1 || !bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return true
+		}
+		orientation = exifOrientation(segment[6:])
+		return false
+	})
+	return orientation
+}
+
+// exifOrientation 在TIFF结构的第一个IFD中查找方向标签（0x0112）
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:4]) {
+	case "II*\x00":
+		order = binary.LittleEndian
+	case "MM\x00*":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	offset := int(order.Uint32(tiff[4:8]))
+	if offset < 8 || offset+2 > len(tiff) {
+		return 1
+	}
+	count := int(order.Uint16(tiff[offset:]))
+	for i := 0; i < count; i++ {
+		entry := offset + 2 + 12*i
+		if entry+12 > len(tiff) {
+			break
+		}
+		// 方向标签的类型是SHORT，值直接保存在条目的最后4个字节中
+		if order.Uint16(tiff[entry:]) == 0x0112 && order.Uint16(tiff[entry+2:]) == 3 {
+			value := int(order.Uint16(tiff[entry+8:]))
+			if value >= 1 && value <= 8 {
+				return value
+			}
+			return 1
+		}
+	}
+	return 1
+}
+
+// stripJPEG 删除JPEG文件中的EXIF、XMP（APP1）、IPTC（APP13）和注释段，
+// 保留JFIF、ICC色彩配置（APP2）和Adobe（APP14）等影响显示的段，图像数据不重新编码
+func stripJPEG(data []byte) []byte {
+	out := make([]byte, 0, len(data))
+	out = append(out, 0xff, 0xd8)
+
+	rest := 2
+	ok := walkJPEG(data, func(marker byte, segment []byte) bool {
+		start := rest
+		rest += 4 + len(segment)
+		if marker == 0xe1 || marker == 0xed || marker == 0xfe {
+			return true
+		}
+		out = append(out, data[start:rest]...)
+		return true
+	})
+	if !ok {
+		return data
+	}
+
+	// 扫描数据开始后不再有元数据段，剩余部分原样保留
+	return append(out, data[rest:]...)
+}
+
+// walkJPEG 依次访问JPEG文件中扫描数据之前的标记段，fn 返回 false 时停止。
+// 文件结构无法解析时返回 false
+func walkJPEG(data []byte, fn func(marker byte, segment []byte) bool) bool {
+	if len(data) < 4 || data[0] != 0xff || data[1] != 0xd8 {
+		return false
+	}
+
+	for p := 2; ; {
+		if p+4 > len(data) || data[p] != 0xff {
+			return false
+		}
+		marker := data[p+1]
+		if marker == 0xda {
+			return true
+		}
+		length := int(binary.BigEndian.Uint16(data[p+2:]))
+		if length < 2 || p+2+length > len(data) {
+			return false
+		}
+		if !fn(marker, data[p+4:p+2+length]) {
+			return true
+		}
+		p += 2 + length
+	}
+}
+
+// pngMetadataChunks 会被删除的PNG数据块：文本、EXIF和修改时间
+var pngMetadataChunks = map[string]bool{
+	"tEXt": true,
+	"zTXt": true,
+	"iTXt": true,
+	"eXIf": true,
+	"tIME": true,
+}
+
+// stripPNG 删除PNG文件中的文本和EXIF数据块，其他数据块原样保留
+func stripPNG(data []byte) []byte {
+	const signature = "\x89PNG\r\n\x1a\n"
+	if !bytes.HasPrefix(data, []byte(signature)) {
+		return data
+	}
+
+	out := make([]byte, 0, len(data))
+	out = append(out, signature...)
+	for p := len(signature); p < len(data); {
+		if p+12 > len(data) {
+			return data
+		}
+		length := int(binary.BigEndian.Uint32(data[p:]))
+		end := p + 12 + length
+		if length < 0 || end > len(data) || end < p {
+			return data
+		}
+		if !pngMetadataChunks[string(data[p+4:p+8])] {
+			out = append(out, data[p:end]...)
+		}
+		p = end
+	}
+	return out
+}
+
+// stripWebP 删除扩展格式（VP8X）WebP文件中的EXIF和XMP数据块并清除对应的标志位，
+// ICC色彩配置、动画和图像数据原样保留；简单格式的文件没有元数据，原样返回
+func stripWebP(data []byte) []byte {
+	if len(data) < 12 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return data
+	}
+
+	out := make([]byte, 12, len(data))
+	copy(out, data[:12])
+	for p := 12; p < len(data); {
+		if p+8 > len(data) {
+			return data
+		}
+		fourCC := string(data[p : p+4])
+		size := int(binary.LittleEndian.Uint32(data[p+4:]))
+		end := p + 8 + size + size%2
+		if size < 0 || end > len(data) || end < p {
+			return data
+		}
+
+		switch fourCC {
+		case "EXIF", "XMP ":
+		case "VP8X":
+			start := len(out)
+			out = append(out, data[p:end]...)
+			if size > 0 {
+				// 标志位：0x08 为EXIF，0x04 为XMP
+				out[start+8] &^= 0x08 | 0x04
+			}
+		default:
+			out = append(out, data[p:end]...)
+		}
+		p = end
+	}
+
+	binary.LittleEndian.PutUint32(out[4:], uint32(len(out)-8))
+	return out
+}
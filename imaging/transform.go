@@ -0,0 +1,131 @@
+package imaging
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+
+	xdraw "golang.org/x/image/draw"
+)
+
+// orient 按EXIF方向标签旋转或翻转图片，使其以正常方向显示
+func orient(src image.Image, orientation int) image.Image {
+	if orientation < 2 || orientation > 8 {
+		return src
+	}
+
+	bounds := src.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	pixels := image.NewNRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(pixels, pixels.Rect, src, bounds.Min, draw.Src)
+
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // 水平翻转
+				dx, dy = w-1-x, y
+			case 3: // 旋转180度
+				dx, dy = w-1-x, h-1-y
+			case 4: // 垂直翻转
+				dx, dy = x, h-1-y
+			case 5: // 沿左上到右下的对角线翻转
+				dx, dy = y, x
+			case 6: // 顺时针旋转90度
+				dx, dy = h-1-y, x
+			case 7: // 沿右上到左下的对角线翻转
+				dx, dy = h-1-y, w-1-x
+			case 8: // 逆时针旋转90度
+				dx, dy = y, w-1-x
+			}
+			copy(dst.Pix[dst.PixOffset(dx, dy):][:4], pixels.Pix[pixels.PixOffset(x, y):][:4])
+		}
+	}
+	return dst
+}
+
+// toRGBA 把图片转换为RGBA格式，缩放RGBA图片比缩放JPEG解码得到的YCbCr图片快得多
+func toRGBA(src image.Image) *image.RGBA {
+	if rgba, ok := src.(*image.RGBA); ok {
+		return rgba
+	}
+	bounds := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(dst, dst.Rect, src, bounds.Min, draw.Src)
+	return dst
+}
+
+// resize 把图片等比缩放到指定宽度。远大于目标的图片先用2×2平均快速缩小，
+// 插值缩放的计算量与原图面积和缩小倍数都成正比
+func resize(src image.Image, width int) image.Image {
+	for src.Bounds().Dx() >= 2*width && src.Bounds().Dy() >= 2 {
+		src = halve(toRGBA(src))
+	}
+
+	bounds := src.Bounds()
+	height := (bounds.Dy()*width + bounds.Dx()/2) / bounds.Dx()
+	if height < 1 {
+		height = 1
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	xdraw.CatmullRom.Scale(dst, dst.Rect, src, bounds, xdraw.Src, nil)
+	return dst
+}
+
+// halve 把图片缩小一半，每个像素取原图2×2像素的平均值
+func halve(src *image.RGBA) *image.RGBA {
+	bounds := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, bounds.Dx()/2, bounds.Dy()/2))
+	for y := 0; y < dst.Rect.Dy(); y++ {
+		top := src.PixOffset(bounds.Min.X, bounds.Min.Y+2*y)
+		bottom := top + src.Stride
+		out := dst.PixOffset(0, y)
+		for x := 0; x < dst.Rect.Dx(); x++ {
+			for c := 0; c < 4; c++ {
+				i := 8*x + c
+				sum := int(src.Pix[top+i]) + int(src.Pix[top+i+4]) + int(src.Pix[bottom+i]) + int(src.Pix[bottom+i+4])
+				dst.Pix[out+4*x+c] = uint8((sum + 2) / 4)
+			}
+		}
+	}
+	return dst
+}
+
+// thumbnail 从图片中间裁出正方形并缩放到指定边长，图片较小时不放大
+func thumbnail(src image.Image, size int) image.Image {
+	bounds := src.Bounds()
+	side := bounds.Dx()
+	if bounds.Dy() < side {
+		side = bounds.Dy()
+	}
+	if size > side {
+		size = side
+	}
+
+	x := bounds.Min.X + (bounds.Dx()-side)/2
+	y := bounds.Min.Y + (bounds.Dy()-side)/2
+	src = toRGBA(src).SubImage(image.Rect(x, y, x+side, y+side))
+	for side >= 2*size {
+		src = halve(src.(*image.RGBA))
+		side = src.Bounds().Dx()
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+	xdraw.CatmullRom.Scale(dst, dst.Rect, src, src.Bounds(), xdraw.Src, nil)
+	return dst
+}
+
+// flatten 把透明图片合成到白色背景上，用于输出不支持透明的JPEG
+func flatten(src image.Image) image.Image {
+	bounds := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(dst, dst.Rect, image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(dst, dst.Rect, src, bounds.Min, draw.Over)
+	return dst
+}
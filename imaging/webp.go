@@ -0,0 +1,593 @@
+package imaging
+
+import (
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/draw"
+	"io"
+)
+
+// EncodeWebP 把图片编码为无损WebP（VP8L）。
+// 只使用减绿、预测变换和简单的重复像素引用，压缩率低于libwebp，但不依赖cgo
+func EncodeWebP(w io.Writer, img image.Image) error {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width < 1 || height < 1 || width > 1<<14 || height > 1<<14 {
+		return errors.New("WebP图片的宽和高必须在1到16384之间")
+	}
+
+	nrgba := image.NewNRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(nrgba, nrgba.Rect, img, bounds.Min, draw.Src)
+	pix := nrgba.Pix
+
+	alphaUsed := uint32(0)
+	for p := 3; p < len(pix); p += 4 {
+		if pix[p] != 0xff {
+			alphaUsed = 1
+			break
+		}
+	}
+
+	bw := &bitWriter{}
+	bw.write(0x2f, 8)
+	bw.write(uint32(width-1), 14)
+	bw.write(uint32(height-1), 14)
+	bw.write(alphaUsed, 1)
+	bw.write(0, 3)
+
+	// 减绿变换：红和蓝减去绿，去掉三个通道共同的亮度变化
+	for p := 0; p < len(pix); p += 4 {
+		pix[p+0] -= pix[p+1]
+		pix[p+2] -= pix[p+1]
+	}
+	bw.write(1, 1)
+	bw.write(transformSubtractGreen, 2)
+
+	// 预测变换：每个区块选择残差最小的预测方式，保存残差
+	modes, residuals := predict(pix, width, height, predictorBits)
+	bw.write(1, 1)
+	bw.write(transformPredictor, 2)
+	bw.write(predictorBits-2, 3)
+	writeImage(bw, modes, (width+1<<predictorBits-1)>>predictorBits, false)
+
+	bw.write(0, 1)
+	writeImage(bw, residuals, width, true)
+
+	data := bw.bytes()
+	header := make([]byte, 20)
+	copy(header[0:], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:], uint32(12+len(data)+len(data)%2))
+	copy(header[8:], "WEBPVP8L")
+	binary.LittleEndian.PutUint32(header[16:], uint32(len(data)))
+	if len(data)%2 == 1 {
+		data = append(data, 0)
+	}
+
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	_, err := w.Write(data)
+	return err
+}
+
+const (
+	transformPredictor     = 0
+	transformSubtractGreen = 2
+
+	// predictorBits 预测区块边长的对数，区块为32×32像素
+	predictorBits = 5
+)
+
+// predictorModes 选择预测方式时尝试的模式，编号见WebP无损格式规范
+var predictorModes = []int{1, 2, 5, 7, 11, 12, 13}
+
+// predict 执行预测变换，返回每个区块的预测方式（保存在绿色通道）和残差
+func predict(pix []byte, width, height int, bits uint) (modes []byte, residuals []byte) {
+	tilesX := (width + 1<<bits - 1) >> bits
+	tilesY := (height + 1<<bits - 1) >> bits
+	tileModes := make([]int, tilesX*tilesY)
+
+	// 第一行和第一列的预测方式是固定的，只比较其余像素的残差
+	for ty := 0; ty < tilesY; ty++ {
+		for tx := 0; tx < tilesX; tx++ {
+			best, bestCost := predictorModes[0], -1
+			for _, mode := range predictorModes {
+				cost := 0
+				for y := maxInt(ty<<bits, 1); y < minInt((ty+1)<<bits, height); y++ {
+					for x := maxInt(tx<<bits, 1); x < minInt((tx+1)<<bits, width); x++ {
+						p := 4 * (y*width + x)
+						pred := predictPixel(mode, pix, p, p-4*width)
+						for c := 0; c < 4; c++ {
+							cost += absInt8(pix[p+c] - pred[c])
+						}
+					}
+				}
+				if bestCost < 0 || cost < bestCost {
+					best, bestCost = mode, cost
+				}
+			}
+			tileModes[ty*tilesX+tx] = best
+		}
+	}
+
+	residuals = make([]byte, len(pix))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			p := 4 * (y*width + x)
+			var pred [4]byte
+			switch {
+			case x == 0 && y == 0:
+				pred = [4]byte{0, 0, 0, 0xff}
+			case y == 0:
+				pred = predictPixel(1, pix, p, 0)
+			case x == 0:
+				pred = predictPixel(2, pix, p, p-4*width)
+			default:
+				pred = predictPixel(tileModes[(y>>bits)*tilesX+x>>bits], pix, p, p-4*width)
+			}
+			for c := 0; c < 4; c++ {
+				residuals[p+c] = pix[p+c] - pred[c]
+			}
+		}
+	}
+
+	modes = make([]byte, 4*len(tileModes))
+	for i, mode := range tileModes {
+		modes[4*i+1] = byte(mode)
+		modes[4*i+3] = 0xff
+	}
+	return modes, residuals
+}
+
+// predictPixel 按预测方式计算位置p的预测值，top为上方像素的位置。
+// 计算方式与解码器完全一致，最右列的右上像素是当前行的第一个像素
+func predictPixel(mode int, pix []byte, p, top int) [4]byte {
+	var pred [4]byte
+	for c := 0; c < 4; c++ {
+		l, t := pix[p-4+c], pix[top+c]
+		switch mode {
+		case 1:
+			pred[c] = l
+		case 2:
+			pred[c] = t
+		case 5:
+			pred[c] = avg2(avg2(l, pix[top+4+c]), t)
+		case 7:
+			pred[c] = avg2(l, t)
+		case 12:
+			pred[c] = clamp(int(l) + int(t) - int(pix[top-4+c]))
+		case 13:
+			a := avg2(l, t)
+			pred[c] = clamp(int(a) + (int(a)-int(pix[top-4+c]))/2)
+		}
+	}
+
+	if mode == 11 {
+		// Select：选择与左上像素差异较小方向上的像素
+		dl, dt := 0, 0
+		for c := 0; c < 4; c++ {
+			tl := int(pix[top-4+c])
+			dl += abs(tl - int(pix[top+c]))
+			dt += abs(tl - int(pix[p-4+c]))
+		}
+		if dl < dt {
+			copy(pred[:], pix[p-4:p])
+		} else {
+			copy(pred[:], pix[top:top+4])
+		}
+	}
+	return pred
+}
+
+// writeImage 写入一个熵编码图像。与左边像素或上一行重复的连续像素使用LZ77反向引用，
+// 其余像素使用字面量编码
+func writeImage(bw *bitWriter, pix []byte, width int, topLevel bool) {
+	bw.write(0, 1) // 不使用颜色缓存
+	if topLevel {
+		bw.write(0, 1) // 不使用元前缀码
+	}
+
+	type token struct {
+		p      int // 字面量像素的位置
+		length int // 反向引用的像素数，为0时是字面量
+		dist   int // 反向引用的距离代码：1为上一行，2为左边像素
+	}
+
+	n := len(pix) / 4
+	var tokens []token
+	for i := 0; i < n; {
+		best, bestDist := 0, 0
+		for _, candidate := range [2][2]int{{width, 1}, {1, 2}} {
+			offset := candidate[0]
+			if i < offset {
+				continue
+			}
+			length := 0
+			for i+length < n && length < maxCopyLength && samePixel(pix, i+length, i+length-offset) {
+				length++
+			}
+			if length > best {
+				best, bestDist = length, candidate[1]
+			}
+		}
+
+		if best >= minCopyLength {
+			tokens = append(tokens, token{length: best, dist: bestDist})
+			i += best
+		} else {
+			tokens = append(tokens, token{p: 4 * i})
+			i++
+		}
+	}
+
+	// 绿色码表包括24个LZ77长度码
+	histograms := [5][]int{make([]int, 256+24), make([]int, 256), make([]int, 256), make([]int, 256), make([]int, 40)}
+	for _, t := range tokens {
+		if t.length > 0 {
+			lengthSymbol, _, _ := lz77Prefix(t.length)
+			distSymbol, _, _ := lz77Prefix(t.dist)
+			histograms[0][256+lengthSymbol]++
+			histograms[4][distSymbol]++
+			continue
+		}
+		histograms[0][pix[t.p+1]]++
+		histograms[1][pix[t.p+0]]++
+		histograms[2][pix[t.p+2]]++
+		histograms[3][pix[t.p+3]]++
+	}
+
+	var codes [5]*prefixCode
+	for i, histogram := range histograms {
+		codes[i] = writePrefixCode(bw, histogram)
+	}
+
+	for _, t := range tokens {
+		if t.length > 0 {
+			symbol, extra, extraBits := lz77Prefix(t.length)
+			codes[0].writeSymbol(bw, 256+symbol)
+			bw.write(extra, extraBits)
+			symbol, extra, extraBits = lz77Prefix(t.dist)
+			codes[4].writeSymbol(bw, symbol)
+			bw.write(extra, extraBits)
+			continue
+		}
+		codes[0].writeSymbol(bw, int(pix[t.p+1]))
+		codes[1].writeSymbol(bw, int(pix[t.p+0]))
+		codes[2].writeSymbol(bw, int(pix[t.p+2]))
+		codes[3].writeSymbol(bw, int(pix[t.p+3]))
+	}
+}
+
+const (
+	// minCopyLength 使用反向引用的最少重复像素数，更短时字面量通常更省空间
+	minCopyLength = 4
+	// maxCopyLength 一个反向引用最多复制的像素数
+	maxCopyLength = 4096
+)
+
+// samePixel 判断两个位置的像素是否相同
+func samePixel(pix []byte, a, b int) bool {
+	return pix[4*a] == pix[4*b] && pix[4*a+1] == pix[4*b+1] && pix[4*a+2] == pix[4*b+2] && pix[4*a+3] == pix[4*b+3]
+}
+
+// lz77Prefix 把反向引用的长度或距离代码（从1开始）编码为前缀符号和额外位
+func lz77Prefix(value int) (symbol int, extra uint32, extraBits uint) {
+	v := value - 1
+	if v < 4 {
+		return v, 0, 0
+	}
+	high := 0
+	for v>>(high+1) != 0 {
+		high++
+	}
+	second := (v >> (high - 1)) & 1
+	extraBits = uint(high - 1)
+	return 2*high + second, uint32(v) & (1<<extraBits - 1), extraBits
+}
+
+// prefixCode 一个前缀码，codes 中的码字已经按写入顺序反转
+type prefixCode struct {
+	lengths []uint8
+	codes   []uint32
+	trivial bool // 只有一个符号，写入时不占用任何位
+}
+
+// writeSymbol 写入一个符号
+func (c *prefixCode) writeSymbol(bw *bitWriter, symbol int) {
+	if !c.trivial {
+		bw.write(c.codes[symbol], uint(c.lengths[symbol]))
+	}
+}
+
+// writePrefixCode 根据符号频率构造并写入前缀码，不超过两个符号时使用简单码
+func writePrefixCode(bw *bitWriter, histogram []int) *prefixCode {
+	var used []int
+	for symbol, count := range histogram {
+		if count > 0 {
+			used = append(used, symbol)
+		}
+	}
+	if len(used) == 0 {
+		used = []int{0}
+	}
+
+	if len(used) <= 2 && used[len(used)-1] < 256 {
+		bw.write(1, 1)
+		bw.write(uint32(len(used)-1), 1)
+		if used[0] < 2 {
+			bw.write(0, 1)
+			bw.write(uint32(used[0]), 1)
+		} else {
+			bw.write(1, 1)
+			bw.write(uint32(used[0]), 8)
+		}
+
+		code := &prefixCode{lengths: make([]uint8, len(histogram)), codes: make([]uint32, len(histogram))}
+		if len(used) == 1 {
+			code.trivial = true
+			return code
+		}
+		bw.write(uint32(used[1]), 8)
+		code.lengths[used[0]], code.lengths[used[1]] = 1, 1
+		code.codes[used[1]] = 1
+		return code
+	}
+
+	lengths := huffmanLengths(histogram, 15)
+	writeCodeLengths(bw, lengths)
+	return newPrefixCode(lengths)
+}
+
+// codeLengthOrder 码长码的码长的写入顺序
+var codeLengthOrder = [19]int{17, 18, 0, 1, 2, 3, 4, 5, 16, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+
+// writeCodeLengths 用码长码写入普通前缀码的码长，连续的0使用17、18编码
+func writeCodeLengths(bw *bitWriter, lengths []uint8) {
+	type token struct {
+		symbol    int
+		extra     uint32
+		extraBits uint
+	}
+
+	var tokens []token
+	for i := 0; i < len(lengths); {
+		if lengths[i] != 0 {
+			tokens = append(tokens, token{symbol: int(lengths[i])})
+			i++
+			continue
+		}
+
+		run := 0
+		for i+run < len(lengths) && lengths[i+run] == 0 {
+			run++
+		}
+		i += run
+		for run > 0 {
+			switch {
+			case run >= 11:
+				n := minInt(run, 138)
+				tokens = append(tokens, token{18, uint32(n - 11), 7})
+				run -= n
+			case run >= 3:
+				n := minInt(run, 10)
+				tokens = append(tokens, token{17, uint32(n - 3), 3})
+				run -= n
+			default:
+				tokens = append(tokens, token{symbol: 0})
+				run--
+			}
+		}
+	}
+
+	histogram := make([]int, 19)
+	for _, t := range tokens {
+		histogram[t.symbol]++
+	}
+	codeLengthLengths := huffmanLengths(histogram, 7)
+
+	count := 4
+	for i, symbol := range codeLengthOrder {
+		if codeLengthLengths[symbol] != 0 && i+1 > count {
+			count = i + 1
+		}
+	}
+
+	bw.write(0, 1)
+	bw.write(uint32(count-4), 4)
+	for _, symbol := range codeLengthOrder[:count] {
+		bw.write(uint32(codeLengthLengths[symbol]), 3)
+	}
+	bw.write(0, 1) // 写入所有符号的码长
+
+	code := newPrefixCode(codeLengthLengths)
+	for _, t := range tokens {
+		code.writeSymbol(bw, t.symbol)
+		bw.write(t.extra, t.extraBits)
+	}
+}
+
+// newPrefixCode 根据码长生成规范前缀码
+func newPrefixCode(lengths []uint8) *prefixCode {
+	code := &prefixCode{lengths: lengths, codes: make([]uint32, len(lengths))}
+
+	var counts [16]uint32
+	used := 0
+	for _, length := range lengths {
+		if length > 0 {
+			counts[length]++
+			used++
+		}
+	}
+	code.trivial = used == 1
+
+	var next [16]uint32
+	for length, c := 1, uint32(0); length < 16; length++ {
+		c = (c + counts[length-1]) << 1
+		next[length] = c
+	}
+	next[0] = 0
+	for symbol, length := range lengths {
+		if length == 0 {
+			continue
+		}
+		c := next[length]
+		next[length]++
+
+		// 码字从高位开始读取，写入器从低位开始写，需要反转
+		reversed := uint32(0)
+		for i := uint8(0); i < length; i++ {
+			reversed = reversed<<1 | (c>>i)&1
+		}
+		code.codes[symbol] = reversed
+	}
+	return code
+}
+
+// huffmanLengths 根据符号频率计算哈夫曼码长，码长超过maxLength时压缩频率后重新计算
+func huffmanLengths(histogram []int, maxLength int) []uint8 {
+	counts := append([]int(nil), histogram...)
+	for {
+		lengths, longest := buildHuffman(counts)
+		if longest <= maxLength {
+			return lengths
+		}
+		for i, count := range counts {
+			if count > 0 {
+				counts[i] = (count + 1) / 2
+			}
+		}
+	}
+}
+
+// buildHuffman 构造哈夫曼树，返回每个符号的码长和最大码长，只有一个符号时码长为1
+func buildHuffman(counts []int) ([]uint8, int) {
+	type node struct {
+		weight      int
+		left, right int // 子节点下标，叶子节点为-1
+		symbol      int
+	}
+
+	var nodes []node
+	var active []int
+	for symbol, count := range counts {
+		if count > 0 {
+			nodes = append(nodes, node{weight: count, left: -1, right: -1, symbol: symbol})
+			active = append(active, len(nodes)-1)
+		}
+	}
+
+	lengths := make([]uint8, len(counts))
+	if len(active) == 1 {
+		lengths[nodes[0].symbol] = 1
+		return lengths, 1
+	}
+
+	// 每次合并权重最小的两个节点
+	for len(active) > 1 {
+		a, b := 0, 1
+		if nodes[active[b]].weight < nodes[active[a]].weight {
+			a, b = b, a
+		}
+		for i := 2; i < len(active); i++ {
+			w := nodes[active[i]].weight
+			if w < nodes[active[a]].weight {
+				a, b = i, a
+			} else if w < nodes[active[b]].weight {
+				b = i
+			}
+		}
+
+		nodes = append(nodes, node{
+			weight: nodes[active[a]].weight + nodes[active[b]].weight,
+			left:   active[a],
+			right:  active[b],
+		})
+		if a > b {
+			a, b = b, a
+		}
+		active[a] = len(nodes) - 1
+		active = append(active[:b], active[b+1:]...)
+	}
+
+	longest := 0
+	var walk func(n, depth int)
+	walk = func(n, depth int) {
+		if nodes[n].left < 0 {
+			lengths[nodes[n].symbol] = uint8(minInt(depth, 255))
+			longest = maxInt(longest, depth)
+			return
+		}
+		walk(nodes[n].left, depth+1)
+		walk(nodes[n].right, depth+1)
+	}
+	walk(active[0], 0)
+	return lengths, longest
+}
+
+// bitWriter 从低位开始写入的位流
+type bitWriter struct {
+	buf   []byte
+	acc   uint64
+	nBits uint
+}
+
+// write 写入v的低n位
+func (w *bitWriter) write(v uint32, n uint) {
+	w.acc |= uint64(v&(1<<n-1)) << w.nBits
+	w.nBits += n
+	for w.nBits >= 8 {
+		w.buf = append(w.buf, byte(w.acc))
+		w.acc >>= 8
+		w.nBits -= 8
+	}
+}
+
+// bytes 返回写入的数据，不足一个字节的部分补0
+func (w *bitWriter) bytes() []byte {
+	if w.nBits > 0 {
+		w.buf = append(w.buf, byte(w.acc))
+		w.acc, w.nBits = 0, 0
+	}
+	return w.buf
+}
+
+func avg2(a, b uint8) uint8 {
+	return uint8((int(a) + int(b)) / 2)
+}
+
+func clamp(x int) uint8 {
+	if x < 0 {
+		return 0
+	}
+	if x > 255 {
+		return 255
+	}
+	return uint8(x)
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// absInt8 残差按有符号数计算大小，255与-1一样小
+func absInt8(x uint8) int {
+	return abs(int(int8(x)))
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
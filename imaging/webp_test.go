@@ -0,0 +1,104 @@
+package imaging
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"math/rand"
+	"testing"
+
+	"golang.org/x/image/webp"
+)
+
+// fill 生成宽w高h的图片，每个像素的颜色由at决定
+func fill(w, h int, at func(x, y int) color.NRGBA) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.SetNRGBA(x, y, at(x, y))
+		}
+	}
+	return img
+}
+
+// noise 生成随机颜色的图片，固定种子保证每次运行结果相同
+func noise(w, h int, seed int64, alpha bool) *image.NRGBA {
+	r := rand.New(rand.NewSource(seed))
+	return fill(w, h, func(x, y int) color.NRGBA {
+		c := color.NRGBA{uint8(r.Intn(256)), uint8(r.Intn(256)), uint8(r.Intn(256)), 0xff}
+		if alpha {
+			c.A = uint8(r.Intn(256))
+		}
+		return c
+	})
+}
+
+func TestEncodeWebPRoundTrip(t *testing.T) {
+	gray := image.NewGray(image.Rect(0, 0, 9, 4))
+	for i := range gray.Pix {
+		gray.Pix[i] = uint8(i * 7)
+	}
+	offset := noise(20, 20, 7, false).SubImage(image.Rect(3, 5, 14, 12))
+
+	tests := []struct {
+		name string
+		img  image.Image
+	}{
+		{"1x1不透明", fill(1, 1, func(x, y int) color.NRGBA { return color.NRGBA{10, 200, 30, 0xff} })},
+		{"1x1透明", fill(1, 1, func(x, y int) color.NRGBA { return color.NRGBA{} })},
+		{"奇数宽度", noise(3, 5, 1, false)},
+		{"跨越预测区块的奇数宽度", noise(33, 7, 2, false)},
+		{"单行", noise(65, 1, 3, false)},
+		{"单列", noise(1, 41, 4, false)},
+		{"全透明", fill(17, 9, func(x, y int) color.NRGBA { return color.NRGBA{} })},
+		{"全透明但保留颜色", fill(17, 9, func(x, y int) color.NRGBA { return color.NRGBA{uint8(x * 15), uint8(y * 28), 99, 0} })},
+		{"半透明", noise(31, 29, 5, true)},
+		{"超过256种颜色的渐变", fill(64, 64, func(x, y int) color.NRGBA { return color.NRGBA{uint8(x * 4), uint8(y * 4), uint8(x ^ y), 0xff} })},
+		{"超过256种颜色的噪声", noise(50, 37, 6, false)},
+		{"大量重复像素", fill(100, 10, func(x, y int) color.NRGBA { return color.NRGBA{uint8(x / 25 * 60), 0, 0, 0xff} })},
+		{"灰度图片", gray},
+		{"原点不为零的子图", offset},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := EncodeWebP(&buf, tt.img); err != nil {
+				t.Fatalf("编码失败: %v", err)
+			}
+			decoded, err := webp.Decode(&buf)
+			if err != nil {
+				t.Fatalf("解码失败: %v", err)
+			}
+
+			bounds := tt.img.Bounds()
+			want := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+			draw.Draw(want, want.Rect, tt.img, bounds.Min, draw.Src)
+			if got := decoded.Bounds(); got != want.Rect {
+				t.Fatalf("尺寸为 %v，期望 %v", got, want.Rect)
+			}
+			for y := 0; y < want.Rect.Dy(); y++ {
+				for x := 0; x < want.Rect.Dx(); x++ {
+					got := color.NRGBAModel.Convert(decoded.At(x, y)).(color.NRGBA)
+					if w := want.NRGBAAt(x, y); got != w {
+						t.Fatalf("像素 (%d, %d) 为 %v，期望 %v", x, y, got, w)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestEncodeWebPInvalidSize(t *testing.T) {
+	tests := []image.Rectangle{
+		image.Rect(0, 0, 0, 0),
+		image.Rect(0, 0, 10, 0),
+		image.Rect(0, 0, 1<<14+1, 1),
+	}
+	for _, r := range tests {
+		if err := EncodeWebP(&bytes.Buffer{}, image.NewNRGBA(r)); err == nil {
+			t.Errorf("尺寸 %v 应当返回错误", r)
+		}
+	}
+}
@@ -1,24 +1,73 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
+	"goblog/commands"
 	"goblog/config"
+	"goblog/controllers"
+	"goblog/db"
 	"goblog/router"
+	"goblog/tasks"
 )
 
 func main() {
 	// 加载配置
 	cfg := config.LoadConfig()
 
+	// 带参数运行时执行命令行子命令，如 goblog migrate up
+	if len(os.Args) > 1 {
+		os.Exit(commands.Run(cfg, os.Args[1:]))
+	}
+
+	// 打开数据库，整个进程共享一个连接池
+	store, err := db.Open(cfg.Database)
+	if err != nil {
+		log.Fatalf("打开数据库失败: %v", err)
+	}
+	defer store.Close()
+
+	// 服务进程在文章存储前加上读取缓存，命令行工具直接读写数据库
+	store.EnableCache(cfg.Cache)
+
+	// 创建应用容器
+	app, err := controllers.NewApp(cfg, store)
+	if err != nil {
+		log.Fatalf("初始化应用失败: %v", err)
+	}
+
+	// 启动后台任务，服务关闭时停止
+	tasksCtx, stopTasks := context.WithCancel(context.Background())
+	var tasksDone sync.WaitGroup
+	retention := time.Duration(cfg.Blog.TrashRetentionDays) * 24 * time.Hour
+	tasksDone.Add(3)
+	go func() {
+		defer tasksDone.Done()
+		tasks.PurgeTrash(tasksCtx, store.Posts, retention)
+	}()
+	go func() {
+		defer tasksDone.Done()
+		tasks.PublishScheduled(tasksCtx, store.Posts)
+	}()
+	go func() {
+		defer tasksDone.Done()
+		if !store.CanSnapshot() {
+			log.Println("当前数据库不支持在线备份，自动备份已关闭")
+			return
+		}
+		tasks.BackupDatabase(tasksCtx, store, cfg.Backup)
+	}()
+
 	// 初始化路由
-	r := router.SetupRouter()
+	r := router.SetupRouter(app)
 
 	// 配置HTTP服务器
 	srv := &http.Server{
@@ -41,4 +90,14 @@ func main() {
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 	log.Println("正在关闭服务...")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		log.Printf("关闭服务失败: %v", err)
+	}
+
+	// 等待后台任务结束后再关闭数据库
+	stopTasks()
+	tasksDone.Wait()
 }
@@ -0,0 +1,105 @@
+package models
+
+import (
+	"context"
+	"time"
+)
+
+// MaxCommentLength 评论内容的最大字符数
+const MaxCommentLength = 5000
+
+// Comment 评论模型
+type Comment struct {
+	ID        int        `json:"id"`
+	PostID    int        `json:"post_id"`
+	UserID    int        `json:"user_id"`
+	User      *User      `json:"user,omitempty"`
+	ParentID  int        `json:"parent_id,omitempty"` // 0表示直接评论文章
+	Content   string     `json:"content"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"` // 删除时间，被删除的评论仍保留在讨论树中
+}
+
+// CommentStore 评论存储接口
+type CommentStore interface {
+	// ListByPost 查找文章的所有评论，包括已删除的，按发表时间正序
+	ListByPost(ctx context.Context, postID int) ([]*Comment, error)
+
+	// CountByPosts 统计每篇文章未删除的评论数，没有评论的文章不在结果中
+	CountByPosts(ctx context.Context, postIDs []int) (map[int]int, error)
+
+	// FindByID 根据ID查找未删除的评论
+	FindByID(ctx context.Context, id int) (*Comment, error)
+
+	// Create 创建评论
+	Create(ctx context.Context, comment *Comment) error
+
+	// Update 更新评论内容
+	Update(ctx context.Context, comment *Comment) error
+
+	// Delete 删除评论，回复仍然保留
+	Delete(ctx context.Context, id int) error
+}
+
+// CanEdit 判断用户能否编辑评论，仅限评论作者
+func (c *Comment) CanEdit(user *User) bool {
+	return user != nil && c.DeletedAt == nil && user.ID == c.UserID
+}
+
+// CanDelete 判断用户能否删除评论，评论作者、文章作者和管理员可以删除
+func (c *Comment) CanDelete(user *User, post *Post) bool {
+	if user == nil || c.DeletedAt != nil {
+		return false
+	}
+	return user.ID == c.UserID || user.ID == post.UserID || user.IsAdmin
+}
+
+// CommentNode 讨论树中的一个节点
+type CommentNode struct {
+	*Comment
+	Replies []*CommentNode
+
+	// Replyable、Editable 和 Deletable 为当前用户对该评论的权限，供模板使用
+	Replyable bool
+	Editable  bool
+	Deletable bool
+}
+
+// BuildCommentTree 把按时间排列的评论组织为讨论树，并按viewer计算每条评论的权限。
+// 已删除且没有未删除回复的评论不出现在树中
+func BuildCommentTree(comments []*Comment, post *Post, viewer *User) []*CommentNode {
+	nodes := make(map[int]*CommentNode, len(comments))
+	for _, c := range comments {
+		nodes[c.ID] = &CommentNode{
+			Comment:   c,
+			Replyable: viewer != nil && c.DeletedAt == nil,
+			Editable:  c.CanEdit(viewer),
+			Deletable: c.CanDelete(viewer, post),
+		}
+	}
+
+	var roots []*CommentNode
+	for _, c := range comments {
+		node := nodes[c.ID]
+		if parent, ok := nodes[c.ParentID]; ok {
+			parent.Replies = append(parent.Replies, node)
+		} else {
+			roots = append(roots, node)
+		}
+	}
+
+	return pruneDeleted(roots)
+}
+
+// pruneDeleted 去掉已删除且没有未删除回复的评论
+func pruneDeleted(nodes []*CommentNode) []*CommentNode {
+	kept := nodes[:0]
+	for _, node := range nodes {
+		node.Replies = pruneDeleted(node.Replies)
+		if node.DeletedAt == nil || len(node.Replies) > 0 {
+			kept = append(kept, node)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,125 @@
+package models
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// MediaTypes 允许上传的文件类型及其扩展名，类型由文件内容判断，与文件名和请求头无关
+var MediaTypes = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/gif":       ".gif",
+	"image/webp":      ".webp",
+	"application/pdf": ".pdf",
+	"application/zip": ".zip",
+	"text/plain":      ".txt",
+}
+
+// Media 用户上传的文件，文件内容按SHA-256保存，相同内容只保存一份
+type Media struct {
+	ID        int       `json:"id"`
+	UserID    int       `json:"user_id"`
+	FileName  string    `json:"file_name"` // 上传时的原始文件名
+	Hash      string    `json:"hash"`      // 文件内容的SHA-256，十六进制小写
+	MIMEType  string    `json:"mime_type"`
+	Size      int64     `json:"size"`
+	Width     int       `json:"width,omitempty"` // 图片的宽和高，其他文件为0
+	Height    int       `json:"height,omitempty"`
+	Private   bool      `json:"private"` // 私有文件只有上传者和管理员可以访问
+	CreatedAt time.Time `json:"created_at"`
+
+	// SkippedVariants 因为比原图大而没有生成的版本名称，页面中改用原图
+	SkippedVariants []string `json:"skipped_variants,omitempty"`
+}
+
+// MediaStore 上传文件的存储接口，只保存文件信息，文件内容由 storage 包中的存储后端保存
+type MediaStore interface {
+	// FindAll 查找所有用户上传的文件，按ID正序
+	FindAll(ctx context.Context) ([]*Media, error)
+
+	// ListByUser 查找用户上传的所有文件，最新的在前
+	ListByUser(ctx context.Context, userID int) ([]*Media, error)
+
+	// FindByID 根据ID查找文件
+	FindByID(ctx context.Context, id int) (*Media, error)
+
+	// FindByHash 查找用户上传过的相同内容的文件
+	FindByHash(ctx context.Context, userID int, hash string) (*Media, error)
+
+	// FindByHashes 按内容查找文件，每个内容返回一条记录，键为哈希，用于给文章中的图片生成 srcset
+	FindByHashes(ctx context.Context, hashes []string) (map[string]*Media, error)
+
+	// ListByHash 查找引用该内容的所有文件记录，按ID正序，用于判断能否访问文件内容
+	ListByHash(ctx context.Context, hash string) ([]*Media, error)
+
+	// CountByHash 统计引用该内容的文件数，为0时可以删除文件内容
+	CountByHash(ctx context.Context, hash string) (int, error)
+
+	// Create 创建文件记录，同一用户的相同内容只能有一条记录
+	Create(ctx context.Context, media *Media) error
+
+	// SetSkippedVariants 更新引用该内容的所有文件记录中没有生成的版本名称，重新生成图片版本后调用
+	SetSkippedVariants(ctx context.Context, hash string, skipped []string) error
+
+	// SetPrivate 修改文件的可见范围
+	SetPrivate(ctx context.Context, id int, private bool) error
+
+	// Delete 删除文件记录
+	Delete(ctx context.Context, id int) error
+}
+
+// IsImage 判断文件是否为图片
+func (m *Media) IsImage() bool {
+	return strings.HasPrefix(m.MIMEType, "image/")
+}
+
+// Key 文件内容的存储路径，按哈希的前两位分目录，如 ab/abcd...ef.png
+func (m *Media) Key() string {
+	return m.Hash[:2] + "/" + m.Hash + MediaTypes[m.MIMEType]
+}
+
+// URL 文件的访问地址
+func (m *Media) URL() string {
+	return "/uploads/" + m.Key()
+}
+
+// VariantKey 图片缩放版本或缩略图的存储路径，如 ab/abcd...ef-960w.jpg，扩展名由版本的类型决定
+func (m *Media) VariantKey(name, mimeType string) string {
+	return m.Hash[:2] + "/" + m.Hash + "-" + name + MediaTypes[mimeType]
+}
+
+// VariantURL 图片缩放版本或缩略图的访问地址
+func (m *Media) VariantURL(name, mimeType string) string {
+	return "/uploads/" + m.VariantKey(name, mimeType)
+}
+
+// HasVariant 判断图片是否有该名称的版本，比原图大而没有生成的版本返回false
+func (m *Media) HasVariant(name string) bool {
+	for _, skipped := range m.SkippedVariants {
+		if skipped == name {
+			return false
+		}
+	}
+	return true
+}
+
+// Markdown 插入文章的Markdown代码，图片使用图片语法，其他文件使用链接
+func (m *Media) Markdown() string {
+	name := strings.NewReplacer("[", "", "]", "", "\n", " ").Replace(m.FileName)
+	if m.IsImage() {
+		return "![" + name + "](" + m.URL() + ")"
+	}
+	return "[" + name + "](" + m.URL() + ")"
+}
+
+// CanEdit 判断用户能否修改文件的可见范围，上传者和管理员可以修改
+func (m *Media) CanEdit(user *User) bool {
+	return user != nil && (user.ID == m.UserID || user.IsAdmin)
+}
+
+// CanDelete 判断用户能否删除文件，上传者和管理员可以删除
+func (m *Media) CanDelete(user *User) bool {
+	return user != nil && (user.ID == m.UserID || user.IsAdmin)
+}
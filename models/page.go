@@ -0,0 +1,166 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ExcerptLength 列表中文章摘要的最大字符数
+const ExcerptLength = 200
+
+// MaxPageSize 单页允许的最大文章数
+const MaxPageSize = 100
+
+// PostSort 文章排序方式
+type PostSort string
+
+const (
+	// SortNewest 按发布时间从新到旧
+	SortNewest PostSort = "newest"
+
+	// SortOldest 按发布时间从旧到新
+	SortOldest PostSort = "oldest"
+)
+
+// ParsePostSort 解析排序参数，无法识别时返回默认的 SortNewest
+func ParsePostSort(s string) PostSort {
+	if PostSort(s) == SortOldest {
+		return SortOldest
+	}
+	return SortNewest
+}
+
+// Cursor 键集分页游标，标识一篇文章在排序中的位置
+type Cursor struct {
+	CreatedAt time.Time
+	ID        int
+}
+
+// CursorFor 返回指向文章位置的游标
+func CursorFor(post *Post) *Cursor {
+	return &Cursor{CreatedAt: post.CreatedAt, ID: post.ID}
+}
+
+// String 把游标编码为URL参数，格式为 "<Unix秒>_<ID>"
+func (c *Cursor) String() string {
+	return fmt.Sprintf("%d_%d", c.CreatedAt.Unix(), c.ID)
+}
+
+// ParseCursor 解析URL参数中的游标
+func ParseCursor(s string) (*Cursor, error) {
+	parts := strings.SplitN(s, "_", 2)
+	if len(parts) != 2 {
+		return nil, errors.New("无效的分页游标")
+	}
+
+	sec, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return nil, errors.New("无效的分页游标")
+	}
+	id, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return nil, errors.New("无效的分页游标")
+	}
+
+	return &Cursor{CreatedAt: time.Unix(sec, 0).UTC(), ID: id}, nil
+}
+
+// Less 判断文章a在给定排序中是否排在b之前
+func (s PostSort) Less(a, b *Cursor) bool {
+	if a.CreatedAt.Equal(b.CreatedAt) {
+		if s == SortOldest {
+			return a.ID < b.ID
+		}
+		return a.ID > b.ID
+	}
+	if s == SortOldest {
+		return a.CreatedAt.Before(b.CreatedAt)
+	}
+	return a.CreatedAt.After(b.CreatedAt)
+}
+
+// ListOptions 文章列表查询参数，After和Before最多设置一个
+type ListOptions struct {
+	// Limit 每页数量，取值范围 1 到 MaxPageSize
+	Limit int
+
+	// After 返回排在该游标之后的文章（下一页）
+	After *Cursor
+
+	// Before 返回排在该游标之前的文章（上一页）
+	Before *Cursor
+
+	// Sort 排序方式
+	Sort PostSort
+}
+
+// Normalize 修正越界的参数。调用方应先按配置填入默认的 Limit，这里只是最后的保护
+func (o *ListOptions) Normalize() {
+	if o.Limit < 1 {
+		o.Limit = 10
+	}
+	if o.Limit > MaxPageSize {
+		o.Limit = MaxPageSize
+	}
+	if o.Sort != SortOldest {
+		o.Sort = SortNewest
+	}
+	if o.After != nil && o.Before != nil {
+		o.Before = nil
+	}
+}
+
+// PostPage 一页文章，列表中的文章只包含摘要，不含正文
+type PostPage struct {
+	Posts []*Post
+
+	// Next 下一页的游标，为nil表示没有下一页
+	Next *Cursor
+
+	// Prev 上一页的游标，为nil表示没有上一页
+	Prev *Cursor
+}
+
+// NewPostPage 根据多查询一条得到的结果构造分页，posts已按页面展示顺序排列。
+// hasMore 表示查询方向上还有更多文章
+func NewPostPage(posts []*Post, opts ListOptions, hasMore bool) *PostPage {
+	page := &PostPage{Posts: posts}
+	if len(posts) == 0 {
+		return page
+	}
+
+	first := CursorFor(posts[0])
+	last := CursorFor(posts[len(posts)-1])
+
+	switch {
+	case opts.Before != nil:
+		// 向前翻页：游标之后必然还有文章
+		page.Next = last
+		if hasMore {
+			page.Prev = first
+		}
+	case opts.After != nil:
+		page.Prev = first
+		if hasMore {
+			page.Next = last
+		}
+	default:
+		if hasMore {
+			page.Next = last
+		}
+	}
+
+	return page
+}
+
+// Excerpt 截取正文开头作为摘要，按字符而不是字节截断
+func Excerpt(content string) string {
+	runes := []rune(strings.TrimSpace(content))
+	if len(runes) <= ExcerptLength {
+		return string(runes)
+	}
+	return string(runes[:ExcerptLength]) + "..."
+}
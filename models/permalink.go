@@ -0,0 +1,133 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Permalink 文章固定链接格式，由以 / 分隔的若干段组成，每段是固定文字或以下占位符之一：
+// {year}、{month}、{day} 为发布日期，{slug} 为文章别名，{id} 为文章ID
+type Permalink struct {
+	pattern  string
+	segments []string
+}
+
+// permalinkTokens 支持的占位符
+var permalinkTokens = map[string]bool{
+	"{year}": true, "{month}": true, "{day}": true, "{slug}": true, "{id}": true,
+}
+
+// ParsePermalink 解析固定链接格式，格式中必须包含 {slug} 或 {id}
+func ParsePermalink(pattern string) (*Permalink, error) {
+	if !strings.HasPrefix(pattern, "/") {
+		return nil, errors.New("固定链接格式必须以 / 开头")
+	}
+
+	segments := strings.Split(strings.Trim(pattern, "/"), "/")
+	identified := false
+	for _, segment := range segments {
+		switch {
+		case segment == "{slug}" || segment == "{id}":
+			identified = true
+		case permalinkTokens[segment]:
+		case segment == "" || strings.ContainsAny(segment, "{}"):
+			return nil, fmt.Errorf("固定链接格式中有无效的段: %q", segment)
+		}
+	}
+	if !identified {
+		return nil, errors.New("固定链接格式必须包含 {slug} 或 {id}")
+	}
+
+	return &Permalink{pattern: pattern, segments: segments}, nil
+}
+
+// String 返回固定链接格式
+func (p *Permalink) String() string {
+	return p.pattern
+}
+
+// URL 生成文章的固定链接，日期使用文章的发布时间
+func (p *Permalink) URL(post *Post) string {
+	parts := make([]string, len(p.segments))
+	for i, segment := range p.segments {
+		switch segment {
+		case "{year}":
+			parts[i] = post.CreatedAt.Format("2006")
+		case "{month}":
+			parts[i] = post.CreatedAt.Format("01")
+		case "{day}":
+			parts[i] = post.CreatedAt.Format("02")
+		case "{slug}":
+			parts[i] = url.PathEscape(post.Slug)
+		case "{id}":
+			parts[i] = strconv.Itoa(post.ID)
+		default:
+			parts[i] = segment
+		}
+	}
+	return "/" + strings.Join(parts, "/")
+}
+
+// PermalinkMatch 从固定链接中解析出的文章标识
+type PermalinkMatch struct {
+	Slug string
+	ID   int
+}
+
+// Match 解析路径，不符合格式时返回false。日期段只检查格式，
+// 是否与文章一致由调用方通过比较 URL 的结果判断
+func (p *Permalink) Match(path string) (PermalinkMatch, bool) {
+	var match PermalinkMatch
+
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(parts) != len(p.segments) {
+		return match, false
+	}
+
+	for i, segment := range p.segments {
+		part := parts[i]
+		switch segment {
+		case "{year}":
+			if !isDigits(part, 4) {
+				return match, false
+			}
+		case "{month}", "{day}":
+			if !isDigits(part, 2) {
+				return match, false
+			}
+		case "{slug}":
+			slug, err := url.PathUnescape(part)
+			if err != nil || slug == "" {
+				return match, false
+			}
+			match.Slug = slug
+		case "{id}":
+			id, err := strconv.Atoi(part)
+			if err != nil {
+				return match, false
+			}
+			match.ID = id
+		default:
+			if part != segment {
+				return match, false
+			}
+		}
+	}
+	return match, true
+}
+
+// isDigits 判断s是否为n位数字
+func isDigits(s string, n int) bool {
+	if len(s) != n {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -1,34 +1,190 @@
 package models
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"time"
 )
 
 // Post 文章模型
 type Post struct {
-	ID        int       `json:"id"`
-	Title     string    `json:"title"`
-	Content   string    `json:"content"`
-	UserID    int       `json:"user_id"`
-	User      *User     `json:"user,omitempty"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID           int        `json:"id"`
+	Title        string     `json:"title"`
+	Slug         string     `json:"slug"` // 文章别名，用于固定链接，全局唯一
+	Content      string     `json:"content"`
+	Format       PostFormat `json:"format"`
+	Excerpt      string     `json:"excerpt,omitempty"` // 列表查询只返回摘要
+	UserID       int        `json:"user_id"`
+	User         *User      `json:"user,omitempty"`
+	Tags         []string   `json:"tags"`
+	CategoryID   int        `json:"category_id,omitempty"` // 0表示未分类
+	Category     *Category  `json:"category,omitempty"`    // 保存时ID为0的分类按Path查找，不存在的各级分类随文章一起创建
+	CommentCount int        `json:"comment_count"`         // 未删除的评论数，仅在列表页面中填充
+	Status       PostStatus `json:"status"`
+	PublishAt    *time.Time `json:"publish_at,omitempty"` // 定时发布或实际发布的时间，草稿为空
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+	DeletedAt    *time.Time `json:"deleted_at,omitempty"` // 移入回收站的时间，未删除时为空
+
+	// Version 文章的版本号，创建时为1，每次 Update 加一，用于检测并发编辑的冲突
+	Version int `json:"version"`
 }
 
 // PostStore 文章存储接口
 type PostStore interface {
-	// FindAll 查找所有文章
-	FindAll() ([]*Post, error)
+	// FindAll 查找所有已发布的文章，不包括回收站中的文章
+	FindAll(ctx context.Context) ([]*Post, error)
+
+	// List 按键集分页查询已发布的文章列表，结果只包含摘要
+	List(ctx context.Context, opts ListOptions) (*PostPage, error)
+
+	// ListByTag 按键集分页查询带有某个标签的文章
+	ListByTag(ctx context.Context, tag string, opts ListOptions) (*PostPage, error)
+
+	// ListByCategory 按键集分页查询某个分类及其下级分类中的文章
+	ListByCategory(ctx context.Context, slug string, opts ListOptions) (*PostPage, error)
+
+	// Search 全文搜索文章，结果按相关度排序
+	Search(ctx context.Context, opts SearchOptions) (*SearchResult, error)
+
+	// FindByID 根据ID查找任意状态的文章，回收站中的文章视为不存在，可见性由调用方检查
+	FindByID(ctx context.Context, id int) (*Post, error)
+
+	// FindBySlug 根据别名查找任意状态的文章，也会查找文章以前使用过的别名，
+	// 返回文章的 Slug 与参数不同时表示这是旧别名
+	FindBySlug(ctx context.Context, slug string) (*Post, error)
+
+	// Create 创建文章，同时保存标签和分类，状态为空时视为已发布，格式为空时视为Markdown。
+	// 别名为空时根据标题生成，与其他文章重复时加上 -2、-3 等后缀
+	Create(ctx context.Context, post *Post) error
+
+	// Update 更新文章及其标签和分类，并以editorID为作者保存一个新版本。
+	// 别名的处理与 Create 相同，修改后旧别名仍然可以找到文章。
+	// post.Version 与保存的版本号不一致时返回冲突错误，成功后 post.Version 加一
+	Update(ctx context.Context, post *Post, editorID int) error
+
+	// Delete 把文章移入回收站
+	Delete(ctx context.Context, id int) error
 
-	// FindByID 根据ID查找文章
-	FindByID(id int) (*Post, error)
+	// Drafts 查找用户的草稿和定时文章，按更新时间倒序
+	Drafts(ctx context.Context, userID int) ([]*Post, error)
 
-	// Create 创建文章
-	Create(post *Post) error
+	// PublishDue 发布定时发布时间不晚于now的文章，返回发布的数量
+	PublishDue(ctx context.Context, now time.Time) (int, error)
+
+	// Trash 查找用户回收站中的文章，按删除时间倒序
+	Trash(ctx context.Context, userID int) ([]*Post, error)
+
+	// FindTrashed 根据ID查找回收站中的文章
+	FindTrashed(ctx context.Context, id int) (*Post, error)
+
+	// Restore 把文章从回收站中恢复
+	Restore(ctx context.Context, id int) error
+
+	// Purge 永久删除回收站中的文章及其历史版本
+	Purge(ctx context.Context, id int) error
+
+	// PurgeDeletedBefore 永久删除在before之前移入回收站的文章，返回删除的数量
+	PurgeDeletedBefore(ctx context.Context, before time.Time) (int, error)
+
+	// Revisions 查找文章的所有历史版本，按时间倒序
+	Revisions(ctx context.Context, postID int) ([]*Revision, error)
+
+	// FindRevision 查找文章的某个历史版本
+	FindRevision(ctx context.Context, postID, revisionID int) (*Revision, error)
+
+	// LatestRevision 查找文章的最新版本，即文章的当前内容，包括渲染结果的缓存
+	LatestRevision(ctx context.Context, postID int) (*Revision, error)
+
+	// SaveRevisionHTML 保存版本的渲染结果
+	SaveRevisionHTML(ctx context.Context, revisionID int, html string) error
+}
 
-	// Update 更新文章
-	Update(post *Post) error
+// PostStatus 文章发布状态
+type PostStatus string
+
+const (
+	// StatusDraft 草稿，只有作者可见
+	StatusDraft PostStatus = "draft"
+
+	// StatusScheduled 定时发布，到 PublishAt 时由后台任务发布
+	StatusScheduled PostStatus = "scheduled"
+
+	// StatusPublished 已发布，所有人可见
+	StatusPublished PostStatus = "published"
+)
+
+// ParsePostStatus 解析状态参数，空字符串视为已发布
+func ParsePostStatus(s string) (PostStatus, error) {
+	switch status := PostStatus(s); status {
+	case "":
+		return StatusPublished, nil
+	case StatusDraft, StatusScheduled, StatusPublished:
+		return status, nil
+	default:
+		return "", fmt.Errorf("未知的文章状态: %s", s)
+	}
+}
+
+// PostFormat 文章正文格式
+type PostFormat string
+
+const (
+	// FormatMarkdown Markdown格式，显示时渲染为HTML
+	FormatMarkdown PostFormat = "markdown"
+
+	// FormatText 纯文本，显示时只做转义
+	FormatText PostFormat = "text"
+)
+
+// ParsePostFormat 解析正文格式参数，空字符串视为Markdown
+func ParsePostFormat(s string) (PostFormat, error) {
+	switch format := PostFormat(s); format {
+	case "":
+		return FormatMarkdown, nil
+	case FormatMarkdown, FormatText:
+		return format, nil
+	default:
+		return "", fmt.Errorf("未知的正文格式: %s", s)
+	}
+}
+
+// IsPublished 判断文章是否已发布，状态为空的文章视为已发布
+func (p *Post) IsPublished() bool {
+	return p.Status == StatusPublished || p.Status == ""
+}
+
+// CanView 判断用户能否查看文章，未发布的文章只有作者可见
+func (p *Post) CanView(user *User) bool {
+	return p.IsPublished() || (user != nil && user.ID == p.UserID)
+}
+
+// SetStatus 设置文章状态，publishAt只用于定时发布。
+// 文章从未发布变为发布时以now作为发布时间，使其出现在列表的最前面
+func (p *Post) SetStatus(status PostStatus, publishAt time.Time, now time.Time) error {
+	switch status {
+	case StatusDraft:
+		p.PublishAt = nil
+	case StatusScheduled:
+		if !publishAt.After(now) {
+			return errors.New("定时发布时间必须晚于当前时间")
+		}
+		p.PublishAt = &publishAt
+	case StatusPublished:
+		if !p.IsPublished() || p.PublishAt == nil {
+			p.CreatedAt = now
+			p.PublishAt = &now
+		}
+	default:
+		return fmt.Errorf("未知的文章状态: %s", status)
+	}
+
+	p.Status = status
+	return nil
+}
 
-	// Delete 删除文章
-	Delete(id int) error
+// CanManageRevisions 判断用户能否查看和恢复文章的历史版本，仅限作者和管理员
+func (p *Post) CanManageRevisions(user *User) bool {
+	return user != nil && (user.ID == p.UserID || user.IsAdmin)
 }
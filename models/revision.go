@@ -0,0 +1,96 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// Revision 文章的一个历史版本，文章每次创建和更新时保存
+type Revision struct {
+	ID        int        `json:"id"`
+	PostID    int        `json:"post_id"`
+	UserID    int        `json:"user_id"`
+	User      *User      `json:"user,omitempty"`
+	Title     string     `json:"title"`
+	Content   string     `json:"content"`
+	Format    PostFormat `json:"format"`
+	HTML      string     `json:"-"` // Markdown渲染结果的缓存，只由 LatestRevision 读取，为空表示尚未渲染
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// DiffOp 差异行的类型
+type DiffOp string
+
+const (
+	DiffEqual  DiffOp = "equal"
+	DiffInsert DiffOp = "insert"
+	DiffDelete DiffOp = "delete"
+)
+
+// DiffLine 统一差异格式中的一行
+type DiffLine struct {
+	Op   DiffOp
+	Text string
+
+	// OldLine 和 NewLine 为该行在旧、新版本中的行号，不存在时为0
+	OldLine int
+	NewLine int
+}
+
+// RevisionDiff 两个版本之间的差异
+type RevisionDiff struct {
+	From *Revision
+	To   *Revision
+
+	// TitleChanged 标题是否有变化
+	TitleChanged bool
+
+	// Lines 正文的逐行差异
+	Lines []DiffLine
+}
+
+// DiffRevisions 按行比较两个版本的正文
+func DiffRevisions(from, to *Revision) *RevisionDiff {
+	return &RevisionDiff{
+		From:         from,
+		To:           to,
+		TitleChanged: from.Title != to.Title,
+		Lines:        DiffText(from.Content, to.Content),
+	}
+}
+
+// DiffText 按行比较两段文本，返回统一差异格式的所有行
+func DiffText(oldText, newText string) []DiffLine {
+	// 统一换行符，避免浏览器提交的 \r\n 使每一行都显示为修改
+	oldLines := splitLines(strings.ReplaceAll(oldText, "\r\n", "\n"))
+	newLines := splitLines(strings.ReplaceAll(newText, "\r\n", "\n"))
+
+	var result []DiffLine
+	matcher := difflib.NewMatcher(oldLines, newLines)
+	for _, op := range matcher.GetOpCodes() {
+		if op.Tag == 'e' {
+			for i, j := op.I1, op.J1; i < op.I2; i, j = i+1, j+1 {
+				result = append(result, DiffLine{Op: DiffEqual, Text: oldLines[i], OldLine: i + 1, NewLine: j + 1})
+			}
+			continue
+		}
+		// 替换显示为先删除旧行再插入新行
+		for i := op.I1; i < op.I2; i++ {
+			result = append(result, DiffLine{Op: DiffDelete, Text: oldLines[i], OldLine: i + 1})
+		}
+		for j := op.J1; j < op.J2; j++ {
+			result = append(result, DiffLine{Op: DiffInsert, Text: newLines[j], NewLine: j + 1})
+		}
+	}
+	return result
+}
+
+// splitLines 把文本拆分为行，行尾的换行符不产生额外的空行
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
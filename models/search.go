@@ -0,0 +1,164 @@
+package models
+
+import (
+	"strings"
+	"unicode"
+)
+
+// 搜索结果中高亮片段的起止标记，渲染时先对文本做HTML转义，再把标记替换为 <mark> 标签
+const (
+	HighlightStart = "\x02"
+	HighlightEnd   = "\x03"
+)
+
+// SnippetLength 搜索结果摘要片段的字符数
+const SnippetLength = 80
+
+// SearchOptions 全文搜索参数
+type SearchOptions struct {
+	// Query 用户输入的搜索词，多个词以空白分隔，需同时匹配
+	Query string
+
+	// Limit 每页数量，取值范围 1 到 MaxPageSize
+	Limit int
+
+	// Offset 跳过的结果数
+	Offset int
+}
+
+// Normalize 修正越界的参数
+func (o *SearchOptions) Normalize() {
+	if o.Limit < 1 {
+		o.Limit = 10
+	}
+	if o.Limit > MaxPageSize {
+		o.Limit = MaxPageSize
+	}
+	if o.Offset < 0 {
+		o.Offset = 0
+	}
+}
+
+// SearchHit 一条搜索结果
+type SearchHit struct {
+	Post *Post
+
+	// Title 带高亮标记的标题
+	Title string
+
+	// Snippet 带高亮标记的正文片段
+	Snippet string
+}
+
+// SearchResult 一页搜索结果
+type SearchResult struct {
+	Hits []*SearchHit
+
+	// Total 匹配的结果总数
+	Total int
+}
+
+// SearchTerms 把搜索词按空白拆分为关键词
+func SearchTerms(query string) []string {
+	return strings.Fields(query)
+}
+
+// Highlight 用高亮标记包裹text中出现的所有关键词，匹配不区分大小写
+func Highlight(text string, terms []string) string {
+	runes := []rune(text)
+	marks := matchMask(runes, terms)
+	return markRunes(runes, marks)
+}
+
+// Snippet 截取text中第一个关键词附近约 SnippetLength 个字符并高亮关键词，
+// 没有匹配时返回开头部分
+func Snippet(text string, terms []string) string {
+	runes := []rune(strings.TrimSpace(text))
+	marks := matchMask(runes, terms)
+
+	first := 0
+	for i, marked := range marks {
+		if marked {
+			first = i
+			break
+		}
+	}
+
+	start := first - SnippetLength/4
+	if start < 0 {
+		start = 0
+	}
+	end := start + SnippetLength
+	if end > len(runes) {
+		end = len(runes)
+	}
+
+	snippet := markRunes(runes[start:end], marks[start:end])
+	if start > 0 {
+		snippet = "..." + snippet
+	}
+	if end < len(runes) {
+		snippet += "..."
+	}
+	return snippet
+}
+
+// MatchesAll 判断text是否包含所有关键词，不区分大小写
+func MatchesAll(text string, terms []string) bool {
+	lower := strings.ToLower(text)
+	for _, term := range terms {
+		if !strings.Contains(lower, strings.ToLower(term)) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchMask 标记runes中属于任一关键词匹配的位置
+func matchMask(runes []rune, terms []string) []bool {
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+
+	marks := make([]bool, len(runes))
+	for _, term := range terms {
+		t := []rune(term)
+		for i, r := range t {
+			t[i] = unicode.ToLower(r)
+		}
+		if len(t) == 0 {
+			continue
+		}
+
+		for i := 0; i+len(t) <= len(lower); i++ {
+			if string(lower[i:i+len(t)]) == string(t) {
+				for j := i; j < i+len(t); j++ {
+					marks[j] = true
+				}
+			}
+		}
+	}
+	return marks
+}
+
+// markRunes 在连续的标记区间两端插入高亮标记
+func markRunes(runes []rune, marks []bool) string {
+	var b strings.Builder
+	inMark := false
+	for i, r := range runes {
+		if marks[i] != inMark {
+			if marks[i] {
+				b.WriteString(HighlightStart)
+			} else {
+				b.WriteString(HighlightEnd)
+			}
+			inMark = marks[i]
+		}
+		b.WriteRune(r)
+	}
+	if inMark {
+		b.WriteString(HighlightEnd)
+	}
+	return b.String()
+}
@@ -0,0 +1,56 @@
+package models
+
+import (
+	"context"
+	"time"
+)
+
+// SiteData 整站数据，用于在实例之间迁移博客。记录之间通过导出时的ID关联，
+// 文章的标签保存在 Post.Tags 中，其余关联字段（作者、分类、上级评论等）都是同一份数据中的ID
+type SiteData struct {
+	Users      []*User
+	Categories []*Category // 上级分类在下级分类之前
+	Posts      []*Post
+	Redirects  []*SlugRedirect
+	Revisions  []*Revision
+	Comments   []*Comment // 被回复的评论在回复之前
+	Media      []*Media
+}
+
+// SlugRedirect 文章以前使用过的别名，访问时重定向到文章当前的地址
+type SlugRedirect struct {
+	Slug      string
+	PostID    int
+	CreatedAt time.Time
+}
+
+// LoadResult 写入整站数据的结果
+type LoadResult struct {
+	// 新建的记录数，已存在而直接使用的分类和文件不计入
+	Users      int
+	Categories int
+	Posts      int
+	Revisions  int
+	Comments   int
+	Media      int
+
+	// Overwritten 被覆盖资料的现有用户数
+	Overwritten int
+
+	// RenamedSlugs 与现有文章重复而加上后缀的别名，键为原别名
+	RenamedSlugs map[string]string
+}
+
+// SiteStore 整站数据的读取和写入接口
+type SiteStore interface {
+	// Dump 读取整站数据，包括草稿、回收站中的文章、旧别名、历史版本和已删除的评论，用户包括密码哈希
+	Dump(ctx context.Context) (*SiteData, error)
+
+	// Load 在一个事务中写入data，为所有记录分配新的ID并转换记录之间的关联，时间保持不变。
+	// existing 把data中的用户ID映射到现有用户，这些用户的内容归属现有用户，
+	// 如果data.Users中仍包含该用户则用其资料覆盖现有用户，密码哈希为空时保留原密码；
+	// 其余用户新建，必须带有密码哈希。
+	// 文章别名与现有文章重复时加上 -2、-3 等后缀，已存在的分类和同一用户相同内容的文件直接使用现有记录。
+	// dryRun为真时执行同样的检查但不保存任何修改
+	Load(ctx context.Context, data *SiteData, existing map[int]int, dryRun bool) (*LoadResult, error)
+}
@@ -0,0 +1,98 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/mozillazg/go-pinyin"
+)
+
+// MaxSlugLength 文章别名的最大字符数
+const MaxSlugLength = 80
+
+// SlugFallback 标题中的非ASCII字符（如汉字）生成别名的方式
+type SlugFallback string
+
+const (
+	// SlugPinyin 汉字转写为不带声调的拼音，每个字一个音节，多音字使用字典中的第一个读音；
+	// 其他非ASCII字母和 SlugUnicode 一样保留
+	SlugPinyin SlugFallback = "pinyin"
+
+	// SlugUnicode 保留汉字等非ASCII字母，URL中会被百分号编码
+	SlugUnicode SlugFallback = "unicode"
+
+	// SlugASCII 只保留ASCII字母和数字，没有可用字符时使用 post
+	SlugASCII SlugFallback = "ascii"
+)
+
+// reservedSlugs 与 /posts/ 下固定路由冲突的别名
+var reservedSlugs = map[string]bool{
+	"new": true, "create": true, "edit": true, "update": true, "delete": true,
+}
+
+// ParseSlugFallback 解析配置中的别名生成方式，空字符串视为 SlugPinyin
+func ParseSlugFallback(s string) (SlugFallback, error) {
+	switch fallback := SlugFallback(s); fallback {
+	case "":
+		return SlugPinyin, nil
+	case SlugPinyin, SlugUnicode, SlugASCII:
+		return fallback, nil
+	default:
+		return "", fmt.Errorf("未知的别名生成方式: %s", s)
+	}
+}
+
+// MakeSlug 根据标题或作者填写的别名生成文章别名：小写，空白和标点替换为 -，
+// 超长时在 - 处截断。纯数字的别名会与文章ID混淆，和保留的别名一样加上前缀或后缀
+func MakeSlug(s string, fallback SlugFallback) string {
+	switch fallback {
+	case SlugPinyin:
+		s = toPinyin(s)
+	case SlugASCII:
+		s = strings.Map(func(r rune) rune {
+			if r > unicode.MaxASCII {
+				return ' '
+			}
+			return r
+		}, s)
+	}
+
+	slug := Slugify(s)
+	if runes := []rune(slug); len(runes) > MaxSlugLength {
+		slug = string(runes[:MaxSlugLength])
+		if i := strings.LastIndexByte(slug, '-'); i > 0 {
+			slug = slug[:i]
+		}
+	}
+
+	switch {
+	case slug == "":
+		return "post"
+	case strings.IndexFunc(slug, func(r rune) bool { return !unicode.IsDigit(r) }) < 0:
+		return "post-" + slug
+	case reservedSlugs[slug]:
+		return slug + "-post"
+	}
+	return slug
+}
+
+// pinyinArgs 转写拼音的参数：不带声调，不启用多音字
+var pinyinArgs = pinyin.NewArgs()
+
+// toPinyin 把汉字替换为两侧带空格的拼音，Slugify 会把空格转为 -，其他字符不变
+func toPinyin(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r <= unicode.MaxASCII {
+			b.WriteRune(r)
+			continue
+		}
+		if py := pinyin.SinglePinyin(r, pinyinArgs); len(py) > 0 {
+			b.WriteString(" " + py[0] + " ")
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,142 @@
+package models
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// MaxTagLength 标签名的最大字符数
+const MaxTagLength = 50
+
+// Tag 标签
+type Tag struct {
+	Name string `json:"name"`
+
+	// PostCount 带有该标签的文章数，不包括回收站中的文章
+	PostCount int `json:"post_count"`
+}
+
+// Category 文章分类，分类可以有上级分类
+type Category struct {
+	ID       int    `json:"id"`
+	ParentID int    `json:"parent_id,omitempty"` // 0表示顶级分类
+	Name     string `json:"name"`
+
+	// Path 从顶级分类开始以 / 连接的名称，如 "技术/Go"
+	Path string `json:"path"`
+
+	// Slug 用于URL的路径，如 "技术/go"
+	Slug string `json:"slug"`
+
+	// Depth 分类的层级，顶级分类为0
+	Depth int `json:"-"`
+
+	// PostCount 该分类及其下级分类中的文章数，不包括回收站中的文章
+	PostCount int `json:"post_count"`
+}
+
+// TaxonomyStore 标签和分类存储接口
+type TaxonomyStore interface {
+	// Tags 查找所有至少有一篇文章的标签，按文章数倒序
+	Tags(ctx context.Context) ([]*Tag, error)
+
+	// FindTag 根据名称查找标签
+	FindTag(ctx context.Context, name string) (*Tag, error)
+
+	// Categories 查找所有分类，按树形结构深度优先排列
+	Categories(ctx context.Context) ([]*Category, error)
+
+	// FindCategory 根据slug查找分类
+	FindCategory(ctx context.Context, slug string) (*Category, error)
+
+	// EnsureCategory 按路径查找分类，不存在的各级分类会被创建，path为空时返回nil
+	EnsureCategory(ctx context.Context, path string) (*Category, error)
+}
+
+// ParseTags 解析以逗号分隔的标签，去除空白和重复，统一为小写
+func ParseTags(input string) []string {
+	fields := strings.FieldsFunc(input, func(r rune) bool {
+		return r == ',' || r == '，' || r == '、'
+	})
+
+	tags := []string{}
+	seen := make(map[string]bool)
+	for _, field := range fields {
+		tag := NormalizeTag(field)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+	return tags
+}
+
+// NormalizeTag 规范化标签名：小写、合并空白、去掉 / 并限制长度
+func NormalizeTag(name string) string {
+	name = strings.ToLower(strings.Join(strings.Fields(name), " "))
+	name = strings.ReplaceAll(name, "/", "")
+	if runes := []rune(name); len(runes) > MaxTagLength {
+		name = strings.TrimSpace(string(runes[:MaxTagLength]))
+	}
+	return name
+}
+
+// SplitCategoryPath 把 "技术 / Go" 形式的分类路径拆分为各级名称
+func SplitCategoryPath(path string) []string {
+	var names []string
+	for _, part := range strings.Split(path, "/") {
+		name := strings.Join(strings.Fields(part), " ")
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Slugify 把分类名转换为URL中的一段路径：小写，空白和标点替换为 -
+func Slugify(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+			dash = false
+			continue
+		}
+		if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	return strings.TrimSuffix(b.String(), "-")
+}
+
+// SortCategoryTree 把分类按树形结构深度优先排列，同级按名称排序，并设置层级
+func SortCategoryTree(categories []*Category) []*Category {
+	children := make(map[int][]*Category)
+	for _, c := range categories {
+		children[c.ParentID] = append(children[c.ParentID], c)
+	}
+
+	sorted := make([]*Category, 0, len(categories))
+	var walk func(parentID, depth int)
+	walk = func(parentID, depth int) {
+		siblings := children[parentID]
+		sort.Slice(siblings, func(i, j int) bool { return siblings[i].Name < siblings[j].Name })
+		for _, c := range siblings {
+			c.Depth = depth
+			sorted = append(sorted, c)
+			walk(c.ID, depth+1)
+		}
+	}
+	walk(0, 0)
+	return sorted
+}
+
+// InCategory 判断slug是否为category本身或其下级分类
+func InCategory(slug, category string) bool {
+	return slug == category || strings.HasPrefix(slug, category+"/")
+}
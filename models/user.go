@@ -1,6 +1,7 @@
 package models
 
 import (
+	"context"
 	"time"
 )
 
@@ -10,6 +11,7 @@ type User struct {
 	Username  string    `json:"username"`
 	Email     string    `json:"email"`
 	Password  string    `json:"-"` // 不输出到JSON
+	IsAdmin   bool      `json:"is_admin"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
@@ -17,23 +19,23 @@ type User struct {
 // UserStore 用户存储接口
 type UserStore interface {
 	// FindByID 根据ID查找用户
-	FindByID(id int) (*User, error)
+	FindByID(ctx context.Context, id int) (*User, error)
 
 	// FindByUsername 根据用户名查找用户
-	FindByUsername(username string) (*User, error)
+	FindByUsername(ctx context.Context, username string) (*User, error)
 
 	// FindByEmail 根据邮箱查找用户
-	FindByEmail(email string) (*User, error)
+	FindByEmail(ctx context.Context, email string) (*User, error)
 
-	// Create 创建用户
-	Create(user *User) error
+	// Create 创建用户，用户名或邮箱已被使用时返回违反唯一约束的错误
+	Create(ctx context.Context, user *User) error
 
-	// Update 更新用户
-	Update(user *User) error
+	// Update 更新用户，用户名或邮箱已被使用时返回违反唯一约束的错误
+	Update(ctx context.Context, user *User) error
 
 	// Delete 删除用户
-	Delete(id int) error
+	Delete(ctx context.Context, id int) error
 
-	// Authenticate 认证用户
-	Authenticate(username, password string) (*User, error)
+	// Authenticate 认证用户，用户不存在和密码错误返回相同的记录不存在错误
+	Authenticate(ctx context.Context, username, password string) (*User, error)
 }
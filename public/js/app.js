@@ -44,6 +44,43 @@ document.addEventListener('DOMContentLoaded', function() {
         });
     });
 
+    // 编辑器中上传文件，上传成功后在光标处插入Markdown代码
+    document.querySelectorAll('input[data-upload-target]').forEach(input => {
+        input.addEventListener('change', function() {
+            const file = input.files[0];
+            if (!file) {
+                return;
+            }
+            const target = document.getElementById(input.dataset.uploadTarget);
+            const status = input.parentElement.querySelector('.upload-status');
+            const body = new FormData();
+            body.append('file', file);
+            status.textContent = '上传中...';
+
+            fetch('/media/upload', {
+                method: 'POST',
+                headers: { 'Accept': 'application/json' },
+                body: body
+            }).then(response => {
+                if (!response.ok) {
+                    return response.text().then(text => { throw new Error(text.trim()); });
+                }
+                return response.json();
+            }).then(media => {
+                const start = target.selectionStart;
+                const end = target.selectionEnd;
+                target.value = target.value.slice(0, start) + media.markdown + target.value.slice(end);
+                target.selectionStart = target.selectionEnd = start + media.markdown.length;
+                target.focus();
+                status.textContent = '已插入 ' + file.name;
+            }).catch(error => {
+                status.textContent = '上传失败: ' + error.message;
+            }).finally(() => {
+                input.value = '';
+            });
+        });
+    });
+
     // 添加响应式导航菜单
     const navToggle = document.querySelector('.nav-toggle');
     if (navToggle) {
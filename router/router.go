@@ -6,33 +6,71 @@ import (
 	"net/http"
 )
 
-// SetupRouter 设置路由
-func SetupRouter() http.Handler {
+// SetupRouter 设置路由，处理器均为应用容器上的方法
+func SetupRouter(app *controllers.App) http.Handler {
 	// 创建默认多路复用器
 	mux := http.NewServeMux()
 
 	// 静态文件服务
 	fileServer := http.FileServer(http.Dir("./public"))
 	mux.Handle("/static/", http.StripPrefix("/static", fileServer))
+	mux.Handle("/uploads/", http.StripPrefix("/uploads", app.UploadsHandler()))
 
 	// 首页
-	mux.HandleFunc("/", controllers.HomeHandler)
+	mux.HandleFunc("/", app.HomeHandler)
 
 	// 文章相关路由
-	mux.HandleFunc("/posts", controllers.ListPostsHandler)
-	mux.HandleFunc("/posts/", controllers.GetPostHandler)
-	mux.HandleFunc("/posts/new", controllers.NewPostFormHandler)
-	mux.HandleFunc("/posts/create", controllers.CreatePostHandler)
-	mux.HandleFunc("/posts/edit/", controllers.EditPostFormHandler)
-	mux.HandleFunc("/posts/update/", controllers.UpdatePostHandler)
-	mux.HandleFunc("/posts/delete/", controllers.DeletePostHandler)
+	mux.HandleFunc("/posts", app.ListPostsHandler)
+	mux.HandleFunc("/posts/", app.PostHandler)
+	mux.HandleFunc("/posts/new", app.NewPostFormHandler)
+	mux.HandleFunc("/posts/create", app.CreatePostHandler)
+	mux.HandleFunc("/posts/edit/", app.EditPostFormHandler)
+	mux.HandleFunc("/posts/update/", app.UpdatePostHandler)
+	mux.HandleFunc("/posts/delete/", app.DeletePostHandler)
+
+	// 评论，发表评论的路由为 /posts/{id}/comments
+	mux.HandleFunc("/comments/edit/", app.EditCommentFormHandler)
+	mux.HandleFunc("/comments/update/", app.UpdateCommentHandler)
+	mux.HandleFunc("/comments/delete/", app.DeleteCommentHandler)
+
+	// 标签和分类
+	mux.HandleFunc("/tags", app.TagsHandler)
+	mux.HandleFunc("/tags/", app.TagHandler)
+	mux.HandleFunc("/categories", app.CategoriesHandler)
+	mux.HandleFunc("/categories/", app.CategoryHandler)
+
+	// 草稿
+	mux.HandleFunc("/drafts", app.DraftsHandler)
+
+	// 媒体库和文件上传
+	mux.HandleFunc("/media", app.MediaHandler)
+	mux.HandleFunc("/media/upload", app.UploadMediaHandler)
+	mux.HandleFunc("/media/delete/", app.DeleteMediaHandler)
+	mux.HandleFunc("/media/visibility/", app.SetMediaVisibilityHandler)
+
+	// 回收站
+	mux.HandleFunc("/trash", app.TrashHandler)
+	mux.HandleFunc("/trash/restore/", app.RestoreTrashHandler)
+	mux.HandleFunc("/trash/purge/", app.PurgeTrashHandler)
+
+	// 管理员：数据库备份
+	mux.HandleFunc("/admin/backups", app.BackupsHandler)
+	mux.HandleFunc("/admin/backups/create", app.CreateBackupHandler)
+	mux.HandleFunc("/admin/backups/download/", app.DownloadBackupHandler)
+	mux.HandleFunc("/admin/export", app.ExportHandler)
+
+	// 运行统计，供监控系统采集
+	mux.HandleFunc("/admin/stats", app.StatsHandler)
+
+	// 搜索
+	mux.HandleFunc("/search", app.SearchHandler)
 
 	// 用户相关路由
-	mux.HandleFunc("/login", controllers.LoginFormHandler)
-	mux.HandleFunc("/login/process", controllers.LoginProcessHandler)
-	mux.HandleFunc("/logout", controllers.LogoutHandler)
-	mux.HandleFunc("/register", controllers.RegisterFormHandler)
-	mux.HandleFunc("/register/process", controllers.RegisterProcessHandler)
+	mux.HandleFunc("/login", app.LoginFormHandler)
+	mux.HandleFunc("/login/process", app.LoginProcessHandler)
+	mux.HandleFunc("/logout", app.LogoutHandler)
+	mux.HandleFunc("/register", app.RegisterFormHandler)
+	mux.HandleFunc("/register/process", app.RegisterProcessHandler)
 
 	// 应用中间件
 	var handler http.Handler = mux
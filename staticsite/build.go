@@ -0,0 +1,409 @@
+// Package staticsite 把博客的公开页面渲染为静态HTML文件，用于在静态托管服务上发布只读镜像。
+// 页面通过与服务相同的路由和模板以匿名用户的身份渲染，站内链接改写为相对路径
+package staticsite
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"goblog/models"
+	"io"
+	"io/fs"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ManifestName 输出目录中记录上次构建结果的文件，用于增量构建
+const ManifestName = ".goblog-build.json"
+
+// manifestVersion 构建记录的格式版本，与记录中的不同时全部重新构建
+const manifestVersion = 1
+
+// listingSeeds 作为构建起点的列表页面，分页、标签和分类页面通过其中的链接找到
+var listingSeeds = []string{"/", "/posts", "/tags", "/categories"}
+
+// Options 构建选项
+type Options struct {
+	Out       string // 输出目录
+	Public    string // 静态资源目录，复制到输出目录的 static/ 下
+	Templates string // 模板目录，内容变化时重新渲染所有页面
+	Force     bool   // 忽略上次的构建结果，重新渲染所有页面
+
+	// Progress 每渲染、复制或删除一个文件调用一次，可以为空
+	Progress func(action, file string)
+}
+
+// Result 构建结果
+type Result struct {
+	Rendered int // 重新渲染的页面
+	Kept     int // 没有变化而保留的页面和文件
+	Copied   int // 复制的静态资源和上传文件
+	Removed  int // 删除的过期文件
+}
+
+// manifest 上次构建的结果
+type manifest struct {
+	Version   int              `json:"version"`
+	Templates string           `json:"templates"` // 模板目录内容的哈希
+	Listings  string           `json:"listings"`  // 所有文章的链接和修改时间的哈希，变化时重新渲染列表页
+	Pages     map[string]*page `json:"pages"`     // 键为站内链接
+	Assets    []string         `json:"assets"`    // 复制的静态资源
+}
+
+// page 一个生成的页面或上传文件
+type page struct {
+	File  string    `json:"file"`
+	Stamp time.Time `json:"stamp,omitempty"` // 文章页面为文章及其评论的最后修改时间
+	Links []string  `json:"links,omitempty"` // 页面中需要一起发布的站内链接
+}
+
+// builder 一次构建的状态
+type builder struct {
+	ctx     context.Context
+	handler http.Handler
+	opts    Options
+	result  Result
+
+	old, next *manifest
+
+	// posts 已发布文章的链接到其页面的修改时间
+	posts map[string]time.Time
+
+	// rebuild 为真时重新渲染所有页面，reuseListings 为真时列表页面没有变化，可以保留上次的结果
+	rebuild, reuseListings bool
+
+	queue []string
+	seen  map[string]bool
+}
+
+// Build 构建静态站点。handler 是服务使用的路由，posts 和 comments 用于判断文章页面是否需要重新渲染，
+// permalink 生成文章的链接。文章及其评论没有修改的页面保留上次的结果，
+// 文章都没有变化时列表页面也全部保留；上次生成而这次没有生成的文件被删除。
+// ctx 用于查询文章和评论以及渲染页面，取消后构建中止
+func Build(ctx context.Context, handler http.Handler, posts models.PostStore, comments models.CommentStore, permalink *models.Permalink, opts Options) (*Result, error) {
+	b := &builder{
+		ctx:     ctx,
+		handler: handler,
+		opts:    opts,
+		next:    &manifest{Version: manifestVersion, Pages: map[string]*page{}},
+		posts:   make(map[string]time.Time),
+		seen:    make(map[string]bool),
+	}
+
+	var err error
+	if b.next.Templates, err = hashDir(opts.Templates); err != nil {
+		return nil, err
+	}
+	b.old = b.readManifest()
+	b.rebuild = opts.Force || b.old.Templates != b.next.Templates
+
+	published, err := posts.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	urls := make([]string, 0, len(published))
+	for _, post := range published {
+		stamp, err := postStamp(ctx, comments, post)
+		if err != nil {
+			return nil, err
+		}
+		u := permalink.URL(post)
+		b.posts[u] = stamp
+		urls = append(urls, u)
+	}
+	sort.Strings(urls)
+	b.next.Listings = b.listingsHash(urls)
+	b.reuseListings = !b.rebuild && b.old.Listings == b.next.Listings
+
+	for _, u := range listingSeeds {
+		b.enqueue(u)
+	}
+	for _, u := range urls {
+		b.enqueue(u)
+	}
+	for len(b.queue) > 0 {
+		u := b.queue[0]
+		b.queue = b.queue[1:]
+		if err := b.visit(u); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := b.copyAssets(); err != nil {
+		return nil, err
+	}
+	b.removeStale()
+	if err := b.writeManifest(); err != nil {
+		return nil, err
+	}
+	return &b.result, nil
+}
+
+// postStamp 返回文章页面的修改时间，即文章和评论修改、删除时间中最晚的一个
+func postStamp(ctx context.Context, comments models.CommentStore, post *models.Post) (time.Time, error) {
+	stamp := post.UpdatedAt
+	list, err := comments.ListByPost(ctx, post.ID)
+	if err != nil {
+		return stamp, err
+	}
+	for _, c := range list {
+		if c.UpdatedAt.After(stamp) {
+			stamp = c.UpdatedAt
+		}
+		if c.DeletedAt != nil && c.DeletedAt.After(stamp) {
+			stamp = *c.DeletedAt
+		}
+	}
+	return stamp, nil
+}
+
+// listingsHash 计算所有文章的链接和修改时间的哈希
+func (b *builder) listingsHash(urls []string) string {
+	h := sha256.New()
+	for _, u := range urls {
+		fmt.Fprintf(h, "%s\t%d\n", u, b.posts[u].UnixNano())
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// enqueue 把站内链接加入待构建队列，只接受文章、列表页面和上传文件
+func (b *builder) enqueue(link string) {
+	key, ok := pageKey(link)
+	if !ok || b.seen[key] || !b.publishable(key) {
+		return
+	}
+	b.seen[key] = true
+	b.queue = append(b.queue, key)
+}
+
+// publishable 判断站内链接是否需要发布：已发布的文章、列表页面和上传文件
+func (b *builder) publishable(key string) bool {
+	_, isPost := b.posts[key]
+	return isPost || isListing(key) || isUpload(key)
+}
+
+// visit 构建一个页面或上传文件，没有变化时保留上次的结果
+func (b *builder) visit(key string) error {
+	file, ok := fileFor(key)
+	if !ok {
+		return nil
+	}
+
+	stamp, isPost := b.posts[key]
+	if old, ok := b.old.Pages[key]; ok && old.File == file && b.exists(file) {
+		// 上传文件以内容哈希命名，内容不会变化
+		reusable := isUpload(key) || b.reuseListings
+		if isPost {
+			reusable = !b.rebuild && old.Stamp.Equal(stamp)
+		}
+		if reusable {
+			b.next.Pages[key] = old
+			for _, link := range old.Links {
+				b.enqueue(link)
+			}
+			b.result.Kept++
+			return nil
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	b.handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, key, nil).WithContext(b.ctx))
+	if rec.Code != http.StatusOK {
+		log.Printf("跳过 %s: 状态码 %d", key, rec.Code)
+		return nil
+	}
+
+	body := rec.Body.Bytes()
+	p := &page{File: file}
+	if isPost {
+		p.Stamp = stamp
+	}
+	if strings.HasPrefix(rec.Header().Get("Content-Type"), "text/html") {
+		var links []string
+		body, links = rewriteLinks(body, file)
+		for _, link := range links {
+			if k, ok := pageKey(link); ok && b.publishable(k) {
+				p.Links = append(p.Links, k)
+			}
+			b.enqueue(link)
+		}
+	}
+
+	if err := b.writeFile(file, body); err != nil {
+		return err
+	}
+	b.next.Pages[key] = p
+	if isUpload(key) {
+		b.result.Copied++
+		b.progress("复制", file)
+	} else {
+		b.result.Rendered++
+		b.progress("渲染", file)
+	}
+	return nil
+}
+
+// copyAssets 把静态资源目录复制到输出目录的 static/ 下，大小和修改时间相同的文件不再复制
+func (b *builder) copyAssets() error {
+	return filepath.WalkDir(b.opts.Public, func(src string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(b.opts.Public, src)
+		if err != nil {
+			return err
+		}
+		file := "static/" + filepath.ToSlash(rel)
+		b.next.Assets = append(b.next.Assets, file)
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		dst := filepath.Join(b.opts.Out, filepath.FromSlash(file))
+		if existing, err := os.Stat(dst); err == nil && existing.Size() == info.Size() && existing.ModTime().Equal(info.ModTime()) {
+			b.result.Kept++
+			return nil
+		}
+
+		data, err := os.ReadFile(src)
+		if err != nil {
+			return err
+		}
+		if err := b.writeFile(file, data); err != nil {
+			return err
+		}
+		if err := os.Chtimes(dst, info.ModTime(), info.ModTime()); err != nil {
+			return err
+		}
+		b.result.Copied++
+		b.progress("复制", file)
+		return nil
+	})
+}
+
+// removeStale 删除上次生成而这次没有生成的文件，以及因此变空的目录
+func (b *builder) removeStale() {
+	current := make(map[string]bool)
+	for _, p := range b.next.Pages {
+		current[p.File] = true
+	}
+	for _, file := range b.next.Assets {
+		current[file] = true
+	}
+
+	var stale []string
+	for _, p := range b.old.Pages {
+		stale = append(stale, p.File)
+	}
+	stale = append(stale, b.old.Assets...)
+	sort.Strings(stale)
+
+	for _, file := range stale {
+		if current[file] {
+			continue
+		}
+		current[file] = true
+
+		path := filepath.Join(b.opts.Out, filepath.FromSlash(file))
+		if err := os.Remove(path); err != nil {
+			if !errors.Is(err, fs.ErrNotExist) {
+				log.Printf("删除过期文件 %s 失败: %v", file, err)
+			}
+			continue
+		}
+		b.result.Removed++
+		b.progress("删除", file)
+
+		// 目录不为空时 os.Remove 失败，停止向上删除
+		for dir := filepath.Dir(path); dir != filepath.Clean(b.opts.Out); dir = filepath.Dir(dir) {
+			if os.Remove(dir) != nil {
+				break
+			}
+		}
+	}
+}
+
+// readManifest 读取上次的构建记录，记录不存在或无法使用时返回空的记录
+func (b *builder) readManifest() *manifest {
+	empty := &manifest{Pages: map[string]*page{}}
+
+	data, err := os.ReadFile(filepath.Join(b.opts.Out, ManifestName))
+	if err != nil {
+		return empty
+	}
+	var m manifest
+	if err := json.Unmarshal(data, &m); err != nil || m.Version != manifestVersion {
+		return empty
+	}
+	if m.Pages == nil {
+		m.Pages = map[string]*page{}
+	}
+	return &m
+}
+
+// writeManifest 保存本次的构建记录
+func (b *builder) writeManifest() error {
+	data, err := json.MarshalIndent(b.next, "", "  ")
+	if err != nil {
+		return err
+	}
+	return b.writeFile(ManifestName, data)
+}
+
+// writeFile 把内容写入输出目录中的文件，先写入临时文件再改名，避免留下不完整的页面
+func (b *builder) writeFile(file string, data []byte) error {
+	path := filepath.Join(b.opts.Out, filepath.FromSlash(file))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// exists 判断输出目录中的文件是否存在
+func (b *builder) exists(file string) bool {
+	_, err := os.Stat(filepath.Join(b.opts.Out, filepath.FromSlash(file)))
+	return err == nil
+}
+
+// progress 报告构建进度
+func (b *builder) progress(action, file string) {
+	if b.opts.Progress != nil {
+		b.opts.Progress(action, file)
+	}
+}
+
+// hashDir 计算目录中所有文件的路径和内容的哈希
+func hashDir(dir string) (string, error) {
+	h := sha256.New()
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		fmt.Fprintf(h, "%s\n", filepath.ToSlash(path))
+		_, err = io.Copy(h, file)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,142 @@
+package staticsite
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"html"
+	"net/url"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// linkAttr 匹配HTML中包含链接的属性，第一个分组为属性名，第二个分组为转义后的属性值
+var linkAttr = regexp.MustCompile(`\b(href|src|srcset)="([^"]*)"`)
+
+// pageKey 把站内链接规范化为构建记录中的键：转义后的路径加上按名称排序的查询参数，不含片段。
+// 不是以 / 开头的站内链接时返回false
+func pageKey(link string) (string, bool) {
+	if !strings.HasPrefix(link, "/") || strings.HasPrefix(link, "//") {
+		return "", false
+	}
+	u, err := url.Parse(link)
+	if err != nil {
+		return "", false
+	}
+	key := u.EscapedPath()
+	if query := u.Query(); len(query) > 0 {
+		key += "?" + query.Encode()
+	}
+	return key, true
+}
+
+// isUpload 判断链接是否为上传文件
+func isUpload(key string) bool {
+	return strings.HasPrefix(key, "/uploads/")
+}
+
+// isAsset 判断链接是否为 public 目录中的静态资源
+func isAsset(key string) bool {
+	return strings.HasPrefix(key, "/static/")
+}
+
+// isListing 判断链接是否为首页、文章列表、标签和分类等列表页面
+func isListing(key string) bool {
+	p, _, _ := strings.Cut(key, "?")
+	switch p {
+	case "/", "/posts", "/tags", "/categories":
+		return true
+	}
+	return strings.HasPrefix(p, "/tags/") || strings.HasPrefix(p, "/categories/")
+}
+
+// fileFor 返回站内链接对应的输出文件，相对输出目录并以 / 分隔。静态资源和上传文件保持原路径，
+// 页面保存为对应目录下的 index.html，带查询参数的分页和排序页面保存在以参数哈希命名的子目录中
+func fileFor(key string) (string, bool) {
+	u, err := url.Parse(key)
+	if err != nil {
+		return "", false
+	}
+	p := path.Clean("/" + u.Path)
+	if strings.Contains(p, "/.") {
+		return "", false
+	}
+	if isAsset(p) || isUpload(p) {
+		return strings.TrimPrefix(p, "/"), true
+	}
+
+	dir := strings.TrimPrefix(p, "/")
+	if u.RawQuery != "" {
+		sum := sha256.Sum256([]byte(u.RawQuery))
+		dir = path.Join(dir, "q-"+hex.EncodeToString(sum[:6]))
+	}
+	return path.Join(dir, "index.html"), true
+}
+
+// relative 返回从输出文件from链接到输出文件to的相对地址，各段经过转义
+func relative(from, to string) string {
+	rel, err := filepath.Rel(filepath.FromSlash(path.Dir(from)), filepath.FromSlash(to))
+	if err != nil {
+		return to
+	}
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+	for i, part := range parts {
+		parts[i] = url.PathEscape(part)
+	}
+	return strings.Join(parts, "/")
+}
+
+// rewriteLinks 把输出文件from的HTML中以 / 开头的站内链接改写为相对地址，
+// 链接到页面时指向其 index.html，这样直接打开本地文件也能浏览。返回改写后的HTML和其中的站内链接
+func rewriteLinks(body []byte, from string) ([]byte, []string) {
+	var links []string
+	seen := make(map[string]bool)
+	relink := func(link string) string {
+		key, ok := pageKey(link)
+		if !ok {
+			return link
+		}
+		file, ok := fileFor(key)
+		if !ok {
+			return link
+		}
+		if !seen[key] {
+			seen[key] = true
+			links = append(links, key)
+		}
+
+		rel := relative(from, file)
+		if _, fragment, found := strings.Cut(link, "#"); found {
+			rel += "#" + fragment
+		}
+		return rel
+	}
+
+	rewritten := linkAttr.ReplaceAllFunc(body, func(match []byte) []byte {
+		sub := linkAttr.FindSubmatch(match)
+		attr, value := string(sub[1]), html.UnescapeString(string(sub[2]))
+
+		var result string
+		if attr == "srcset" {
+			// srcset 由逗号分隔的“地址 宽度”组成
+			candidates := strings.Split(value, ",")
+			for i, candidate := range candidates {
+				fields := strings.Fields(candidate)
+				if len(fields) > 0 {
+					fields[0] = relink(fields[0])
+				}
+				candidates[i] = strings.Join(fields, " ")
+			}
+			result = strings.Join(candidates, ", ")
+		} else {
+			result = relink(value)
+		}
+
+		if result == value {
+			return match
+		}
+		return []byte(attr + `="` + html.EscapeString(result) + `"`)
+	})
+	return rewritten, links
+}
@@ -0,0 +1,109 @@
+// Package storage 保存上传文件的内容，文件以相对路径形式的键访问，如 ab/abcd...ef.png
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"goblog/config"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path"
+	"time"
+)
+
+// ErrNotExist 表示文件不存在
+var ErrNotExist = errors.New("文件不存在")
+
+// Storage 上传文件内容的存储后端
+type Storage interface {
+	// Put 保存文件内容，键已存在时覆盖
+	Put(key string, r io.Reader, size int64, contentType string) error
+
+	// Get 读取文件内容，文件不存在时返回 ErrNotExist
+	Get(key string) (io.ReadCloser, error)
+
+	// Delete 删除文件，文件不存在时不返回错误
+	Delete(key string) error
+
+	// URL 返回浏览器直接访问文件的地址，可能是带签名的临时地址；
+	// 返回空字符串表示没有可以直接访问的地址，由应用读取内容后返回
+	URL(key string) (string, error)
+}
+
+// New 按配置创建存储后端，name 为空时使用配置中选择的后端
+func New(cfg config.UploadConfig, name string) (Storage, error) {
+	if name == "" {
+		name = cfg.Storage
+	}
+
+	switch name {
+	case "", "local":
+		return NewLocal(cfg.Dir)
+	case "s3":
+		return NewS3(cfg.S3)
+	default:
+		return nil, fmt.Errorf("未知的存储后端: %s", name)
+	}
+}
+
+// Signer 由可以生成带签名临时地址的后端实现
+type Signer interface {
+	// SignedURL 返回带签名的临时地址，后端没有启用签名时返回空字符串
+	SignedURL(key string) (string, error)
+}
+
+// Serve 返回上传文件的内容，文章中保存的是应用的地址，更换存储后端或签名过期都不影响已插入的文件。
+// 调用方负责检查访问权限；private 为true时不使用公开地址，只重定向到带签名的临时地址，
+// 后端不支持签名时由应用读取内容后返回，并且禁止共享缓存
+func Serve(w http.ResponseWriter, r *http.Request, s Storage, key string, private bool) {
+	// 可以直接访问的后端重定向到文件地址，签名地址会过期，不能使用永久重定向
+	var url string
+	var err error
+	if !private {
+		url, err = s.URL(key)
+	} else if signer, ok := s.(Signer); ok {
+		url, err = signer.SignedURL(key)
+	}
+	if err != nil {
+		log.Printf("获取文件 %s 的地址失败: %v", key, err)
+		http.Error(w, "无法读取文件", http.StatusInternalServerError)
+		return
+	}
+	if private {
+		w.Header().Set("Cache-Control", "private, no-cache")
+	}
+	if url != "" {
+		http.Redirect(w, r, url, http.StatusFound)
+		return
+	}
+
+	body, err := s.Get(key)
+	if errors.Is(err, ErrNotExist) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		log.Printf("读取文件 %s 失败: %v", key, err)
+		http.Error(w, "无法读取文件", http.StatusInternalServerError)
+		return
+	}
+	defer body.Close()
+
+	// 上传文件的类型已经检查过，禁止浏览器再按内容猜测类型；
+	// 文件名包含内容的哈希，内容不会改变，公开文件可以长期缓存
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if !private {
+		w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	}
+
+	if seeker, ok := body.(io.ReadSeeker); ok {
+		http.ServeContent(w, r, key, time.Time{}, seeker)
+		return
+	}
+	if contentType := mime.TypeByExtension(path.Ext(key)); contentType != "" {
+		w.Header().Set("Content-Type", contentType)
+	}
+	io.Copy(w, body)
+}
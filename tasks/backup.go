@@ -0,0 +1,44 @@
+package tasks
+
+import (
+	"context"
+	"goblog/backup"
+	"goblog/config"
+	"log"
+	"time"
+)
+
+// BackupCheckInterval 检查是否需要自动备份的间隔
+const BackupCheckInterval = time.Hour
+
+// BackupDatabase 在最新的备份超过配置的间隔时创建新备份并删除旧备份，直到ctx取消。
+// 按已有备份的时间判断，服务频繁重启不会产生多余的备份，停机期间错过的备份在启动时补上
+func BackupDatabase(ctx context.Context, store backup.Snapshotter, cfg config.BackupConfig) {
+	if cfg.IntervalHours <= 0 {
+		log.Println("数据库自动备份已关闭")
+		return
+	}
+	interval := time.Duration(cfg.IntervalHours) * time.Hour
+
+	ticker := time.NewTicker(BackupCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		backups, err := backup.List(cfg.Dir)
+		if err != nil {
+			log.Printf("读取备份目录失败: %v", err)
+		} else if len(backups) == 0 || time.Since(backups[0].CreatedAt) >= interval {
+			if info, err := backup.Create(ctx, store, cfg); err != nil {
+				log.Printf("自动备份数据库失败: %v", err)
+			} else {
+				log.Printf("已自动备份数据库: %s", info.Name)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
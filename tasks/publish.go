@@ -0,0 +1,33 @@
+package tasks
+
+import (
+	"context"
+	"goblog/models"
+	"log"
+	"time"
+)
+
+// PublishInterval 检查定时发布文章的间隔，定时发布时间精确到分钟
+const PublishInterval = 30 * time.Second
+
+// PublishScheduled 定期发布已到时间的定时文章，直到ctx取消。
+// 定时状态保存在数据库中，启动时立即执行一次，停机期间到期的文章在重启后发布
+func PublishScheduled(ctx context.Context, posts models.PostStore) {
+	ticker := time.NewTicker(PublishInterval)
+	defer ticker.Stop()
+
+	for {
+		published, err := posts.PublishDue(ctx, time.Now())
+		if err != nil {
+			log.Printf("发布定时文章失败: %v", err)
+		} else if published > 0 {
+			log.Printf("已发布 %d 篇定时文章", published)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
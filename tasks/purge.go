@@ -0,0 +1,39 @@
+// Package tasks 实现随服务运行的后台定时任务
+package tasks
+
+import (
+	"context"
+	"goblog/models"
+	"log"
+	"time"
+)
+
+// PurgeInterval 清理回收站的间隔
+const PurgeInterval = time.Hour
+
+// PurgeTrash 定期永久删除在回收站中超过retention的文章，直到ctx取消。
+// 启动时立即执行一次，retention不大于0时不执行任何清理
+func PurgeTrash(ctx context.Context, posts models.PostStore, retention time.Duration) {
+	if retention <= 0 {
+		log.Println("回收站自动清理已关闭")
+		return
+	}
+
+	ticker := time.NewTicker(PurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		purged, err := posts.PurgeDeletedBefore(ctx, time.Now().Add(-retention))
+		if err != nil {
+			log.Printf("清理回收站失败: %v", err)
+		} else if purged > 0 {
+			log.Printf("已从回收站永久删除 %d 篇文章", purged)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
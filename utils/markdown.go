@@ -0,0 +1,50 @@
+package utils
+
+import (
+	"bytes"
+	"regexp"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/renderer/html"
+)
+
+var (
+	// markdown 是Markdown渲染器，支持CommonMark、GFM（表格、任务列表、删除线、自动链接）和脚注。
+	// 允许原始HTML，输出统一经过 sanitizer 清理
+	markdown = goldmark.New(
+		goldmark.WithExtensions(extension.GFM, extension.Footnote),
+		goldmark.WithRendererOptions(html.WithUnsafe()),
+	)
+
+	// sanitizer 是白名单HTML清理策略，在用户内容策略的基础上允许任务列表的复选框和脚注的链接
+	sanitizer = newSanitizer()
+)
+
+// newSanitizer 创建HTML清理策略
+func newSanitizer() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+
+	// 任务列表：<input checked="" disabled="" type="checkbox">
+	p.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	p.AllowAttrs("checked", "disabled").OnElements("input")
+
+	// 代码块的语言：<code class="language-go">
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^language-[\w+#-]+$`)).OnElements("code")
+
+	// 脚注：<sup id="fnref:1"><a href="#fn:1" class="footnote-ref" role="doc-noteref">
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^footnote(s|-ref|-backref)$`)).OnElements("a", "div")
+	p.AllowAttrs("role").Matching(regexp.MustCompile(`^doc-(noteref|endnotes|backlink)$`)).OnElements("a", "div")
+
+	return p
+}
+
+// RenderMarkdown 把Markdown渲染为清理后的HTML
+func RenderMarkdown(source string) (string, error) {
+	var buf bytes.Buffer
+	if err := markdown.Convert([]byte(source), &buf); err != nil {
+		return "", err
+	}
+	return sanitizer.Sanitize(buf.String()), nil
+}
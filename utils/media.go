@@ -0,0 +1,43 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"goblog/models"
+	"image"
+	"mime"
+	"net/http"
+	"strings"
+
+	// 注册解码器，用于读取图片尺寸
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+
+	_ "golang.org/x/image/webp"
+)
+
+// ErrUnsupportedMedia 表示文件类型不在允许上传的范围内
+var ErrUnsupportedMedia = errors.New("不支持的文件类型")
+
+// DetectMedia 根据文件内容判断类型，图片同时返回宽和高。
+// 只接受 models.MediaTypes 中的类型，文件名和客户端声明的类型都不可信
+func DetectMedia(data []byte) (mimeType string, width, height int, err error) {
+	mimeType, _, err = mime.ParseMediaType(http.DetectContentType(data))
+	if err != nil {
+		return "", 0, 0, ErrUnsupportedMedia
+	}
+	if _, ok := models.MediaTypes[mimeType]; !ok {
+		return "", 0, 0, ErrUnsupportedMedia
+	}
+
+	if strings.HasPrefix(mimeType, "image/") {
+		config, _, err := image.DecodeConfig(bytes.NewReader(data))
+		if err != nil {
+			return "", 0, 0, errors.New("图片文件已损坏")
+		}
+		width, height = config.Width, config.Height
+	}
+
+	return mimeType, width, height, nil
+}
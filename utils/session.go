@@ -30,6 +30,7 @@ func SetUserSession(w http.ResponseWriter, r *http.Request, user *models.User) e
 		"id":       user.ID,
 		"username": user.Username,
 		"email":    user.Email,
+		"is_admin": user.IsAdmin,
 	}
 
 	// 序列化用户数据
@@ -75,6 +76,11 @@ func GetUserFromSession(r *http.Request) *models.User {
 		UpdatedAt: time.Time{},
 	}
 
+	// 旧会话中没有管理员标记，视为普通用户
+	if isAdmin, ok := userMap["is_admin"].(bool); ok {
+		user.IsAdmin = isAdmin
+	}
+
 	return user
 }
 